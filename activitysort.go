@@ -67,6 +67,20 @@ func activityTypeGetterNameToType(getter string) ActivityType {
 		return ActivityCreateInvite
 	case "inviteDeleted":
 		return ActivityDeleteInvite
+	case "accountAdopted":
+		return ActivityAdopted
+	case "accountDowngraded":
+		return ActivityDowngraded
+	case "accountRenewed":
+		return ActivityRenewed
+	case "paymentReceived":
+		return ActivityPayment
+	case "emailUndeliverable":
+		return ActivityEmailUndeliverable
+	case "adminCommand":
+		return ActivityAdminCommand
+	case "discordDeparture":
+		return ActivityDiscordDeparture
 	}
 	return ActivityUnknown
 }
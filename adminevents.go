@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/lithammer/shortuuid/v3"
+)
+
+const (
+	AdminEventUserExpired      = "user_expired"
+	AdminEventUserDeleted      = "user_deleted"
+	AdminEventPasswordReset    = "password_reset"
+	AdminEventContactUnlinked  = "contact_unlinked"
+	AdminEventFailedLogin      = "failed_login"
+	AdminEventDiscordDeparture = "discord_departure"
+	AdminEventPaymentUnapplied = "payment_unapplied"
+	// Failed login notifications aren't sent more often than this, so a brute-force attempt doesn't flood admins.
+	FAILED_LOGIN_NOTIFY_COOLDOWN = 10 * time.Minute
+)
+
+// adminEvent describes an event admins can be notified of.
+type adminEvent struct {
+	Content string // Name of the event's CustomContentInfo.
+	Lang    string // Prefix of the event's strings in the "adminEvents" email lang section.
+}
+
+// adminEvents maps events (also the setting names enabling them in [admin_events]) to their message details.
+var adminEvents = map[string]adminEvent{
+	AdminEventUserExpired:      {"AdminUserExpired", "userExpired"},
+	AdminEventUserDeleted:      {"AdminUserDeleted", "userDeleted"},
+	AdminEventPasswordReset:    {"AdminPasswordReset", "passwordReset"},
+	AdminEventContactUnlinked:  {"AdminContactUnlinked", "contactUnlinked"},
+	AdminEventFailedLogin:      {"AdminFailedLogin", "failedLogin"},
+	AdminEventDiscordDeparture: {"AdminDiscordDeparture", "discordDeparture"},
+	AdminEventPaymentUnapplied: {"AdminPaymentUnapplied", "paymentUnapplied"},
+}
+
+// recordActivity stores the given activity, and notifies admins if it's an event they want to know about.
+func (app *appContext) recordActivity(act Activity, gc *gin.Context, user bool) {
+	app.storage.SetActivityKey(shortuuid.New(), act, gc, user)
+	app.notifyAdminsOfActivity(act)
+}
+
+// notifyAdminsOfActivity sends the relevant admin notification for the given activity, if there is one.
+func (app *appContext) notifyAdminsOfActivity(act Activity) {
+	if !messagesEnabled {
+		return
+	}
+	username := act.Value
+	if username == "" || act.Type == ActivityContactUnlinked {
+		username = app.usernameOrID(act.UserID)
+	}
+	// Don't notify an admin of their own actions.
+	exclude := ""
+	if act.SourceType == ActivityAdmin {
+		exclude = act.Source
+	}
+	switch act.Type {
+	case ActivityDisabled, ActivityDeletion:
+		if act.SourceType == ActivityDaemon && act.Source == "" {
+			action := app.email.lang.AdminEvents.get("disabled")
+			if act.Type == ActivityDeletion {
+				action = app.email.lang.AdminEvents.get("deleted")
+			}
+			app.notifyAdmins(AdminEventUserExpired, map[string]any{"name": username, "action": action}, exclude)
+		} else if act.Type == ActivityDeletion && act.SourceType == ActivityAdmin {
+			admin := app.email.lang.AdminEvents.get("anAdmin")
+			if act.Source != "" {
+				admin = app.usernameOrID(act.Source)
+			}
+			app.notifyAdmins(AdminEventUserDeleted, map[string]any{"name": username, "admin": admin}, exclude)
+		}
+	case ActivityResetPassword:
+		app.notifyAdmins(AdminEventPasswordReset, map[string]any{"name": username}, exclude)
+	case ActivityContactUnlinked:
+		app.notifyAdmins(AdminEventContactUnlinked, map[string]any{"name": username, "method": act.Value}, exclude)
+	}
+}
+
+// notifyAdminsOfFailedLogin notifies admins of a failed attempt to log in to the admin page.
+func (app *appContext) notifyAdminsOfFailedLogin(username string, gc *gin.Context) {
+	app.failedLoginNotifyLock.Lock()
+	if time.Since(app.failedLoginNotified) < FAILED_LOGIN_NOTIFY_COOLDOWN {
+		app.failedLoginNotifyLock.Unlock()
+		return
+	}
+	app.failedLoginNotified = time.Now()
+	app.failedLoginNotifyLock.Unlock()
+	ip := app.email.lang.AdminEvents.get("unknown")
+	if LOGIP {
+		ip = gc.ClientIP()
+	}
+	app.notifyAdmins(AdminEventFailedLogin, map[string]any{"name": username, "ip": ip}, "")
+}
+
+// adminEventChannels returns the contact methods the given event should be sent through. If none are set, any can be used.
+func (app *appContext) adminEventChannels(event string) []string {
+	channels := []string{}
+	for _, c := range app.config.Section("admin_events").Key(event + "_channels").StringsWithShadows("|") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			channels = append(channels, c)
+		}
+	}
+	return channels
+}
+
+// notifyAdmins sends the notification for the given event to admins (except "exclude"), if it's enabled.
+func (app *appContext) notifyAdmins(event string, vars map[string]any, exclude string) {
+	if !messagesEnabled || !app.config.Section("admin_events").Key(event).MustBool(false) {
+		return
+	}
+	msg, err := app.email.constructAdminEvent(event, vars, time.Now(), false)
+	if err != nil {
+		app.err.Printf(lm.FailedConstructAdminEvent, event, err)
+		return
+	}
+	app.sendToAdminsVia(msg, app.adminEventChannels(event), exclude, func(recipient string, err error) {
+		if err != nil {
+			app.err.Printf(lm.FailedSendAdminEvent, event, recipient, err)
+		} else {
+			app.debug.Printf(lm.SentAdminEvent, event, recipient)
+		}
+	})
+}
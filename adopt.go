@@ -0,0 +1,168 @@
+package main
+
+import (
+	"time"
+
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/hrfee/mediabrowser"
+	"github.com/lithammer/shortuuid/v3"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const ADOPTION_BASELINE_KEY = "adoption_baseline"
+
+// hasJfaGoRecord returns whether jfa-go stores anything about the given user.
+func (app *appContext) hasJfaGoRecord(jfID string) bool {
+	if _, ok := app.storage.GetEmailsKey(jfID); ok {
+		return true
+	}
+	if _, ok := app.storage.GetDiscordKey(jfID); ok {
+		return true
+	}
+	if _, ok := app.storage.GetTelegramKey(jfID); ok {
+		return true
+	}
+	if _, ok := app.storage.GetMatrixKey(jfID); ok {
+		return true
+	}
+	if _, ok := app.storage.GetPushKey(jfID); ok {
+		return true
+	}
+	if _, ok := app.storage.GetUserExpiryKey(jfID); ok {
+		return true
+	}
+	return false
+}
+
+// adoptUnknownUsers finds Jellyfin users jfa-go hasn't seen before, and adopts those that weren't created through jfa-go.
+// Every user checked is marked as known, so it won't be considered again.
+func (app *appContext) adoptUnknownUsers() {
+	app.debug.Println(lm.CheckUnknownUsers)
+	users, err := app.jf.GetUsers(false)
+	if err != nil {
+		app.err.Printf(lm.FailedGetUsers, lm.Jellyfin, err)
+		return
+	}
+
+	known := map[string]bool{}
+	for _, u := range app.storage.GetKnownUsers() {
+		known[u.JellyfinID] = true
+	}
+
+	userExists := map[string]bool{}
+	unknown := []mediabrowser.User{}
+	for _, user := range users {
+		userExists[user.ID] = true
+		if !known[user.ID] {
+			unknown = append(unknown, user)
+		}
+	}
+	// Forget users which no longer exist
+	for id := range known {
+		if !userExists[id] {
+			app.storage.DeleteKnownUserKey(id)
+		}
+	}
+	if len(unknown) == 0 {
+		return
+	}
+
+	// On the first run, users that already exist are left alone unless "adopt_existing" is set.
+	baseline := MigrationStatus{}
+	app.storage.db.Get(ADOPTION_BASELINE_KEY, &baseline)
+	if !baseline.Done {
+		if !app.config.Section("user_adoption").Key("adopt_existing").MustBool(false) {
+			app.info.Printf(lm.MarkExistingUsers, len(unknown))
+			for _, user := range unknown {
+				app.storage.SetKnownUserKey(user.ID, KnownUser{Seen: time.Now()})
+			}
+			unknown = []mediabrowser.User{}
+		}
+		app.storage.db.Upsert(ADOPTION_BASELINE_KEY, MigrationStatus{true})
+	}
+
+	// Users created by jfa-go before adoption was enabled won't have been marked as known,
+	// so check the activity log for them too.
+	created := map[string]bool{}
+	if len(unknown) != 0 {
+		creations := []Activity{}
+		err := app.storage.db.Find(&creations, badgerhold.Where("Type").Eq(ActivityCreation))
+		if err != nil && err != badgerhold.ErrNotFound {
+			app.err.Printf(lm.FailedGetAdoptionData, err)
+			return
+		}
+		for _, act := range creations {
+			created[act.UserID] = true
+		}
+	}
+
+	adopted := false
+	for _, user := range unknown {
+		record := KnownUser{Seen: time.Now()}
+		// Admins are never adopted, as applying a profile would strip their privileges.
+		if !user.Policy.IsAdministrator && !created[user.ID] && !app.hasJfaGoRecord(user.ID) {
+			app.adoptUser(user)
+			record.Adopted = true
+			adopted = true
+		}
+		app.storage.SetKnownUserKey(user.ID, record)
+	}
+	if adopted {
+		app.InvalidateUserCaches()
+	}
+}
+
+// adoptUser applies the default profile, expiry and label (if enabled) to the given user, records the adoption and notifies admins.
+func (app *appContext) adoptUser(user mediabrowser.User) {
+	app.info.Printf(lm.AdoptUser, user.Name)
+	section := app.config.Section("user_adoption")
+
+	profileName := ""
+	if section.Key("apply_profile").MustBool(false) {
+		profile := app.storage.GetDefaultProfile()
+		if profile.Name == "" {
+			app.err.Printf(lm.FailedGetProfile, "default")
+		} else if err := app.ApplyProfileToUser(user.ID, &profile); err == nil {
+			profileName = profile.Name
+		}
+	}
+
+	var expiry time.Time
+	months := section.Key("expiry_months").MustInt(0)
+	days := section.Key("expiry_days").MustInt(0)
+	if months > 0 || days > 0 {
+		expiry = time.Now().AddDate(0, months, days)
+		app.storage.SetUserExpiryKey(user.ID, UserExpiry{Expiry: expiry})
+	}
+
+	label := section.Key("label").String()
+	if label != "" {
+		emailStore, _ := app.storage.GetEmailsKey(user.ID)
+		emailStore.Label = label
+		app.storage.SetEmailsKey(user.ID, emailStore)
+	}
+
+	app.storage.SetActivityKey(shortuuid.New(), Activity{
+		Type:       ActivityAdopted,
+		UserID:     user.ID,
+		SourceType: ActivityDaemon,
+		Value:      user.Name,
+		Time:       time.Now(),
+	}, nil, false)
+
+	if !messagesEnabled || !section.Key("notify_admins").MustBool(true) {
+		return
+	}
+	msg, err := app.email.constructAdopted(user.Name, profileName, label, expiry, time.Now(), false)
+	if err != nil {
+		app.err.Printf(lm.FailedConstructAdoptionAdmin, user.Name, err)
+		return
+	}
+	app.sendToAdmins(msg, func(recipient string, err error) {
+		if err != nil {
+			app.err.Printf(lm.FailedSendAdoptionAdmin, user.Name, recipient, err)
+		} else {
+			app.debug.Printf(lm.SentAdoptionAdmin, user.Name, recipient)
+		}
+	})
+}
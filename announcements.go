@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/hrfee/mediabrowser"
+)
+
+const (
+	RecurrenceNone    = ""
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+	// Number of sends kept in a ScheduledAnnouncement's history.
+	ANNOUNCEMENT_HISTORY_LENGTH = 20
+)
+
+var announcementRecurrences = []string{RecurrenceNone, RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly}
+
+// announcementUserVars are the variables available to announcements which differ between users.
+// If any are used, a message is constructed for each user.
+var announcementUserVars = []string{"username", "unsubscribeLink", "expiry", "expiryTime", "expiresIn", "label", "email", "referralLink", "myAccountURL", "profile"}
+
+func init() {
+	// Stored filters hold QueryDTO.Value as an interface, which may be a DateAttempt.
+	gob.Register(DateAttempt{})
+}
+
+// nextOccurrence returns the first time after "after" that an announcement starting at "start" recurs,
+// or a zero time if it doesn't recur. Monthly announcements are sent on the same day of each month,
+// or the last day of shorter months.
+func nextOccurrence(start time.Time, recurrence string, after time.Time) time.Time {
+	var step func(n int) time.Time
+	switch recurrence {
+	case RecurrenceDaily:
+		step = func(n int) time.Time { return start.AddDate(0, 0, n) }
+	case RecurrenceWeekly:
+		step = func(n int) time.Time { return start.AddDate(0, 0, 7*n) }
+	case RecurrenceMonthly:
+		step = func(n int) time.Time {
+			y, m, d := start.Date()
+			month := time.Date(y, m+time.Month(n), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+			lastDay := month.AddDate(0, 1, -1).Day()
+			return month.AddDate(0, 0, min(d, lastDay)-1)
+		}
+	default:
+		return time.Time{}
+	}
+	for n := 1; ; n++ {
+		if t := step(n); t.After(after) {
+			return t
+		}
+	}
+}
+
+// announcementTargets returns the IDs of users matching the given filter, or the given list of users if filter is nil.
+func (app *appContext) announcementTargets(users []string, filter *ServerFilterReqDTO) ([]string, error) {
+	if filter == nil {
+		return users, nil
+	}
+	userList, err := app.userCache.GetUserDTOs(app, false)
+	if err != nil {
+		return nil, err
+	}
+	matched := userList
+	if len(filter.SearchTerms) != 0 || len(filter.Queries) != 0 {
+		matched = app.userCache.Filter(userList, filter.SearchTerms, filter.Queries)
+	}
+	ids := make([]string, len(matched))
+	for i, user := range matched {
+		ids[i] = user.ID
+	}
+	return ids, nil
+}
+
+// announcementVars returns the values of announcementUserVars for the given user. Those that don't apply to them are left blank.
+func (app *appContext) announcementVars(user mediabrowser.User, message string) map[string]any {
+	vars := map[string]any{
+		"username":        user.Name,
+		"unsubscribeLink": app.unsubscribeURL(user.ID, MessageCategoryAnnouncements),
+	}
+	for _, v := range announcementUserVars {
+		if _, ok := vars[v]; !ok {
+			vars[v] = ""
+		}
+	}
+	if expiry, ok := app.storage.GetUserExpiryKey(user.ID); ok {
+		vars["expiry"], vars["expiryTime"], vars["expiresIn"] = app.email.formatExpiry(expiry.Expiry, false)
+	}
+	if emailStore, ok := app.storage.GetEmailsKey(user.ID); ok {
+		vars["label"] = emailStore.Label
+		vars["email"] = emailStore.Addr
+		vars["profile"] = emailStore.Profile
+	}
+	if app.config.Section("user_page").Key("enabled").MustBool(false) {
+		vars["myAccountURL"] = ExternalURI(nil) + PAGES.MyAccount
+		// Getting the referral link may create an invite, so only do it when needed.
+		if app.config.Section("user_page").Key("referrals").MustBool(false) && strings.Contains(message, "{referralLink}") {
+			if inv, ok := app.getOrCreateReferral(user.ID); ok {
+				vars["referralLink"] = ExternalURI(nil) + PAGES.Form + "/" + inv.Code
+			}
+		}
+	}
+	return vars
+}
+
+// sendAnnouncement constructs and sends an announcement to each of the given users, returning the number of users it couldn't be sent to.
+func (app *appContext) sendAnnouncement(subject, message string, users []string) (failed int, err error) {
+	var errs []error
+	// Generally, we only need to construct once. If any per-user variables are included, however, this needs to be done for each user.
+	unique := slices.ContainsFunc(announcementUserVars, func(v string) bool { return strings.Contains(message, "{"+v+"}") })
+	if !unique {
+		vars := map[string]any{}
+		for _, v := range announcementUserVars {
+			vars[v] = ""
+		}
+		msg, err := app.email.construct(AnnouncementCustomContent(subject), CustomContent{
+			Enabled: true,
+			Content: message,
+		}, vars)
+		if err != nil {
+			app.err.Printf(lm.FailedConstructAnnouncementMessage, "*", err)
+			return len(users), err
+		}
+		for _, userID := range users {
+			if err := app.sendByID(msg, MessageCategoryAnnouncements, userID); err != nil {
+				app.err.Printf(lm.FailedSendAnnouncementMessage, userID, "?", err)
+				errs = append(errs, err)
+				failed++
+			}
+		}
+		return failed, errors.Join(errs...)
+	}
+	for _, userID := range users {
+		user, err := app.jf.UserByID(userID, false)
+		if err != nil {
+			app.err.Printf(lm.FailedGetUser, userID, lm.Jellyfin, err)
+			errs = append(errs, err)
+			failed++
+			continue
+		}
+		msg, err := app.email.construct(AnnouncementCustomContent(subject), CustomContent{
+			Enabled: true,
+			Content: message,
+		}, app.announcementVars(user, message))
+		if err != nil {
+			app.err.Printf(lm.FailedConstructAnnouncementMessage, userID, err)
+			return len(users), err
+		}
+		if err := app.sendByID(msg, MessageCategoryAnnouncements, userID); err != nil {
+			app.err.Printf(lm.FailedSendAnnouncementMessage, userID, "?", err)
+			errs = append(errs, err)
+			failed++
+		}
+	}
+	return failed, errors.Join(errs...)
+}
+
+// sendScheduledAnnouncements sends any scheduled announcements that are due, and schedules the next send of recurring ones.
+func (app *appContext) sendScheduledAnnouncements() {
+	now := time.Now()
+	for _, a := range app.storage.GetScheduledAnnouncements() {
+		if a.NextSend.IsZero() || a.NextSend.After(now) {
+			continue
+		}
+		app.info.Printf(lm.SendScheduledAnnouncement, a.ID, a.Subject)
+		record := AnnouncementSend{Time: now}
+		users, err := app.announcementTargets(a.Users, a.Filter)
+		if err != nil {
+			app.err.Printf(lm.FailedGetUsers, lm.Jellyfin, err)
+			// Try again next time.
+			continue
+		}
+		record.Recipients = len(users)
+		record.Failed, _ = app.sendAnnouncement(a.Subject, a.Message, users)
+
+		a.History = append(a.History, record)
+		if len(a.History) > ANNOUNCEMENT_HISTORY_LENGTH {
+			a.History = a.History[len(a.History)-ANNOUNCEMENT_HISTORY_LENGTH:]
+		}
+		// If jfa-go wasn't running for some sends, they're skipped rather than all being sent at once.
+		a.NextSend = nextOccurrence(a.Start.Local(), a.Recurrence, now)
+		app.storage.SetScheduledAnnouncementKey(a.ID, a)
+	}
+}
+
+func newAnnouncementDaemon(interval time.Duration, app *appContext) *GenericDaemon {
+	d := NewGenericDaemon(interval, app,
+		func(app *appContext) {
+			app.sendScheduledAnnouncements()
+		},
+	)
+	d.Name("Announcement")
+	return d
+}
+
+func (a ScheduledAnnouncement) asDTO() ScheduledAnnouncementDTO {
+	dto := ScheduledAnnouncementDTO{
+		ID:         a.ID,
+		Subject:    a.Subject,
+		Message:    a.Message,
+		Users:      a.Users,
+		Filter:     a.Filter,
+		Recurrence: a.Recurrence,
+		Created:    a.Created.Unix(),
+		History:    make([]AnnouncementSendDTO, len(a.History)),
+	}
+	if !a.NextSend.IsZero() {
+		dto.NextSend = a.NextSend.Unix()
+	}
+	for i, s := range a.History {
+		dto.History[i] = AnnouncementSendDTO{Time: s.Time.Unix(), Recipients: s.Recipients, Failed: s.Failed}
+	}
+	return dto
+}
+
+// @Summary Get scheduled and recurring announcements, pending or not, and their send history.
+// @Produce json
+// @Success 200 {object} ScheduledAnnouncementsDTO
+// @Router /users/announce/scheduled [get]
+// @Security Bearer
+// @tags Users
+func (app *appContext) GetScheduledAnnouncements(gc *gin.Context) {
+	announcements := app.storage.GetScheduledAnnouncements()
+	slices.SortFunc(announcements, func(a, b ScheduledAnnouncement) int { return b.Created.Compare(a.Created) })
+	resp := ScheduledAnnouncementsDTO{Announcements: make([]ScheduledAnnouncementDTO, len(announcements))}
+	for i, a := range announcements {
+		resp.Announcements[i] = a.asDTO()
+	}
+	gc.JSON(200, resp)
+}
+
+// @Summary Cancel a scheduled announcement. Announcements that have already been sent are kept for their history, but won't be sent again.
+// @Produce json
+// @Param id path string true "ID of announcement"
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} boolResponse
+// @Router /users/announce/scheduled/{id} [delete]
+// @Security Bearer
+// @tags Users
+func (app *appContext) CancelScheduledAnnouncement(gc *gin.Context) {
+	id := gc.Param("id")
+	a, ok := app.storage.GetScheduledAnnouncementKey(id)
+	if !ok {
+		respondBool(400, false, gc)
+		return
+	}
+	if len(a.History) == 0 {
+		app.storage.DeleteScheduledAnnouncementKey(id)
+	} else {
+		a.NextSend = time.Time{}
+		app.storage.SetScheduledAnnouncementKey(id, a)
+	}
+	app.info.Printf(lm.CancelScheduledAnnouncement, id)
+	respondBool(200, true, gc)
+}
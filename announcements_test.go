@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	start := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		recurrence string
+		after      time.Time
+		want       time.Time
+	}{
+		{RecurrenceNone, start, time.Time{}},
+		{RecurrenceDaily, start, time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		{RecurrenceWeekly, start.Add(8 * 24 * time.Hour), time.Date(2024, time.February, 14, 9, 0, 0, 0, time.UTC)},
+		// Shorter months use their last day.
+		{RecurrenceMonthly, start, time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC)},
+		{RecurrenceMonthly, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 31, 9, 0, 0, 0, time.UTC)},
+		{RecurrenceMonthly, time.Date(2024, time.December, 31, 10, 0, 0, 0, time.UTC), time.Date(2025, time.January, 31, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := nextOccurrence(start, c.recurrence, c.after); !got.Equal(c.want) {
+			t.Errorf("nextOccurrence(%q, after %v): got %v, want %v", c.recurrence, c.after, got, c.want)
+		}
+	}
+
+	first := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if got := nextOccurrence(first, RecurrenceMonthly, first); !got.Equal(time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("first of the month recurrence: got %v", got)
+	}
+}
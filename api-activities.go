@@ -28,6 +28,20 @@ func activityTypeToString(v ActivityType) string {
 		return "createInvite"
 	case ActivityDeleteInvite:
 		return "deleteInvite"
+	case ActivityAdopted:
+		return "adopted"
+	case ActivityDowngraded:
+		return "downgraded"
+	case ActivityRenewed:
+		return "renewed"
+	case ActivityPayment:
+		return "payment"
+	case ActivityEmailUndeliverable:
+		return "emailUndeliverable"
+	case ActivityAdminCommand:
+		return "adminCommand"
+	case ActivityDiscordDeparture:
+		return "discordDeparture"
 	}
 	return "unknown"
 }
@@ -54,6 +68,20 @@ func stringToActivityType(v string) ActivityType {
 		return ActivityCreateInvite
 	case "deleteInvite":
 		return ActivityDeleteInvite
+	case "adopted":
+		return ActivityAdopted
+	case "downgraded":
+		return ActivityDowngraded
+	case "renewed":
+		return ActivityRenewed
+	case "payment":
+		return ActivityPayment
+	case "emailUndeliverable":
+		return ActivityEmailUndeliverable
+	case "adminCommand":
+		return ActivityAdminCommand
+	case "discordDeparture":
+		return ActivityDiscordDeparture
 	}
 	return ActivityUnknown
 }
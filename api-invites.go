@@ -142,9 +142,9 @@ func (app *appContext) sendAdminExpiryNotification(data Invite) *sync.WaitGroup
 			} else {
 				// Check whether notify "address" is an email address or Jellyfin ID
 				if strings.Contains(addr, "@") {
-					err = app.email.send(msg, addr)
+					err = app.messageQueue.Enqueue(msg, ChannelEmail, "", addr, addr)
 				} else {
-					err = app.sendByID(msg, addr)
+					err = app.sendByID(msg, MessageCategoryRequired, addr)
 				}
 				if err != nil {
 					app.err.Printf(lm.FailedSendExpiryAdmin, data.Code, addr, err)
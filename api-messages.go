@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -166,6 +167,24 @@ func (app *appContext) GetCustomMessageTemplate(gc *gin.Context) {
 			msg, err = app.email.constructConfirmation("", "", "", true)
 		case "UserExpired":
 			msg, err = app.email.constructUserExpired("", true)
+		case "UserAdopted":
+			msg, err = app.email.constructAdopted("", "", "", time.Time{}, time.Time{}, true)
+		case "AdminDigest":
+			msg, err = app.email.constructAdminDigest(AdminDigest{}, true)
+		case "AdminUserExpired":
+			msg, err = app.email.constructAdminEvent(AdminEventUserExpired, nil, time.Time{}, true)
+		case "AdminUserDeleted":
+			msg, err = app.email.constructAdminEvent(AdminEventUserDeleted, nil, time.Time{}, true)
+		case "AdminPasswordReset":
+			msg, err = app.email.constructAdminEvent(AdminEventPasswordReset, nil, time.Time{}, true)
+		case "AdminContactUnlinked":
+			msg, err = app.email.constructAdminEvent(AdminEventContactUnlinked, nil, time.Time{}, true)
+		case "AdminFailedLogin":
+			msg, err = app.email.constructAdminEvent(AdminEventFailedLogin, nil, time.Time{}, true)
+		case "AdminDiscordDeparture":
+			msg, err = app.email.constructAdminEvent(AdminEventDiscordDeparture, nil, time.Time{}, true)
+		case "AdminPaymentUnapplied":
+			msg, err = app.email.constructAdminEvent(AdminEventPaymentUnapplied, nil, time.Time{}, true)
 		case "Announcement":
 		case "UserPage":
 		case "UserLogin":
@@ -327,9 +346,22 @@ func (app *appContext) setContactPreferences(req SetContactPreferencesDTO, gc *g
 			contactPrefs.Matrix = &req.Matrix
 		}
 	}
+	if pushUser, ok := app.storage.GetPushKey(req.ID); ok {
+		change := pushUser.Contact != req.Push
+		pushUser.Contact = req.Push
+		app.storage.SetPushKey(req.ID, pushUser)
+		if change {
+			app.debug.Printf(lm.SetContactPrefForService, lm.Push, pushUser.Topic, req.Push)
+		}
+	}
 	if email, ok := app.storage.GetEmailsKey(req.ID); ok {
 		change := email.Contact != req.Email
 		email.Contact = req.Email
+		// Re-enabling contact through a bounced address assumes the issue's been fixed.
+		if change && req.Email {
+			email.Undeliverable = false
+			email.UndeliverableReason = ""
+		}
 		app.storage.SetEmailsKey(req.ID, email)
 		if change {
 			app.debug.Printf(lm.SetContactPrefForService, lm.Email, email.Addr, req.Email)
@@ -507,6 +539,69 @@ func (app *appContext) MatrixCheckPIN(gc *gin.Context) {
 	respondBool(200, true, gc)
 }
 
+// @Summary Generate and send a new PIN to a specified ntfy topic/Gotify application. NOTE: "/invite" might have been changed in Settings > URL Paths.
+// @Produce json
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} stringResponse
+// @Failure 401 {object} boolResponse
+// @Failure 500 {object} boolResponse
+// @Param invCode path string true "invite Code"
+// @Param PushSendPINDTO body PushSendPINDTO true "User's topic/token."
+// @Router /invite/{invCode}/push/user [post]
+// @tags Other
+func (app *appContext) PushSendPIN(gc *gin.Context) {
+	code := gc.Param("invCode")
+	if _, ok := app.storage.GetInvitesKey(code); !ok {
+		respondBool(401, false, gc)
+		return
+	}
+	var req PushSendPINDTO
+	gc.BindJSON(&req)
+	app.pushSendPIN(req, gc)
+}
+
+// pushSendPIN validates the given topic and sends a PIN to it, responding to the user.
+func (app *appContext) pushSendPIN(req PushSendPINDTO, gc *gin.Context) {
+	req.Topic = strings.TrimSpace(req.Topic)
+	if !ValidPushTopic(req.Topic) {
+		respond(400, "errorInvalidPushTopic", gc)
+		return
+	}
+	if app.push.UniqueRequired() && app.push.UserExists(req.Topic) {
+		respond(400, "errorAccountLinked", gc)
+		return
+	}
+	if !app.push.SendStart(req.Topic) {
+		respondBool(500, false, gc)
+		return
+	}
+	respondBool(200, true, gc)
+}
+
+// @Summary Check whether a push notification PIN is valid, and mark the token as verified if so. Requires invite code. NOTE: "/invite" might have been changed in Settings > URL Paths.
+// @Produce json
+// @Success 200 {object} boolResponse
+// @Failure 401 {object} boolResponse
+// @Param pin path string true "PIN code to check"
+// @Param invCode path string true "invite Code"
+// @Router /invite/{invCode}/push/verified/{pin} [get]
+// @tags Other
+func (app *appContext) PushCheckPIN(gc *gin.Context) {
+	code := gc.Param("invCode")
+	if _, ok := app.storage.GetInvitesKey(code); !ok {
+		app.debug.Printf(lm.InvalidInviteCode, code)
+		respondBool(401, false, gc)
+		return
+	}
+	pin := gc.Param("pin")
+	if _, ok := app.push.VerifyPIN(pin); !ok {
+		app.debug.Printf(lm.InvalidPIN, pin)
+		respondBool(200, false, gc)
+		return
+	}
+	respondBool(200, true, gc)
+}
+
 // @Summary Generates a Matrix access token from a username and password.
 // @Produce json
 // @Success 200 {object} boolResponse
@@ -571,6 +666,7 @@ func (app *appContext) MatrixConnect(gc *gin.Context) {
 		Lang:    "en-us",
 		Contact: true,
 	})
+	app.inviteToMatrixRooms(req.JellyfinID)
 	app.InvalidateWebUserCache()
 	respondBool(200, true, gc)
 }
@@ -626,6 +722,7 @@ func (app *appContext) DiscordConnect(gc *gin.Context) {
 	}
 
 	app.storage.SetDiscordKey(req.JellyfinID, user)
+	app.syncDiscordRolesForUser(req.JellyfinID)
 
 	for _, tps := range app.thirdPartyServices {
 		if err := tps.SetContactMethods(req.JellyfinID, nil, &user, nil, &common.ContactPreferences{
@@ -664,7 +761,11 @@ func (app *appContext) UnlinkDiscord(gc *gin.Context) {
 		respond(400, "User not found", gc)
 		return
 	} */
+	dcUser, linked := app.storage.GetDiscordKey(req.ID)
 	app.storage.DeleteDiscordKey(req.ID)
+	if linked {
+		app.syncDiscordMember(dcUser.ID, nil)
+	}
 
 	contact := false
 
@@ -676,7 +777,7 @@ func (app *appContext) UnlinkDiscord(gc *gin.Context) {
 		}
 	}
 
-	app.storage.SetActivityKey(shortuuid.New(), Activity{
+	app.recordActivity(Activity{
 		Type:       ActivityContactUnlinked,
 		UserID:     req.ID,
 		SourceType: ActivityAdmin,
@@ -716,7 +817,7 @@ func (app *appContext) UnlinkTelegram(gc *gin.Context) {
 		}
 	}
 
-	app.storage.SetActivityKey(shortuuid.New(), Activity{
+	app.recordActivity(Activity{
 		Type:       ActivityContactUnlinked,
 		UserID:     req.ID,
 		SourceType: ActivityAdmin,
@@ -729,6 +830,31 @@ func (app *appContext) UnlinkTelegram(gc *gin.Context) {
 	respondBool(200, true, gc)
 }
 
+// @Summary unlink a push notification topic from a Jellyfin user. Always succeeds.
+// @Produce json
+// @Success 200 {object} boolResponse
+// @Param forUserDTO body forUserDTO true "User's Jellyfin ID."
+// @Router /users/push [delete]
+// @Security Bearer
+// @Tags Users
+func (app *appContext) UnlinkPush(gc *gin.Context) {
+	var req forUserDTO
+	gc.BindJSON(&req)
+	app.storage.DeletePushKey(req.ID)
+
+	app.recordActivity(Activity{
+		Type:       ActivityContactUnlinked,
+		UserID:     req.ID,
+		SourceType: ActivityAdmin,
+		Source:     gc.GetString("jfId"),
+		Value:      "push",
+		Time:       time.Now(),
+	}, gc, false)
+
+	app.InvalidateWebUserCache()
+	respondBool(200, true, gc)
+}
+
 // @Summary unlink a Matrix account from a Jellyfin user. Always succeeds.
 // @Produce json
 // @Success 200 {object} boolResponse
@@ -746,7 +872,7 @@ func (app *appContext) UnlinkMatrix(gc *gin.Context) {
 	} */
 	app.storage.DeleteMatrixKey(req.ID)
 
-	app.storage.SetActivityKey(shortuuid.New(), Activity{
+	app.recordActivity(Activity{
 		Type:       ActivityContactUnlinked,
 		UserID:     req.ID,
 		SourceType: ActivityAdmin,
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/lithammer/shortuuid/v3"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	PAYMENT_SIGNATURE_HEADER = "Stripe-Signature"
+	PAYMENT_EVENT_RETENTION  = 30 * 24 * time.Hour
+)
+
+// PaymentWebhookEvent is the subset of a Stripe-compatible event we care about.
+type PaymentWebhookEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object PaymentWebhookObject `json:"object"`
+	} `json:"data"`
+}
+
+// PaymentWebhookObject covers the fields of Checkout Sessions, Invoices and PaymentIntents used to identify the payer and duration.
+type PaymentWebhookObject struct {
+	Customer        string `json:"customer"`
+	CustomerEmail   string `json:"customer_email"`
+	ReceiptEmail    string `json:"receipt_email"`
+	CustomerDetails struct {
+		Email string `json:"email"`
+	} `json:"customer_details"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Email returns the first email address given in the object.
+func (obj PaymentWebhookObject) Email() string {
+	for _, addr := range []string{obj.CustomerDetails.Email, obj.CustomerEmail, obj.ReceiptEmail} {
+		if addr != "" {
+			return addr
+		}
+	}
+	return ""
+}
+
+// verifyPaymentSignature checks a Stripe-style signature header ("t=<unix>,v1=<hex HMAC-SHA256 of "<t>.<body>">[,v1=...]")
+// against the given secret, rejecting signatures older than tolerance.
+func verifyPaymentSignature(header string, body []byte, secret string, tolerance time.Duration, now time.Time) error {
+	if secret == "" {
+		return errors.New("no secret set")
+	}
+	var timestamp string
+	signatures := []string{}
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errors.New("malformed signature header")
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if tolerance > 0 && math.Abs(now.Sub(time.Unix(unix, 0)).Seconds()) > tolerance.Seconds() {
+		return errors.New("signature timestamp outside tolerance")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, sig := range signatures {
+		decoded, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return errors.New("no matching signature")
+}
+
+// paymentDuration returns the time to add for the given payment, from its metadata if given, otherwise from the config.
+func (app *appContext) paymentDuration(obj PaymentWebhookObject) (months, days, hours int) {
+	months, mErr := strconv.Atoi(obj.Metadata["jfa_months"])
+	days, dErr := strconv.Atoi(obj.Metadata["jfa_days"])
+	hours, hErr := strconv.Atoi(obj.Metadata["jfa_hours"])
+	if mErr != nil && dErr != nil && hErr != nil {
+		months = app.config.Section("payment_webhook").Key("months").MustInt(1)
+		days = app.config.Section("payment_webhook").Key("days").MustInt(0)
+		hours = 0
+	}
+	return
+}
+
+// paymentUser finds the Jellyfin ID of the user who made the given payment, by stored customer ID, then email address.
+// If matched by email, the customer ID is stored for future payments.
+func (app *appContext) paymentUser(obj PaymentWebhookObject) (jfID string, ok bool) {
+	if obj.Customer != "" {
+		if customer, ok := app.storage.GetPaymentCustomerKey(obj.Customer); ok {
+			return customer.JellyfinID, true
+		}
+	}
+	addr := obj.Email()
+	if addr == "" {
+		return "", false
+	}
+	// Payment providers often change the case of addresses, so they're matched case-insensitively.
+	for _, emailStore := range app.storage.GetEmails() {
+		if !strings.EqualFold(emailStore.Addr, addr) {
+			continue
+		}
+		if _, err := app.jf.UserByID(emailStore.JellyfinID, false); err != nil {
+			continue
+		}
+		if obj.Customer != "" {
+			app.storage.SetPaymentCustomerKey(obj.Customer, PaymentCustomer{JellyfinID: emailStore.JellyfinID})
+		}
+		return emailStore.JellyfinID, true
+	}
+	return "", false
+}
+
+// notifyPaymentUnapplied tells admins a payment was received but not applied, for the given reason.
+func (app *appContext) notifyPaymentUnapplied(event PaymentWebhookEvent, reason string) {
+	payer := event.Data.Object.Email()
+	if payer == "" {
+		payer = event.Data.Object.Customer
+	}
+	if payer == "" {
+		payer = app.email.lang.AdminEvents.get("unknown")
+	}
+	app.notifyAdmins(AdminEventPaymentUnapplied, map[string]any{
+		"event":  event.ID,
+		"payer":  payer,
+		"reason": reason,
+	}, "")
+}
+
+// @Summary Receive a signed payment event and extend the paying user's expiry. Events follow Stripe's format, and are signed in the "Stripe-Signature" header.
+// @Produce json
+// @Param PaymentWebhookEvent body PaymentWebhookEvent true "Payment event"
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} boolResponse
+// @Router /webhooks/payment [post]
+// @tags Payments
+func (app *appContext) PaymentWebhook(gc *gin.Context) {
+	body, err := io.ReadAll(gc.Request.Body)
+	if err != nil {
+		respondBool(400, false, gc)
+		return
+	}
+	section := app.config.Section("payment_webhook")
+	if err := verifyPaymentSignature(gc.GetHeader(PAYMENT_SIGNATURE_HEADER), body, section.Key("secret").String(), app.paymentTolerance(), time.Now()); err != nil {
+		app.err.Printf(lm.FailedVerifyPayment, gc.ClientIP(), err)
+		respondBool(400, false, gc)
+		return
+	}
+	var event PaymentWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" {
+		app.err.Printf(lm.FailedParsePayment, err)
+		respondBool(400, false, gc)
+		return
+	}
+
+	accepted := strings.Split(section.Key("events").MustString("checkout.session.completed, invoice.paid"), ",")
+	if !slices.ContainsFunc(accepted, func(t string) bool { return strings.TrimSpace(t) == event.Type }) {
+		app.debug.Printf(lm.IgnorePaymentEvent, event.ID, event.Type)
+		respondBool(200, true, gc)
+		return
+	}
+
+	// Events can be retried or delivered concurrently, so only handle each once.
+	app.paymentEventsLock.Lock()
+	defer app.paymentEventsLock.Unlock()
+	if _, ok := app.storage.GetPaymentEventKey(event.ID); ok {
+		app.debug.Printf(lm.DuplicatePaymentEvent, event.ID)
+		respondBool(200, true, gc)
+		return
+	}
+	record := PaymentEvent{Type: event.Type, Received: time.Now()}
+	// Acknowledge the event even if it can't be applied, as retrying wouldn't change anything.
+	defer func() { app.storage.SetPaymentEventKey(event.ID, record) }()
+
+	obj := event.Data.Object
+	jfID, ok := app.paymentUser(obj)
+	if !ok {
+		app.err.Printf(lm.FailedMatchPayment, event.ID, obj.Customer, obj.Email())
+		app.notifyPaymentUnapplied(event, app.email.lang.AdminEvents.get("paymentNoUser"))
+		respondBool(200, true, gc)
+		return
+	}
+	record.JellyfinID = jfID
+	user, err := app.jf.UserByID(jfID, false)
+	if err != nil {
+		app.err.Printf(lm.FailedGetUser, jfID, lm.Jellyfin, err)
+		respondBool(500, false, gc)
+		return
+	}
+
+	// Users disabled on expiry usually no longer have one, but are who payments are most likely to be from.
+	lapsed := false
+	if user.Policy.IsDisabled {
+		_, lapsed = app.lastExpiryDisable(jfID)
+	}
+	if _, ok := app.storage.GetUserExpiryKey(jfID); !ok && !lapsed && !section.Key("create_expiry").MustBool(false) {
+		app.err.Printf(lm.PaymentNoExpiry, event.ID, jfID)
+		app.notifyPaymentUnapplied(event, app.email.lang.AdminEvents.template("paymentNoExpiry", tmpl{"name": user.Name}))
+		respondBool(200, true, gc)
+		return
+	}
+	months, days, hours := app.paymentDuration(obj)
+	if months <= 0 && days <= 0 && hours <= 0 {
+		app.err.Printf(lm.FailedApplyPayment, event.ID, jfID, "no duration")
+		app.notifyPaymentUnapplied(event, app.email.lang.AdminEvents.get("paymentNoDuration"))
+		respondBool(200, true, gc)
+		return
+	}
+	// An expiry that's passed is extended from now.
+	expiry := app.ExtendUserExpiry(jfID, ExpiryExtension{Months: months, Days: days, Hours: hours})
+	record.Expiry = expiry.Expiry
+	app.info.Printf(lm.ApplyPayment, event.ID, jfID, expiry.Expiry)
+	if lapsed {
+		err, _, activityType := app.SetUserDisabled(user, false)
+		if err != nil {
+			app.err.Printf(lm.FailedApplyTemplate, "policy", lm.Jellyfin, jfID, err)
+		} else {
+			app.info.Printf(lm.EnabledPaidUser, user.Name, event.ID)
+			app.storage.SetActivityKey(shortuuid.New(), Activity{
+				Type:       activityType,
+				UserID:     jfID,
+				SourceType: ActivityUser,
+				Source:     jfID,
+				Time:       time.Now(),
+			}, nil, false)
+		}
+	}
+
+	app.storage.SetActivityKey(shortuuid.New(), Activity{
+		Type:       ActivityPayment,
+		UserID:     jfID,
+		SourceType: ActivityUser,
+		Source:     jfID,
+		Value:      event.ID,
+		Time:       time.Now(),
+	}, nil, false)
+
+	if messagesEnabled && section.Key("notify_user").MustBool(true) {
+		go func() {
+			user, err := app.jf.UserByID(jfID, false)
+			if err != nil {
+				return
+			}
+			msg, err := app.email.constructExpiryAdjusted(user.Name, expiry.Expiry, "", false)
+			if err != nil {
+				app.err.Printf(lm.FailedConstructExpiryAdjustmentMessage, jfID, err)
+				return
+			}
+			if err := app.sendByID(msg, MessageCategoryExpiry, jfID); err != nil {
+				app.err.Printf(lm.FailedSendExpiryAdjustmentMessage, jfID, "?", err)
+			}
+		}()
+	}
+
+	app.InvalidateUserCaches()
+	respondBool(200, true, gc)
+}
+
+// paymentTolerance returns how long after being signed payment events are accepted. Zero means they always are.
+func (app *appContext) paymentTolerance() time.Duration {
+	return time.Duration(app.config.Section("payment_webhook").Key("tolerance").MustInt(300)) * time.Second
+}
+
+// clearPaymentEvents deletes processed payment events kept for at least PAYMENT_EVENT_RETENTION and longer than their signatures are accepted for.
+// With no tolerance, signatures are always accepted, so events are kept indefinitely to stop them being replayed.
+func (app *appContext) clearPaymentEvents() {
+	tolerance := app.paymentTolerance()
+	if tolerance <= 0 {
+		return
+	}
+	app.debug.Println(lm.HousekeepingPayments)
+	retention := max(PAYMENT_EVENT_RETENTION, tolerance)
+	err := app.storage.db.DeleteMatching(&PaymentEvent{}, badgerhold.Where("Received").Lt(time.Now().Add(-retention)))
+	if err != nil {
+		app.err.Printf(lm.FailedClearPaymentEvents, err)
+	}
+}
+
+// @Summary Get stored payment customer IDs and the users they're mapped to.
+// @Produce json
+// @Success 200 {object} GetPaymentCustomersDTO
+// @Router /payments/customers [get]
+// @Security Bearer
+// @tags Payments
+func (app *appContext) GetPaymentCustomers(gc *gin.Context) {
+	customers := app.storage.GetPaymentCustomers()
+	resp := GetPaymentCustomersDTO{Customers: make([]PaymentCustomerDTO, len(customers))}
+	for i, c := range customers {
+		resp.Customers[i] = PaymentCustomerDTO{CustomerID: c.CustomerID, UserID: c.JellyfinID}
+	}
+	gc.JSON(200, resp)
+}
+
+// @Summary Map a payment customer ID to a user, so their payments are applied to them regardless of email address.
+// @Produce json
+// @Param PaymentCustomerDTO body PaymentCustomerDTO true "Customer ID and Jellyfin ID"
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} boolResponse
+// @Router /payments/customers [post]
+// @Security Bearer
+// @tags Payments
+func (app *appContext) SetPaymentCustomer(gc *gin.Context) {
+	var req PaymentCustomerDTO
+	gc.BindJSON(&req)
+	if req.CustomerID == "" || req.UserID == "" {
+		respondBool(400, false, gc)
+		return
+	}
+	if _, err := app.jf.UserByID(req.UserID, false); err != nil {
+		app.err.Printf(lm.FailedGetUser, req.UserID, lm.Jellyfin, err)
+		respondBool(400, false, gc)
+		return
+	}
+	app.storage.SetPaymentCustomerKey(req.CustomerID, PaymentCustomer{JellyfinID: req.UserID})
+	respondBool(200, true, gc)
+}
+
+// @Summary Remove a stored payment customer ID.
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Success 200 {object} boolResponse
+// @Router /payments/customers/{id} [delete]
+// @Security Bearer
+// @tags Payments
+func (app *appContext) DeletePaymentCustomer(gc *gin.Context) {
+	app.storage.DeletePaymentCustomerKey(gc.Param("id"))
+	respondBool(200, true, gc)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func signPayment(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d.", timestamp)))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Tests verification of Stripe-style signature headers.
+func TestPaymentSignature(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":"evt_1","type":"checkout.session.completed"}`)
+	now := time.Date(2025, 8, 9, 12, 0, 0, 0, time.UTC)
+	ts := now.Add(-time.Minute).Unix()
+	sig := signPayment(secret, ts, body)
+	tolerance := 5 * time.Minute
+
+	cases := []struct {
+		name   string
+		header string
+		body   []byte
+		secret string
+		ok     bool
+	}{
+		{"valid", fmt.Sprintf("t=%d,v1=%s", ts, sig), body, secret, true},
+		{"valid among several", fmt.Sprintf("t=%d,v1=deadbeef,v1=%s,v0=abc", ts, sig), body, secret, true},
+		{"wrong secret", fmt.Sprintf("t=%d,v1=%s", ts, sig), body, "whsec_other", false},
+		{"no secret", fmt.Sprintf("t=%d,v1=%s", ts, sig), body, "", false},
+		{"modified body", fmt.Sprintf("t=%d,v1=%s", ts, sig), []byte(`{"id":"evt_2"}`), secret, false},
+		{"modified timestamp", fmt.Sprintf("t=%d,v1=%s", ts+1, sig), body, secret, false},
+		{"too old", fmt.Sprintf("t=%d,v1=%s", now.Add(-time.Hour).Unix(), signPayment(secret, now.Add(-time.Hour).Unix(), body)), body, secret, false},
+		{"no timestamp", "v1=" + sig, body, secret, false},
+		{"empty", "", body, secret, false},
+	}
+	for _, c := range cases {
+		err := verifyPaymentSignature(c.header, c.body, c.secret, tolerance, now)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: expected ok=%t, got err=%v", c.name, c.ok, err)
+		}
+	}
+}
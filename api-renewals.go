@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/lithammer/shortuuid/v3"
+)
+
+// checkRenewalCodes performs housekeeping on renewal codes, i.e. deleting expired ones.
+func (app *appContext) checkRenewalCodes() {
+	currentTime := time.Now()
+	for _, code := range app.storage.GetRenewalCodes() {
+		if !currentTime.After(code.ValidTill) {
+			continue
+		}
+		app.debug.Printf(lm.DeleteOldRenewalCode, code.Code)
+		app.storage.DeleteRenewalCodeKey(code.Code)
+	}
+}
+
+// @Summary Generate one or more renewal codes, which users can redeem on their user page to extend their expiry.
+// @Produce json
+// @Param GenerateRenewalCodesDTO body GenerateRenewalCodesDTO true "Renewal code settings"
+// @Success 200 {object} GetRenewalCodesDTO
+// @Failure 400 {object} boolResponse
+// @Router /renewals [post]
+// @Security Bearer
+// @tags Renewals
+func (app *appContext) GenerateRenewalCodes(gc *gin.Context) {
+	var req GenerateRenewalCodesDTO
+	gc.BindJSON(&req)
+	if req.Count <= 0 || (req.Months <= 0 && req.Days <= 0 && req.Hours <= 0 && req.Minutes <= 0) || (req.ValidMonths <= 0 && req.ValidDays <= 0) {
+		respondBool(400, false, gc)
+		return
+	}
+	app.debug.Printf(lm.GenerateRenewalCodes, req.Count)
+	currentTime := time.Now()
+	resp := GetRenewalCodesDTO{Codes: make([]RenewalCodeDTO, 0, req.Count)}
+	for range req.Count {
+		code := RenewalCode{
+			Code:      GenerateInviteCode(),
+			Created:   currentTime,
+			ValidTill: currentTime.AddDate(0, req.ValidMonths, req.ValidDays),
+			NoLimit:   req.NoLimit,
+			Months:    req.Months,
+			Days:      req.Days,
+			Hours:     req.Hours,
+			Minutes:   req.Minutes,
+			Label:     req.Label,
+		}
+		if !code.NoLimit {
+			code.RemainingUses = max(req.RemainingUses, 1)
+		}
+		app.storage.SetRenewalCodeKey(code.Code, code)
+		resp.Codes = append(resp.Codes, app.renewalCodeToDTO(code))
+	}
+	gc.JSON(200, resp)
+}
+
+func (app *appContext) renewalCodeToDTO(code RenewalCode) RenewalCodeDTO {
+	dto := RenewalCodeDTO{
+		Code:          code.Code,
+		Created:       code.Created.Unix(),
+		ValidTill:     code.ValidTill.Unix(),
+		NoLimit:       code.NoLimit,
+		RemainingUses: code.RemainingUses,
+		Months:        code.Months,
+		Days:          code.Days,
+		Hours:         code.Hours,
+		Minutes:       code.Minutes,
+		Label:         code.Label,
+	}
+	if len(code.UsedBy) != 0 {
+		dto.UsedBy = map[string]int64{}
+		for _, pair := range code.UsedBy {
+			unix, err := strconv.ParseInt(pair[1], 10, 64)
+			if err != nil {
+				app.err.Printf(lm.FailedParseTime, err)
+			}
+			name := pair[0]
+			if user, err := app.jf.UserByID(pair[0], false); err == nil {
+				name = user.Name
+			}
+			dto.UsedBy[name] = unix
+		}
+	}
+	return dto
+}
+
+// @Summary Get renewal codes.
+// @Produce json
+// @Success 200 {object} GetRenewalCodesDTO
+// @Router /renewals [get]
+// @Security Bearer
+// @tags Renewals
+func (app *appContext) GetRenewalCodes(gc *gin.Context) {
+	app.checkRenewalCodes()
+	codes := app.storage.GetRenewalCodes()
+	resp := GetRenewalCodesDTO{Codes: make([]RenewalCodeDTO, len(codes))}
+	for i, code := range codes {
+		resp.Codes[i] = app.renewalCodeToDTO(code)
+	}
+	gc.JSON(200, resp)
+}
+
+// @Summary Delete renewal codes.
+// @Produce json
+// @Param DeleteRenewalCodesDTO body DeleteRenewalCodesDTO true "Codes to delete"
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} boolResponse
+// @Router /renewals [delete]
+// @Security Bearer
+// @tags Renewals
+func (app *appContext) DeleteRenewalCodes(gc *gin.Context) {
+	var req DeleteRenewalCodesDTO
+	gc.BindJSON(&req)
+	if len(req.Codes) == 0 {
+		respondBool(400, false, gc)
+		return
+	}
+	for _, code := range req.Codes {
+		app.info.Printf(lm.DeleteRenewalCode, code)
+		app.storage.DeleteRenewalCodeKey(code)
+	}
+	respondBool(200, true, gc)
+}
+
+// redeemRenewalCode extends the given user's expiry by the duration of the given renewal code, restoring them if they'd been downgraded.
+// The returned string is a lang key describing the error to the user.
+func (app *appContext) redeemRenewalCode(jfID, codeStr string) (expiry UserExpiry, langKey string, err error) {
+	// Codes can be redeemed concurrently, so only let one redemption check and use up a code at a time.
+	app.renewalCodesLock.Lock()
+	defer app.renewalCodesLock.Unlock()
+	code, ok := app.storage.GetRenewalCodeKey(codeStr)
+	if !ok || time.Now().After(code.ValidTill) || (!code.NoLimit && code.RemainingUses <= 0) {
+		langKey = "errorInvalidRenewalCode"
+		err = errors.New(lm.InvalidRenewalCode)
+		return
+	}
+	if slices.ContainsFunc(code.UsedBy, func(pair []string) bool { return pair[0] == jfID }) {
+		langKey = "errorRenewalCodeUsed"
+		err = errors.New(lm.InvalidRenewalCode)
+		return
+	}
+	if _, ok := app.storage.GetUserExpiryKey(jfID); !ok {
+		// Without an expiry, the user's account doesn't need renewing, and giving them one would only shorten it.
+		langKey = "errorNoExpiry"
+		err = errors.New(lm.RenewalCodeNoExpiry)
+		return
+	}
+	expiry = app.ExtendUserExpiry(jfID, ExpiryExtension{Months: code.Months, Days: code.Days, Hours: code.Hours, Minutes: code.Minutes})
+
+	if !code.NoLimit {
+		code.RemainingUses--
+	}
+	code.UsedBy = append(code.UsedBy, []string{jfID, strconv.FormatInt(time.Now().Unix(), 10)})
+	app.storage.SetRenewalCodeKey(code.Code, code)
+	return
+}
+
+// @Summary Redeem a renewal code, extending the user's expiry.
+// @Produce json
+// @Param RedeemRenewalCodeDTO body RedeemRenewalCodeDTO true "Renewal code"
+// @Success 200 {object} RedeemRenewalCodeRespDTO
+// @Failure 400 {object} stringResponse
+// @Failure 500 {object} boolResponse
+// @Router /my/renewal [post]
+// @Security Bearer
+// @Tags User Page
+func (app *appContext) RedeemMyRenewalCode(gc *gin.Context) {
+	var req RedeemRenewalCodeDTO
+	gc.BindJSON(&req)
+	jfID := gc.GetString("jfId")
+	user, err := app.jf.UserByID(jfID, false)
+	if err != nil {
+		app.err.Printf(lm.FailedGetUser, jfID, lm.Jellyfin, err)
+		respondBool(500, false, gc)
+		return
+	}
+	expiry, langKey, err := app.redeemRenewalCode(jfID, req.Code)
+	if err != nil {
+		app.info.Printf(lm.FailedRedeemRenewal, req.Code, user.Name, err)
+		respond(400, langKey, gc)
+		return
+	}
+	app.info.Printf(lm.RedeemRenewalCode, user.Name, req.Code)
+
+	app.storage.SetActivityKey(shortuuid.New(), Activity{
+		Type:       ActivityRenewed,
+		UserID:     jfID,
+		SourceType: ActivityUser,
+		Source:     jfID,
+		Value:      req.Code,
+		Time:       time.Now(),
+	}, gc, true)
+
+	if messagesEnabled {
+		go func() {
+			msg, err := app.email.constructExpiryAdjusted(user.Name, expiry.Expiry, "", false)
+			if err != nil {
+				app.err.Printf(lm.FailedConstructExpiryAdjustmentMessage, jfID, err)
+				return
+			}
+			if err := app.sendByID(msg, MessageCategoryExpiry, jfID); err != nil {
+				app.err.Printf(lm.FailedSendExpiryAdjustmentMessage, jfID, "?", err)
+			}
+		}()
+	}
+
+	app.InvalidateUserCaches()
+	gc.JSON(200, RedeemRenewalCodeRespDTO{Expiry: expiry.Expiry.Unix()})
+}
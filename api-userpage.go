@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -49,6 +50,9 @@ func (app *appContext) MyDetails(gc *gin.Context) {
 
 	if exp, ok := app.storage.GetUserExpiryKey(user.ID); ok {
 		resp.Expiry = exp.Expiry.Unix()
+		if app.config.Section("user_expiry").Key("calendar_feed").MustBool(false) {
+			resp.CalendarToken = app.userCalendarToken(user.ID, false)
+		}
 	}
 
 	if emailEnabled {
@@ -83,6 +87,16 @@ func (app *appContext) MyDetails(gc *gin.Context) {
 		}
 	}
 
+	if pushEnabled {
+		resp.Push = &MyDetailsContactMethodsDTO{}
+		if push, ok := app.storage.GetPushKey(user.ID); ok {
+			resp.Push.Value = push.Topic
+			resp.Push.Enabled = push.Contact
+		}
+	}
+
+	resp.Preferences = app.messagePreferences(user.ID)
+
 	if app.config.Section("user_page").Key("referrals").MustBool(false) {
 		// 1. Look for existing template bound to this Jellyfin ID
 		//    If one exists, that means its just for us and so we
@@ -125,6 +139,41 @@ func (app *appContext) SetMyContactMethods(gc *gin.Context) {
 	app.setContactPreferences(req, gc)
 }
 
+// @Summary Sets which categories of message to receive on each contact method.
+// @Produce json
+// @Param MessagePreferencesDTO body MessagePreferencesDTO true "Map of categories to whether they're sent on each channel."
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} boolResponse
+// @Router /my/preferences [post]
+// @Security Bearer
+// @tags User Page
+func (app *appContext) SetMyMessagePreferences(gc *gin.Context) {
+	var req MessagePreferencesDTO
+	gc.BindJSON(&req)
+	prefs := MessagePreferences{Disabled: map[string]map[string]bool{}}
+	for category, channels := range req {
+		if !slices.Contains(messageCategories, category) {
+			respondBool(400, false, gc)
+			return
+		}
+		for channel, enabled := range channels {
+			if !slices.Contains(queueChannels, channel) {
+				respondBool(400, false, gc)
+				return
+			}
+			if enabled {
+				continue
+			}
+			if prefs.Disabled[category] == nil {
+				prefs.Disabled[category] = map[string]bool{}
+			}
+			prefs.Disabled[category][channel] = true
+		}
+	}
+	app.storage.SetMessagePreferencesKey(gc.GetString("jfId"), prefs)
+	respondBool(200, true, gc)
+}
+
 // @Summary Logout by deleting refresh token from cookies.
 // @Produce json
 // @Success 200 {object} boolResponse
@@ -316,6 +365,7 @@ func (app *appContext) GetMyPIN(gc *gin.Context) {
 	switch service {
 	case "discord":
 		resp.PIN = app.discord.NewAssignedAuthToken(gc.GetString("jfId"))
+		app.setDiscordOAuthCookie(gc, resp.PIN)
 		break
 	case "telegram":
 		resp.PIN = app.telegram.NewAssignedAuthToken(gc.GetString("jfId"))
@@ -353,6 +403,7 @@ func (app *appContext) MyDiscordVerifiedInvite(gc *gin.Context) {
 		dcUser.Contact = existingUser.Contact
 	}
 	app.storage.SetDiscordKey(gc.GetString("jfId"), dcUser)
+	app.syncDiscordRolesForUser(gc.GetString("jfId"))
 
 	if err := app.js.ModifyNotifications(gc.GetString("jfId"), map[jellyseerr.NotificationsField]any{
 		jellyseerr.FieldDiscord:        dcUser.ID,
@@ -498,6 +549,7 @@ func (app *appContext) MatrixCheckMyPIN(gc *gin.Context) {
 	}
 
 	app.storage.SetMatrixKey(gc.GetString("jfId"), mxUser)
+	app.inviteToMatrixRooms(gc.GetString("jfId"))
 
 	app.storage.SetActivityKey(shortuuid.New(), Activity{
 		Type:       ActivityContactLinked,
@@ -512,6 +564,58 @@ func (app *appContext) MatrixCheckMyPIN(gc *gin.Context) {
 	respondBool(200, true, gc)
 }
 
+// @Summary Generate and send a new PIN to your given ntfy topic/Gotify application.
+// @Produce json
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} stringResponse
+// @Failure 500 {object} boolResponse
+// @Param PushSendPINDTO body PushSendPINDTO true "User's topic/token."
+// @Router /my/push/user [post]
+// @Security Bearer
+// @tags User Page
+func (app *appContext) PushSendMyPIN(gc *gin.Context) {
+	var req PushSendPINDTO
+	gc.BindJSON(&req)
+	app.pushSendPIN(req, gc)
+}
+
+// @Summary Check whether your push notification PIN is valid, and link the topic to your account if so.
+// @Produce json
+// @Success 200 {object} boolResponse
+// @Param pin path string true "PIN code to check"
+// @Router /my/push/verified/{pin} [get]
+// @Security Bearer
+// @tags User Page
+func (app *appContext) PushCheckMyPIN(gc *gin.Context) {
+	pin := gc.Param("pin")
+	user, ok := app.push.VerifyPIN(pin)
+	if !ok {
+		app.debug.Printf(lm.InvalidPIN, pin)
+		respondBool(200, false, gc)
+		return
+	}
+
+	pushUser := *user
+	pushUser.Contact = true
+	if existingUser, ok := app.storage.GetPushKey(gc.GetString("jfId")); ok {
+		pushUser.Contact = existingUser.Contact
+	}
+
+	app.storage.SetPushKey(gc.GetString("jfId"), pushUser)
+
+	app.storage.SetActivityKey(shortuuid.New(), Activity{
+		Type:       ActivityContactLinked,
+		UserID:     gc.GetString("jfId"),
+		SourceType: ActivityUser,
+		Source:     gc.GetString("jfId"),
+		Value:      "push",
+		Time:       time.Now(),
+	}, gc, true)
+
+	app.push.DeleteVerifiedToken(pin)
+	respondBool(200, true, gc)
+}
+
 // @Summary unlink the Discord account from your Jellyfin user. Always succeeds.
 // @Produce json
 // @Success 200 {object} boolResponse
@@ -519,7 +623,11 @@ func (app *appContext) MatrixCheckMyPIN(gc *gin.Context) {
 // @Security Bearer
 // @Tags User Page
 func (app *appContext) UnlinkMyDiscord(gc *gin.Context) {
+	dcUser, linked := app.storage.GetDiscordKey(gc.GetString("jfId"))
 	app.storage.DeleteDiscordKey(gc.GetString("jfId"))
+	if linked {
+		app.syncDiscordMember(dcUser.ID, nil)
+	}
 
 	if err := app.js.ModifyNotifications(gc.GetString("jfId"), map[jellyseerr.NotificationsField]any{
 		jellyseerr.FieldDiscord:        jellyseerr.BogusIdentifier,
@@ -528,7 +636,7 @@ func (app *appContext) UnlinkMyDiscord(gc *gin.Context) {
 		app.err.Printf(lm.FailedSyncContactMethods, lm.Jellyseerr, err)
 	}
 
-	app.storage.SetActivityKey(shortuuid.New(), Activity{
+	app.recordActivity(Activity{
 		Type:       ActivityContactUnlinked,
 		UserID:     gc.GetString("jfId"),
 		SourceType: ActivityUser,
@@ -556,7 +664,7 @@ func (app *appContext) UnlinkMyTelegram(gc *gin.Context) {
 		app.err.Printf(lm.FailedSyncContactMethods, lm.Jellyseerr, err)
 	}
 
-	app.storage.SetActivityKey(shortuuid.New(), Activity{
+	app.recordActivity(Activity{
 		Type:       ActivityContactUnlinked,
 		UserID:     gc.GetString("jfId"),
 		SourceType: ActivityUser,
@@ -577,7 +685,7 @@ func (app *appContext) UnlinkMyTelegram(gc *gin.Context) {
 func (app *appContext) UnlinkMyMatrix(gc *gin.Context) {
 	app.storage.DeleteMatrixKey(gc.GetString("jfId"))
 
-	app.storage.SetActivityKey(shortuuid.New(), Activity{
+	app.recordActivity(Activity{
 		Type:       ActivityContactUnlinked,
 		UserID:     gc.GetString("jfId"),
 		SourceType: ActivityUser,
@@ -589,6 +697,27 @@ func (app *appContext) UnlinkMyMatrix(gc *gin.Context) {
 	respondBool(200, true, gc)
 }
 
+// @Summary unlink the push notification topic from your Jellyfin user. Always succeeds.
+// @Produce json
+// @Success 200 {object} boolResponse
+// @Router /my/push [delete]
+// @Security Bearer
+// @Tags User Page
+func (app *appContext) UnlinkMyPush(gc *gin.Context) {
+	app.storage.DeletePushKey(gc.GetString("jfId"))
+
+	app.recordActivity(Activity{
+		Type:       ActivityContactUnlinked,
+		UserID:     gc.GetString("jfId"),
+		SourceType: ActivityUser,
+		Source:     gc.GetString("jfId"),
+		Value:      "push",
+		Time:       time.Now(),
+	}, gc, true)
+
+	respondBool(200, true, gc)
+}
+
 // @Summary Generate & send a password reset link if the given username/email/contact method exists. Doesn't give you any info about it's success.
 // @Produce json
 // @Param address path string true "address/contact method associated w/ your account."
@@ -612,9 +741,6 @@ func (app *appContext) ResetMyPassword(gc *gin.Context) {
 		respondBool(400, false, gc)
 		return
 	}
-	var pwr InternalPWR
-	var err error
-
 	jfUser, ok := app.ReverseUserSearch(address, usernameAllowed, emailAllowed, contactMethodAllowed)
 	if !ok {
 		app.debug.Printf(lm.FailedGetUsers, lm.Jellyfin, "no results")
@@ -625,36 +751,15 @@ func (app *appContext) ResetMyPassword(gc *gin.Context) {
 		}
 		return
 	}
-	pwr, err = app.GenInternalReset(jfUser.ID)
-	if err != nil {
-		app.err.Printf(lm.FailedGetUsers, lm.Jellyfin, err)
-		for range timerWait {
-			respondBool(204, true, gc)
-			return
-		}
-		return
-	}
-	if app.internalPWRs == nil {
-		app.internalPWRs = map[string]InternalPWR{}
-	}
-	app.internalPWRs[pwr.PIN] = pwr
 	// FIXME: Send to all contact methods
-	msg, err := app.email.constructReset(
-		PasswordReset{
-			Pin:      pwr.PIN,
-			Username: pwr.Username,
-			Expiry:   pwr.Expiry,
-			Internal: true,
-		}, false,
-	)
+	pwr, msg, err := app.newInternalReset(jfUser.ID)
 	if err != nil {
-		app.err.Printf(lm.FailedConstructPWRMessage, pwr.Username, err)
 		for range timerWait {
 			respondBool(204, true, gc)
 			return
 		}
 		return
-	} else if err := app.sendByID(msg, jfUser.ID); err != nil {
+	} else if err := app.sendByID(msg, MessageCategoryRequired, jfUser.ID); err != nil {
 		app.err.Printf(lm.FailedSendPWRMessage, pwr.Username, "?", err)
 	} else {
 		app.info.Printf(lm.SentPWRMessage, pwr.Username, "?")
@@ -740,30 +845,22 @@ func (app *appContext) ChangeMyPassword(gc *gin.Context) {
 	respondBool(204, true, gc)
 }
 
-// @Summary Get or generate a new referral code.
-// @Produce json
-// @Success 200 {object} GetMyReferralRespDTO
-// @Failure 400 {object} boolResponse
-// @Failure 401 {object} boolResponse
-// @Failure 500 {object} boolResponse
-// @Router /my/referral [get]
-// @Security Bearer
-// @Tags User Page
-func (app *appContext) GetMyReferral(gc *gin.Context) {
+// getOrCreateReferral returns the user's referral invite, creating one from their referral template if necessary,
+// or renewing it if it's expired.
+func (app *appContext) getOrCreateReferral(jfID string) (Invite, bool) {
 	// 1. Look for existing template bound to this Jellyfin ID
 	//    If one exists, that means its just for us and so we
 	//    can use it directly.
 	inv := Invite{}
-	err := app.storage.db.FindOne(&inv, badgerhold.Where("ReferrerJellyfinID").Eq(gc.GetString("jfId")))
+	err := app.storage.db.FindOne(&inv, badgerhold.Where("ReferrerJellyfinID").Eq(jfID))
 	if err != nil {
 		// 2. Look for a template matching the key found in the user storage
 		//    Since this key is shared between users in a profile, we make a copy.
-		user, ok := app.storage.GetEmailsKey(gc.GetString("jfId"))
+		user, ok := app.storage.GetEmailsKey(jfID)
 		err = app.storage.db.Get(user.ReferralTemplateKey, &inv)
 		if !ok || err != nil || user.ReferralTemplateKey == "" {
 			app.debug.Printf(lm.FailedGetReferralTemplate, user.ReferralTemplateKey, err)
-			respondBool(400, false, gc)
-			return
+			return inv, false
 		}
 		inv.Code = GenerateInviteCode()
 		expiryDelta := inv.ValidTill.Sub(inv.Created)
@@ -774,7 +871,7 @@ func (app *appContext) GetMyReferral(gc *gin.Context) {
 			inv.ValidTill = inv.Created.Add(REFERRAL_EXPIRY_DAYS * 24 * time.Hour)
 		}
 		inv.IsReferral = true
-		inv.ReferrerJellyfinID = gc.GetString("jfId")
+		inv.ReferrerJellyfinID = jfID
 		app.storage.SetInvitesKey(inv.Code, inv)
 	} else if time.Now().After(inv.ValidTill) {
 		// 3. We found an invite for us, but it's expired.
@@ -783,14 +880,13 @@ func (app *appContext) GetMyReferral(gc *gin.Context) {
 		app.storage.DeleteInvitesKey(inv.Code)
 		if inv.UseReferralExpiry {
 			app.debug.Printf(lm.DeleteOldReferral, inv.Code)
-			user, ok := app.storage.GetEmailsKey(gc.GetString("jfId"))
+			user, ok := app.storage.GetEmailsKey(jfID)
 			if ok {
 				user.ReferralTemplateKey = ""
-				app.storage.SetEmailsKey(gc.GetString("jfId"), user)
+				app.storage.SetEmailsKey(jfID, user)
 			}
 			app.debug.Printf("Ignoring referral request, expired.")
-			respondBool(400, false, gc)
-			return
+			return inv, false
 		}
 		app.debug.Printf(lm.RenewOldReferral, inv.Code)
 		inv.Code = GenerateInviteCode()
@@ -798,6 +894,24 @@ func (app *appContext) GetMyReferral(gc *gin.Context) {
 		inv.ValidTill = inv.Created.Add(REFERRAL_EXPIRY_DAYS * 24 * time.Hour)
 		app.storage.SetInvitesKey(inv.Code, inv)
 	}
+	return inv, true
+}
+
+// @Summary Get or generate a new referral code.
+// @Produce json
+// @Success 200 {object} GetMyReferralRespDTO
+// @Failure 400 {object} boolResponse
+// @Failure 401 {object} boolResponse
+// @Failure 500 {object} boolResponse
+// @Router /my/referral [get]
+// @Security Bearer
+// @Tags User Page
+func (app *appContext) GetMyReferral(gc *gin.Context) {
+	inv, ok := app.getOrCreateReferral(gc.GetString("jfId"))
+	if !ok {
+		respondBool(400, false, gc)
+		return
+	}
 	app.InvalidateWebUserCache()
 	gc.JSON(200, GetMyReferralRespDTO{
 		Code:          inv.Code,
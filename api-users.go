@@ -62,6 +62,9 @@ func (app *appContext) NewUserFromAdmin(gc *gin.Context) {
 		}
 		app.storage.SetEmailsKey(nu.User.ID, *emailStore)
 	}
+	if nu.Created {
+		app.setUserProfile(nu.User.ID, profile.Name)
+	}
 
 	for _, tps := range app.thirdPartyServices {
 		if !tps.Enabled(app, &profile) {
@@ -157,6 +160,16 @@ func (app *appContext) NewUserFromInvite(gc *gin.Context) {
 				respond(400, "errorAccountLinked", gc)
 				return
 			}
+			if t, ok := cm.(*TelegramDaemon); ok {
+				if member, err := t.InRequiredChat(completeContactMethods[i].User.MethodID().(int64)); !member {
+					if err != nil {
+						app.err.Printf(lm.FailedGetTelegramChatMember, completeContactMethods[i].User.Name(), err)
+					}
+					app.info.Printf(lm.FailedLinkUser, cm.Name(), completeContactMethods[i].User.Name(), req.Code, lm.NotInRequiredChat)
+					respond(401, "errorTelegramChatMember", gc)
+					return
+				}
+			}
 			if err := cm.PostVerificationTasks(completeContactMethods[i].PIN, completeContactMethods[i].User); err != nil {
 				app.err.Printf(lm.FailedLinkUser, cm.Name(), completeContactMethods[i].User.Name(), req.Code, err)
 			}
@@ -303,6 +316,9 @@ func (app *appContext) PostNewUserFromInvite(nu NewUserData, req ConfirmationKey
 		}
 		app.storage.SetEmailsKey(nu.User.ID, emailStore)
 	}
+	if profile != nil {
+		app.setUserProfile(nu.User.ID, profile.Name)
+	}
 	if emailEnabled {
 		if app.config.Section("notifications").Key("enabled").MustBool(false) {
 			for address, settings := range invite.Notify {
@@ -316,9 +332,9 @@ func (app *appContext) PostNewUserFromInvite(nu NewUserData, req ConfirmationKey
 					} else {
 						// Check whether notify "addr" is an email address of Jellyfin ID
 						if strings.Contains(addr, "@") {
-							err = app.email.send(msg, addr)
+							err = app.messageQueue.Enqueue(msg, ChannelEmail, "", addr, addr)
 						} else {
-							err = app.sendByID(msg, addr)
+							err = app.sendByID(msg, MessageCategoryRequired, addr)
 						}
 						if err != nil {
 							app.err.Printf(lm.FailedSendCreationAdmin, req.Code, addr, err)
@@ -444,7 +460,7 @@ func (app *appContext) EnableDisableUsers(gc *gin.Context) {
 		}, gc, false)
 
 		if sendMail && req.Notify {
-			if err := app.sendByID(msg, user.ID); err != nil {
+			if err := app.sendByID(msg, MessageCategoryAccount, user.ID); err != nil {
 				app.err.Printf(lm.FailedSendEnableDisableMessage, user.ID, "?", err)
 				continue
 			}
@@ -500,7 +516,7 @@ func (app *appContext) DeleteUsers(gc *gin.Context) {
 
 		if deleted {
 			// Record activity
-			app.storage.SetActivityKey(shortuuid.New(), Activity{
+			app.recordActivity(Activity{
 				Type:       ActivityDeletion,
 				UserID:     userID,
 				SourceType: ActivityAdmin,
@@ -513,7 +529,7 @@ func (app *appContext) DeleteUsers(gc *gin.Context) {
 		// Contact details are stored separately and periodically removed,
 		// putting this here is hoping the daemon doesn't beat us.
 		if sendMail && req.Notify {
-			if err := app.sendByID(msg, user.ID); err != nil {
+			if err := app.sendByID(msg, MessageCategoryAccount, user.ID); err != nil {
 				app.err.Printf(lm.FailedSendDeletionMessage, userID, "?", err)
 			}
 		}
@@ -545,37 +561,16 @@ func (app *appContext) ExtendExpiry(gc *gin.Context) {
 		respondBool(400, false, gc)
 		return
 	}
+	ext := ExpiryExtension{
+		Months:                      req.Months,
+		Days:                        req.Days,
+		Hours:                       req.Hours,
+		Minutes:                     req.Minutes,
+		Timestamp:                   req.Timestamp,
+		TryExtendFromPreviousExpiry: req.TryExtendFromPreviousExpiry,
+	}
 	for _, id := range req.Users {
-		base := time.Now()
-		if expiry, ok := app.storage.GetUserExpiryKey(id); ok {
-			base = expiry.Expiry
-			app.debug.Printf(lm.FoundExistingExpiry)
-		} else if req.TryExtendFromPreviousExpiry {
-			var acts []Activity
-			app.storage.db.Find(&acts, badgerhold.Where("Type").Eq(ActivityDisabled).And("UserID").Eq(id).SortBy("Time").Reverse().Limit(1))
-			if len(acts) != 0 {
-				// Only do it if the most recent reason for disabling was expiry
-				if acts[0].SourceType == ActivityDaemon {
-					app.debug.Printf(lm.FoundPreviousExpiryLog, acts[0].Time)
-					newExpiry := acts[0].Time.AddDate(0, req.Months, req.Days).Add(time.Duration(((60 * req.Hours) + req.Minutes)) * time.Minute)
-					if newExpiry.After(base) {
-						base = acts[0].Time
-					} else {
-						app.debug.Printf(lm.ExpiryWouldBeInPast)
-					}
-				} else {
-					app.debug.Printf(lm.PreviousExpiryNotExpiry)
-				}
-			}
-		}
-		app.debug.Printf(lm.ExtendCreateExpiry, id)
-		expiry := UserExpiry{}
-		if req.Timestamp != 0 {
-			expiry.Expiry = time.Unix(req.Timestamp, 0)
-		} else {
-			expiry.Expiry = base.AddDate(0, req.Months, req.Days).Add(time.Duration(((60 * req.Hours) + req.Minutes)) * time.Minute)
-		}
-		app.storage.SetUserExpiryKey(id, expiry)
+		expiry := app.ExtendUserExpiry(id, ext)
 		if messagesEnabled && req.Notify {
 			go func(uid string, exp time.Time) {
 				user, err := app.jf.UserByID(uid, false)
@@ -587,13 +582,13 @@ func (app *appContext) ExtendExpiry(gc *gin.Context) {
 					app.err.Printf(lm.FailedConstructExpiryAdjustmentMessage, uid, err)
 					return
 				}
-				if err := app.sendByID(msg, uid); err != nil {
+				if err := app.sendByID(msg, MessageCategoryExpiry, uid); err != nil {
 					app.err.Printf(lm.FailedSendExpiryAdjustmentMessage, uid, "?", err)
 				}
 			}(id, expiry.Expiry)
 		}
 	}
-	app.InvalidateWebUserCache()
+	app.InvalidateUserCaches()
 	respondBool(204, true, gc)
 }
 
@@ -601,10 +596,25 @@ func (app *appContext) ExtendExpiry(gc *gin.Context) {
 // @Produce json
 // @Param id path string true "id of user to extend expiry of."
 // @Success 200 {object} boolResponse
+// @Failure 500 {object} boolResponse
 // @Router /users/{id}/expiry [delete]
 // @tags Users
 func (app *appContext) RemoveExpiry(gc *gin.Context) {
+	// Without an expiry, a downgraded user is no longer expired, so give them their old policy back.
+	expiry, ok := app.storage.GetUserExpiryKey(gc.Param("id"))
+	downgraded := ok && expiry.Downgraded
+	if downgraded {
+		if err := app.RestoreDowngradedUser(gc.Param("id"), expiry); err != nil {
+			app.err.Printf(lm.FailedRestoreDowngrade, gc.Param("id"), err)
+			respondBool(500, false, gc)
+			return
+		}
+		app.InvalidateJellyfinCache()
+	}
 	app.storage.DeleteUserExpiryKey(gc.Param("id"))
+	if downgraded {
+		app.syncDiscordRolesForUser(gc.Param("id"))
+	}
 	app.InvalidateWebUserCache()
 	respondBool(200, true, gc)
 }
@@ -700,7 +710,7 @@ func (app *appContext) DisableReferralForUsers(gc *gin.Context) {
 	respondBool(200, true, gc)
 }
 
-// @Summary Send an announcement via email to a given list of users.
+// @Summary Send an announcement via email to a given list of users, or those matching a search. If "send_at" is in the future or "recurrence" is set, the announcement is scheduled instead.
 // @Produce json
 // @Param announcementDTO body announcementDTO true "Announcement request object"
 // @Success 200 {object} boolResponse
@@ -716,45 +726,41 @@ func (app *appContext) Announce(gc *gin.Context) {
 		respondBool(400, false, gc)
 		return
 	}
-	// Generally, we only need to construct once. If {username} is included, however, this needs to be done for each user.
-	unique := strings.Contains(req.Message, "{username}")
-	if unique {
-		for _, userID := range req.Users {
-			user, err := app.jf.UserByID(userID, false)
-			if err != nil {
-				app.err.Printf(lm.FailedGetUser, userID, lm.Jellyfin, err)
-				continue
-			}
-			msg, err := app.email.construct(AnnouncementCustomContent(req.Subject), CustomContent{
-				Enabled: true,
-				Content: req.Message,
-			}, map[string]any{"username": user.Name})
-			if err != nil {
-				app.err.Printf(lm.FailedConstructAnnouncementMessage, userID, err)
-				respondBool(500, false, gc)
-				return
-			} else if err := app.sendByID(msg, userID); err != nil {
-				app.err.Printf(lm.FailedSendAnnouncementMessage, userID, "?", err)
-				respondBool(500, false, gc)
-				return
-			}
-		}
-		// app.info.Printf(lm.SentAnnouncementMessage, "*", "?")
-	} else {
-		msg, err := app.email.construct(AnnouncementCustomContent(req.Subject), CustomContent{
-			Enabled: true,
-			Content: req.Message,
-		}, map[string]any{"username": ""})
-		if err != nil {
-			app.err.Printf(lm.FailedConstructAnnouncementMessage, "*", err)
-			respondBool(500, false, gc)
-			return
-		} else if err := app.sendByID(msg, req.Users...); err != nil {
-			app.err.Printf(lm.FailedSendAnnouncementMessage, "*", "?", err)
-			respondBool(500, false, gc)
-			return
-		}
-		// app.info.Printf(lm.SentAnnouncementMessage, "*", "?")
+	if !slices.Contains(announcementRecurrences, req.Recurrence) {
+		app.debug.Printf(lm.InvalidRecurrence, req.Recurrence)
+		respondBool(400, false, gc)
+		return
+	}
+	sendAt := time.Unix(req.SendAt, 0)
+	if req.Recurrence != RecurrenceNone || (req.SendAt != 0 && sendAt.After(time.Now())) {
+		if req.SendAt == 0 {
+			sendAt = time.Now()
+		}
+		a := ScheduledAnnouncement{
+			Subject:    req.Subject,
+			Message:    req.Message,
+			Users:      req.Users,
+			Filter:     req.Filter,
+			Recurrence: req.Recurrence,
+			Start:      sendAt,
+			NextSend:   sendAt,
+			Created:    time.Now(),
+		}
+		id := shortuuid.New()
+		app.storage.SetScheduledAnnouncementKey(id, a)
+		app.info.Printf(lm.ScheduleAnnouncement, id, sendAt, req.Recurrence)
+		respondBool(200, true, gc)
+		return
+	}
+	users, err := app.announcementTargets(req.Users, req.Filter)
+	if err != nil {
+		app.err.Printf(lm.FailedGetUsers, lm.Jellyfin, err)
+		respondBool(500, false, gc)
+		return
+	}
+	if _, err := app.sendAnnouncement(req.Subject, req.Message, users); err != nil {
+		respondBool(500, false, gc)
+		return
 	}
 	app.info.Printf(lm.SentAnnouncementMessage, "*", "?")
 	respondBool(200, true, gc)
@@ -889,7 +895,7 @@ func (app *appContext) AdminPasswordReset(gc *gin.Context) {
 				app.err.Printf(lm.FailedConstructPWRMessage, id, err)
 				respondBool(500, false, gc)
 				return
-			} else if err := app.sendByID(msg, id); err != nil {
+			} else if err := app.sendByID(msg, MessageCategoryRequired, id); err != nil {
 				app.err.Printf(lm.FailedSendPWRMessage, id, sendAddress, err)
 			} else {
 				app.info.Printf(lm.SentPWRMessage, id, sendAddress)
@@ -904,7 +910,7 @@ func (app *appContext) AdminPasswordReset(gc *gin.Context) {
 }
 
 // userSummary functions the same as userSummary, but pulls from the given caches rather than the database.
-func (app *appContext) userSummary(jfUser mediabrowser.User, email *EmailAddress, expiry *UserExpiry, discord *DiscordUser, telegram *TelegramUser, matrix *MatrixUser, referralActive bool) respUser {
+func (app *appContext) userSummary(jfUser mediabrowser.User, email *EmailAddress, expiry *UserExpiry, discord *DiscordUser, telegram *TelegramUser, matrix *MatrixUser, push *PushUser, referralActive bool) respUser {
 	adminOnly := app.config.Section("ui").Key("admin_only").MustBool(true)
 	allowAll := app.config.Section("ui").Key("allow_all").MustBool(false)
 
@@ -921,6 +927,7 @@ func (app *appContext) userSummary(jfUser mediabrowser.User, email *EmailAddress
 	if email != nil {
 		user.Email = email.Addr
 		user.NotifyThroughEmail = email.Contact
+		user.EmailUndeliverable = email.Undeliverable
 		user.Label = email.Label
 		user.AccountsAdmin = (app.jellyfinLogin) && (email.Admin || (adminOnly && jfUser.Policy.IsAdministrator) || allowAll)
 	}
@@ -935,6 +942,10 @@ func (app *appContext) userSummary(jfUser mediabrowser.User, email *EmailAddress
 		user.Matrix = matrix.UserID
 		user.NotifyThroughMatrix = matrix.Contact
 	}
+	if push != nil {
+		user.Push = push.Topic
+		user.NotifyThroughPush = push.Contact
+	}
 	if discord != nil {
 		user.Discord = RenderDiscordUsername(*discord)
 		// user.Discord = discord.Username + "#" + discord.Discriminator
@@ -975,6 +986,12 @@ func (app *appContext) GetUserSummary(jfUser mediabrowser.User) respUser {
 			matrixPtr = &matrix
 		}
 	}
+	var pushPtr *PushUser = nil
+	if pushEnabled {
+		if push, ok := app.storage.GetPushKey(jfUser.ID); ok {
+			pushPtr = &push
+		}
+	}
 	referralsActive := false
 	// FIXME: Send referral data
 	referrerInv := Invite{}
@@ -986,7 +1003,7 @@ func (app *appContext) GetUserSummary(jfUser mediabrowser.User) respUser {
 		}
 		// 2. performed by userSummaryFixme
 	}
-	return app.userSummary(jfUser, emailPtr, expiryPtr, discordPtr, telegramPtr, matrixPtr, referralsActive)
+	return app.userSummary(jfUser, emailPtr, expiryPtr, discordPtr, telegramPtr, matrixPtr, pushPtr, referralsActive)
 }
 
 // @Summary Returns the total number of Jellyfin users.
@@ -1050,7 +1067,9 @@ func (app *appContext) GetUser(gc *gin.Context) {
 			return
 		}
 	}
-	gc.JSON(200, app.GetUserSummary(user))
+	summary := app.GetUserSummary(user)
+	summary.Deliveries = app.userDeliveries(user.ID)
+	gc.JSON(200, summary)
 }
 
 // @Summary Get a list of -all- Jellyfin users.
@@ -1226,6 +1245,11 @@ func (app *appContext) modifyEmail(jfID string, addr string) {
 			Contact: true,
 		}
 	}
+	// A new address hasn't bounced yet.
+	if emailStore.Addr != addr {
+		emailStore.Undeliverable = false
+		emailStore.UndeliverableReason = ""
+	}
 	emailStore.Addr = addr
 	app.storage.SetEmailsKey(jfID, emailStore)
 
@@ -1267,7 +1291,7 @@ func (app *appContext) ModifyEmails(gc *gin.Context) {
 			if address == "" {
 				activityType = ActivityContactUnlinked
 			}
-			app.storage.SetActivityKey(shortuuid.New(), Activity{
+			app.recordActivity(Activity{
 				Type:       activityType,
 				UserID:     id,
 				SourceType: ActivityAdmin,
@@ -1379,6 +1403,12 @@ func (app *appContext) ApplySettings(gc *gin.Context) {
 			err = app.jf.SetPolicy(id, policy)
 			if err != nil {
 				errors["policy"][id] = err.Error()
+			} else {
+				// Otherwise, the new policy would be overwritten if the user's restored from a downgrade.
+				app.updateDowngradedPolicy(id, func(p *mediabrowser.Policy) { *p = policy })
+				if req.From == "profile" {
+					app.setUserProfile(id, req.Profile)
+				}
 			}
 		}
 		if shouldDelay {
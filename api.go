@@ -10,7 +10,6 @@ import (
 	lm "github.com/hrfee/jfa-go/logmessages"
 	"github.com/hrfee/mediabrowser"
 	"github.com/itchyny/timefmt-go"
-	"github.com/lithammer/shortuuid/v3"
 	"gopkg.in/ini.v1"
 )
 
@@ -174,7 +173,7 @@ func (app *appContext) ResetSetPassword(gc *gin.Context) {
 		return
 	}
 
-	app.storage.SetActivityKey(shortuuid.New(), Activity{
+	app.recordActivity(Activity{
 		Type:       ActivityResetPassword,
 		UserID:     user.ID,
 		SourceType: ActivityUser,
@@ -229,6 +228,7 @@ func (app *appContext) GetConfig(gc *gin.Context) {
 	if discordEnabled {
 		app.PatchConfigDiscordRoles()
 	}
+	app.PatchConfigProfiles()
 	gc.JSON(200, app.patchedConfig)
 }
 
@@ -207,6 +207,9 @@ func (app *appContext) validateJellyfinCredentials(username, password string, gc
 	if err != nil {
 		if errors.As(err, &mediabrowser.ErrUnauthorized{}) {
 			app.logIpInfo(gc, userpage, fmt.Sprintf(lm.FailedAuthRequest, lm.InvalidUserOrPass))
+			if !userpage {
+				app.notifyAdminsOfFailedLogin(username, gc)
+			}
 			respond(401, "Unauthorized", gc)
 			return
 		} else if errors.As(err, &mediabrowser.ErrForbidden{}) {
@@ -247,6 +250,7 @@ func (app *appContext) getTokenLogin(gc *gin.Context) {
 	}
 	if !app.jellyfinLogin && !match {
 		app.logIpInfo(gc, false, fmt.Sprintf(lm.FailedAuthRequest, lm.InvalidUserOrPass))
+		app.notifyAdminsOfFailedLogin(username, gc)
 		respond(401, "Unauthorized", gc)
 		return
 	}
@@ -260,6 +264,7 @@ func (app *appContext) getTokenLogin(gc *gin.Context) {
 		accountsAdmin := app.canAccessAdminPage(user, emailStore)
 		if !accountsAdmin {
 			app.authLog(fmt.Sprintf(lm.NonAdminUser, username))
+			app.notifyAdminsOfFailedLogin(username, gc)
 			respond(401, "Unauthorized", gc)
 			return
 		}
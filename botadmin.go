@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hrfee/mediabrowser"
+)
+
+// parseDurationString parses durations of the form "1mo2w3d4h30min", where every unit is optional.
+func parseDurationString(s string) (months, days, hours, minutes int, ok bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return
+	}
+	for s != "" {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return 0, 0, 0, 0, false
+		}
+		n, _ := strconv.Atoi(s[:i])
+		s = s[i:]
+		switch {
+		case strings.HasPrefix(s, "min"):
+			minutes += n
+			s = s[3:]
+		case strings.HasPrefix(s, "mo"):
+			months += n
+			s = s[2:]
+		case strings.HasPrefix(s, "w"):
+			days += 7 * n
+			s = s[1:]
+		case strings.HasPrefix(s, "d"):
+			days += n
+			s = s[1:]
+		case strings.HasPrefix(s, "h"):
+			hours += n
+			s = s[1:]
+		default:
+			return 0, 0, 0, 0, false
+		}
+	}
+	ok = months != 0 || days != 0 || hours != 0 || minutes != 0
+	return
+}
+
+// botInviteOptions are the options for an invite created through a chat bot's admin commands.
+type botInviteOptions struct {
+	Months, Days, Hours, Minutes int
+	Profile                      string
+	UserLabel                    string
+}
+
+// parseBotInviteOptions parses options given as "expiry=<duration>", "profile=<name>" and "label=<user label>".
+// If one is invalid, the name of the lang string to reply with and its values are returned.
+func (app *appContext) parseBotInviteOptions(opts []string) (o botInviteOptions, errKey string, errVals tmpl) {
+	o = botInviteOptions{Minutes: 30, Profile: app.storage.GetDefaultProfile().Name}
+	for _, opt := range opts {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "expiry":
+			var ok bool
+			o.Months, o.Days, o.Hours, o.Minutes, ok = parseDurationString(value)
+			if !ok {
+				return o, "invalidDuration", tmpl{"duration": value}
+			}
+		case "label", "user_label":
+			o.UserLabel = value
+		case "profile":
+			if _, ok := app.storage.GetProfileKey(value); !ok {
+				return o, "profileNotFound", tmpl{"profile": value}
+			}
+			o.Profile = value
+		}
+	}
+	return
+}
+
+// createBotInvite stores a single-use invite with the given options, and records its creation by the given admin.
+func (app *appContext) createBotInvite(o botInviteOptions, label, adminID string) Invite {
+	currentTime := time.Now()
+	invite := Invite{
+		Code:          GenerateInviteCode(),
+		Created:       currentTime,
+		RemainingUses: 1,
+		ValidTill:     currentTime.AddDate(0, o.Months, o.Days).Add(time.Duration(60*o.Hours+o.Minutes) * time.Minute),
+		UserLabel:     o.UserLabel,
+		Profile:       o.Profile,
+		Label:         label,
+	}
+	app.storage.SetInvitesKey(invite.Code, invite)
+	app.recordActivity(Activity{
+		Type:       ActivityCreateInvite,
+		SourceType: ActivityAdmin,
+		Source:     adminID,
+		InviteCode: invite.Code,
+		Value:      invite.Label,
+		Time:       currentTime,
+	}, nil, false)
+	return invite
+}
+
+// recordAdminCommand records a command run through a chat bot by the given admin in the activity log.
+func (app *appContext) recordAdminCommand(adminID, command string) {
+	app.recordActivity(Activity{
+		Type:       ActivityAdminCommand,
+		SourceType: ActivityAdmin,
+		Source:     adminID,
+		Value:      command,
+		Time:       time.Now(),
+	}, nil, false)
+}
+
+// botActivityDescription describes an activity log entry for chat bot admin commands.
+func (app *appContext) botActivityDescription(act Activity, strs langSection) string {
+	vals := tmpl{"value": act.Value}
+	switch act.Type {
+	case ActivityCreation, ActivityDeletion:
+		vals["user"] = act.Value
+	case ActivityAdminCommand:
+		vals["user"] = app.usernameOrID(act.Source)
+	default:
+		vals["user"] = app.usernameOrID(act.UserID)
+	}
+	if act.InviteCode != "" {
+		vals["invite"] = app.inviteName(act.InviteCode)
+	}
+	typ := activityTypeToString(act.Type)
+	desc := strs.template("activity"+strings.ToUpper(typ[:1])+typ[1:], vals)
+	if act.SourceType == ActivityAdmin && act.Source != "" && act.Type != ActivityAdminCommand {
+		desc += " (" + strs.template("byAdmin", tmpl{"admin": app.usernameOrID(act.Source)}) + ")"
+	}
+	return desc
+}
+
+// botUserSummary describes a user's account for chat bot admin commands, as "field: value" lines.
+func (app *appContext) botUserSummary(jfUser mediabrowser.User, strs langSection) []string {
+	user := app.GetUserSummary(jfUser)
+	never := strs.get("never")
+	status := strs.get("enabled")
+	if user.Disabled {
+		status = strs.get("disabled")
+	}
+	if user.Admin || user.AccountsAdmin {
+		status += ", " + strs.get("admin")
+	}
+	expiry, lastActive := never, never
+	if user.Expiry != 0 {
+		expiry = formatDatetime(time.Unix(user.Expiry, 0))
+	}
+	if user.LastActive > 0 {
+		lastActive = formatDatetime(time.Unix(user.LastActive, 0))
+	}
+	contact := []string{}
+	for _, method := range []string{user.Email, user.Discord, user.Telegram, user.Matrix} {
+		if method != "" {
+			contact = append(contact, method)
+		}
+	}
+	lines := []string{
+		fmt.Sprintf("%s: %s", strs.get("status"), status),
+		fmt.Sprintf("%s: %s", strs.get("expiry"), expiry),
+		fmt.Sprintf("%s: %s", strs.get("lastActive"), lastActive),
+	}
+	if user.Label != "" {
+		lines = append(lines, fmt.Sprintf("%s: %s", strs.get("label"), user.Label))
+	}
+	if len(contact) != 0 {
+		lines = append(lines, fmt.Sprintf("%s: %s", strs.get("contactMethods"), strings.Join(contact, ", ")))
+	}
+	return lines
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestParseDurationString(t *testing.T) {
+	type result struct {
+		months, days, hours, minutes int
+		ok                           bool
+	}
+	cases := map[string]result{
+		"1mo2w3d4h30min": {1, 17, 4, 30, true},
+		"30min":          {0, 0, 0, 30, true},
+		"2D":             {0, 2, 0, 0, true},
+		"1w1w":           {0, 14, 0, 0, true},
+		"3mo":            {3, 0, 0, 0, true},
+		"":               {0, 0, 0, 0, false},
+		"0d":             {0, 0, 0, 0, false},
+		"5":              {0, 0, 0, 0, false},
+		"d":              {0, 0, 0, 0, false},
+		"2y":             {0, 0, 0, 0, false},
+	}
+	for in, want := range cases {
+		months, days, hours, minutes, ok := parseDurationString(in)
+		got := result{months, days, hours, minutes, ok}
+		if got != want {
+			t.Errorf("parseDurationString(%q) = %+v, want %+v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hrfee/jfa-go/common"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/lithammer/shortuuid/v3"
+)
+
+const (
+	BOUNCE_TOKEN_HEADER = "X-Bounce-Token"
+)
+
+// BounceEvent is a bounce or complaint for a single address, parsed from one of the accepted formats.
+type BounceEvent struct {
+	Address   string
+	Complaint bool
+	Permanent bool // Temporary failures (e.g. a full mailbox) are ignored.
+	Reason    string
+}
+
+// GenericBounceDTO is the simplest accepted format, for use with scripts or providers without a dedicated parser.
+type GenericBounceDTO struct {
+	Type      string `json:"type" example:"bounce"` // "bounce" or "complaint".
+	Email     string `json:"email" example:"jeff@jellyf.in"`
+	Permanent *bool  `json:"permanent,omitempty"` // Whether or not the failure is permanent, defaults to true.
+	Reason    string `json:"reason,omitempty" example:"550 5.1.1 User unknown"`
+}
+
+// MailgunBounceEvent covers the parts of Mailgun's "failed" and "complained" webhook events we care about.
+type MailgunBounceEvent struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		Event          string `json:"event"`
+		Severity       string `json:"severity"`
+		Recipient      string `json:"recipient"`
+		Reason         string `json:"reason"`
+		DeliveryStatus struct {
+			Message     string `json:"message"`
+			Description string `json:"description"`
+		} `json:"delivery-status"`
+	} `json:"event-data"`
+}
+
+// parseGenericBounce parses a GenericBounceDTO.
+func parseGenericBounce(body []byte) ([]BounceEvent, error) {
+	var req GenericBounceDTO
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Email == "" {
+		return nil, errors.New("no email address given")
+	}
+	ev := BounceEvent{Address: req.Email, Permanent: true, Reason: req.Reason}
+	switch strings.ToLower(req.Type) {
+	case "bounce", "":
+	case "complaint":
+		ev.Complaint = true
+	default:
+		return nil, errors.New("unknown type \"" + req.Type + "\"")
+	}
+	if req.Permanent != nil {
+		ev.Permanent = *req.Permanent
+	}
+	return []BounceEvent{ev}, nil
+}
+
+// parseMailgunBounce parses a MailgunBounceEvent. Events other than "failed" and "complained" are ignored.
+func parseMailgunBounce(event MailgunBounceEvent) []BounceEvent {
+	data := event.EventData
+	if data.Recipient == "" {
+		return []BounceEvent{}
+	}
+	ev := BounceEvent{Address: data.Recipient, Permanent: true}
+	switch data.Event {
+	case "failed":
+		ev.Permanent = data.Severity == "permanent"
+		for _, reason := range []string{data.DeliveryStatus.Description, data.DeliveryStatus.Message, data.Reason} {
+			if reason != "" {
+				ev.Reason = reason
+				break
+			}
+		}
+	case "complained":
+		ev.Complaint = true
+	default:
+		return []BounceEvent{}
+	}
+	return []BounceEvent{ev}
+}
+
+// parseDSN reads the per-recipient fields of a delivery status notification (RFC 3464), or the fields of an
+// abuse feedback report (RFC 5965). The whole report email, or just its "message/delivery-status" or
+// "message/feedback-report" part can be given. Only recipients with the action "failed" are returned.
+func parseDSN(body []byte) []BounceEvent {
+	events := []BounceEvent{}
+	var current *BounceEvent
+	action := ""
+	finish := func() {
+		if current != nil && current.Address != "" && (current.Complaint || action == "failed") {
+			events = append(events, *current)
+		}
+		current = nil
+		action = ""
+	}
+	// Header fields can be folded over multiple lines, so unfold them first.
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) != 0 && line != "" && (line[0] == ' ' || line[0] == '\t') {
+			lines[len(lines)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	complaint := false
+	for _, line := range lines {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "feedback-type":
+			complaint = true
+		case "final-recipient", "original-rcpt-to":
+			finish()
+			// Final-Recipient is given as "<type>; <address>".
+			if _, addr, ok := strings.Cut(v, ";"); ok {
+				v = addr
+			}
+			current = &BounceEvent{Address: strings.Trim(strings.TrimSpace(v), "<>"), Complaint: complaint, Permanent: true}
+		case "action":
+			if current != nil {
+				action = strings.ToLower(v)
+			}
+		case "status":
+			if current != nil {
+				// 5.x.x are permanent failures, 4.x.x temporary.
+				current.Permanent = !strings.HasPrefix(v, "4")
+				if current.Reason == "" {
+					current.Reason = v
+				}
+			}
+		case "diagnostic-code":
+			if current != nil {
+				if _, code, ok := strings.Cut(v, ";"); ok {
+					v = strings.TrimSpace(code)
+				}
+				current.Reason = v
+			}
+		}
+	}
+	finish()
+	return events
+}
+
+// verifyMailgunSignature checks the signature of a Mailgun webhook event (hex HMAC-SHA256 of "<timestamp><token>"),
+// rejecting signatures older than tolerance.
+func verifyMailgunSignature(event MailgunBounceEvent, key string, tolerance time.Duration, now time.Time) error {
+	if key == "" {
+		return errors.New("no signing key set")
+	}
+	sig := event.Signature
+	unix, err := strconv.ParseInt(sig.Timestamp, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if tolerance > 0 && math.Abs(now.Sub(time.Unix(unix, 0)).Seconds()) > tolerance.Seconds() {
+		return errors.New("signature timestamp outside tolerance")
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(sig.Timestamp))
+	mac.Write([]byte(sig.Token))
+	decoded, err := hex.DecodeString(sig.Signature)
+	if err != nil || !hmac.Equal(decoded, mac.Sum(nil)) {
+		return errors.New("no matching signature")
+	}
+	return nil
+}
+
+// verifyBounceToken checks the shared secret given in the X-Bounce-Token header, as a bearer token, or in the "token" query parameter.
+func verifyBounceToken(gc *gin.Context, secret string) error {
+	if secret == "" {
+		return errors.New("no secret set")
+	}
+	token := gc.GetHeader(BOUNCE_TOKEN_HEADER)
+	if token == "" {
+		token, _ = strings.CutPrefix(gc.GetHeader("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		token = gc.Query("token")
+	}
+	if !hmac.Equal([]byte(token), []byte(secret)) {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+// markUndeliverable marks all stored addresses matching the event as undeliverable and disables contact through them.
+// Returns false if no users have the address.
+func (app *appContext) markUndeliverable(ev BounceEvent) bool {
+	found := false
+	for _, emailStore := range app.storage.GetEmails() {
+		if !strings.EqualFold(emailStore.Addr, ev.Address) {
+			continue
+		}
+		found = true
+		if emailStore.Undeliverable {
+			continue
+		}
+		emailStore.Undeliverable = true
+		emailStore.UndeliverableReason = ev.Reason
+		if ev.Complaint {
+			emailStore.UndeliverableReason = "complaint"
+		}
+		emailStore.Contact = false
+		app.storage.SetEmailsKey(emailStore.JellyfinID, emailStore)
+		app.info.Printf(lm.MarkEmailUndeliverable, emailStore.Addr, emailStore.JellyfinID, emailStore.UndeliverableReason)
+
+		for _, tps := range app.thirdPartyServices {
+			if err := tps.SetContactMethods(emailStore.JellyfinID, nil, nil, nil, &common.ContactPreferences{
+				Email: &(emailStore.Contact),
+			}); err != nil {
+				app.err.Printf(lm.FailedSetEmailAddress, tps.Name(), emailStore.JellyfinID, err)
+			}
+		}
+
+		app.storage.SetActivityKey(shortuuid.New(), Activity{
+			Type:       ActivityEmailUndeliverable,
+			UserID:     emailStore.JellyfinID,
+			SourceType: ActivityAnon,
+			Value:      emailStore.UndeliverableReason,
+			Time:       time.Now(),
+		}, nil, false)
+	}
+	return found
+}
+
+// @Summary Receive a bounce or complaint notification, and mark the address as undeliverable. Accepts a GenericBounceDTO, a Mailgun "failed"/"complained" webhook event (signed with the Mailgun signing key), or a raw delivery status notification/feedback report. Apart from Mailgun events, the shared secret must be given in the "X-Bounce-Token" header, as a bearer token, or in the "token" query parameter.
+// @Produce json
+// @Param GenericBounceDTO body GenericBounceDTO true "Bounce notification"
+// @Param token query string false "Shared secret"
+// @Success 200 {object} boolResponse
+// @Failure 400 {object} boolResponse
+// @Router /webhooks/bounce [post]
+// @tags Other
+func (app *appContext) BounceWebhook(gc *gin.Context) {
+	body, err := io.ReadAll(gc.Request.Body)
+	if err != nil {
+		respondBool(400, false, gc)
+		return
+	}
+	section := app.config.Section("email_bounces")
+	var events []BounceEvent
+	if strings.Contains(gc.ContentType(), "json") {
+		var mgEvent MailgunBounceEvent
+		if err := json.Unmarshal(body, &mgEvent); err == nil && mgEvent.EventData.Event != "" {
+			tolerance := time.Duration(section.Key("tolerance").MustInt(300)) * time.Second
+			if err := verifyMailgunSignature(mgEvent, section.Key("mailgun_signing_key").String(), tolerance, time.Now()); err != nil {
+				app.err.Printf(lm.FailedVerifyBounce, gc.ClientIP(), err)
+				respondBool(400, false, gc)
+				return
+			}
+			events = parseMailgunBounce(mgEvent)
+		} else {
+			if err := verifyBounceToken(gc, section.Key("secret").String()); err != nil {
+				app.err.Printf(lm.FailedVerifyBounce, gc.ClientIP(), err)
+				respondBool(400, false, gc)
+				return
+			}
+			events, err = parseGenericBounce(body)
+			if err != nil {
+				app.err.Printf(lm.FailedParseBounce, err)
+				respondBool(400, false, gc)
+				return
+			}
+		}
+	} else {
+		if err := verifyBounceToken(gc, section.Key("secret").String()); err != nil {
+			app.err.Printf(lm.FailedVerifyBounce, gc.ClientIP(), err)
+			respondBool(400, false, gc)
+			return
+		}
+		events = parseDSN(body)
+	}
+
+	ignoreComplaints := !section.Key("complaints").MustBool(true)
+	for _, ev := range events {
+		if !ev.Permanent || (ev.Complaint && ignoreComplaints) {
+			app.debug.Printf(lm.IgnoreBounce, ev.Address, ev.Reason)
+			continue
+		}
+		if !app.markUndeliverable(ev) {
+			app.debug.Printf(lm.FailedMatchBounce, ev.Address)
+		}
+	}
+	app.InvalidateUserCaches()
+	// Always acknowledge valid requests, as retrying wouldn't change anything.
+	respondBool(200, true, gc)
+}
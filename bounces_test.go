@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseGenericBounce(t *testing.T) {
+	events, err := parseGenericBounce([]byte(`{"type": "complaint", "email": "jeff@jellyf.in"}`))
+	if err != nil || len(events) != 1 || !events[0].Complaint || !events[0].Permanent || events[0].Address != "jeff@jellyf.in" {
+		t.Fatalf("complaint parsed incorrectly: %+v, %v", events, err)
+	}
+	events, err = parseGenericBounce([]byte(`{"email": "jeff@jellyf.in", "permanent": false, "reason": "mailbox full"}`))
+	if err != nil || len(events) != 1 || events[0].Complaint || events[0].Permanent || events[0].Reason != "mailbox full" {
+		t.Fatalf("temporary bounce parsed incorrectly: %+v, %v", events, err)
+	}
+	for _, bad := range []string{`{"type": "bounce"}`, `{"type": "delivered", "email": "jeff@jellyf.in"}`, `not json`} {
+		if _, err := parseGenericBounce([]byte(bad)); err == nil {
+			t.Errorf("invalid notification accepted: %s", bad)
+		}
+	}
+}
+
+func TestParseMailgunBounce(t *testing.T) {
+	var event MailgunBounceEvent
+	body := `{"event-data": {"event": "failed", "severity": "permanent", "recipient": "jeff@jellyf.in", "delivery-status": {"description": "No such user"}}}`
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		t.Fatal(err)
+	}
+	events := parseMailgunBounce(event)
+	if len(events) != 1 || !events[0].Permanent || events[0].Reason != "No such user" {
+		t.Fatalf("failed event parsed incorrectly: %+v", events)
+	}
+
+	event.EventData.Severity = "temporary"
+	if events := parseMailgunBounce(event); len(events) != 1 || events[0].Permanent {
+		t.Errorf("temporary failure marked permanent: %+v", events)
+	}
+
+	event.EventData.Event = "complained"
+	if events := parseMailgunBounce(event); len(events) != 1 || !events[0].Complaint {
+		t.Errorf("complaint not parsed: %+v", events)
+	}
+
+	event.EventData.Event = "delivered"
+	if events := parseMailgunBounce(event); len(events) != 0 {
+		t.Errorf("delivered event returned bounces: %+v", events)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	dsn := "Content-Type: multipart/report; report-type=delivery-status;\r\n" +
+		"\tboundary=\"abc\"\r\n" +
+		"\r\n" +
+		"--abc\r\n" +
+		"Content-Type: message/delivery-status\r\n" +
+		"\r\n" +
+		"Reporting-MTA: dns; mail.jellyf.in\r\n" +
+		"\r\n" +
+		"Final-Recipient: rfc822; <jeff@jellyf.in>\r\n" +
+		"Action: failed\r\n" +
+		"Status: 5.1.1\r\n" +
+		"Diagnostic-Code: smtp; 550 5.1.1 User unknown\r\n" +
+		"\r\n" +
+		"Final-Recipient: rfc822; full@jellyf.in\r\n" +
+		"Action: failed\r\n" +
+		"Status: 4.2.2\r\n" +
+		"\r\n" +
+		"Final-Recipient: rfc822; fine@jellyf.in\r\n" +
+		"Action: delayed\r\n" +
+		"Status: 4.4.1\r\n" +
+		"--abc--\r\n"
+	events := parseDSN([]byte(dsn))
+	if len(events) != 2 {
+		t.Fatalf("expected 2 failed recipients, got %+v", events)
+	}
+	if events[0].Address != "jeff@jellyf.in" || !events[0].Permanent || events[0].Reason != "550 5.1.1 User unknown" {
+		t.Errorf("permanent failure parsed incorrectly: %+v", events[0])
+	}
+	if events[1].Address != "full@jellyf.in" || events[1].Permanent {
+		t.Errorf("temporary failure parsed incorrectly: %+v", events[1])
+	}
+
+	arf := "Feedback-Type: abuse\nUser-Agent: SomeGenerator/1.0\nVersion: 1\nOriginal-Rcpt-To: jeff@jellyf.in\n"
+	events = parseDSN([]byte(arf))
+	if len(events) != 1 || !events[0].Complaint || events[0].Address != "jeff@jellyf.in" {
+		t.Errorf("feedback report parsed incorrectly: %+v", events)
+	}
+}
+
+func TestVerifyMailgunSignature(t *testing.T) {
+	key := "mailgun-key"
+	now := time.Unix(1700000000, 0)
+	var event MailgunBounceEvent
+	event.Signature.Timestamp = strconv.FormatInt(now.Unix(), 10)
+	event.Signature.Token = "randomtoken"
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(event.Signature.Timestamp + event.Signature.Token))
+	event.Signature.Signature = hex.EncodeToString(mac.Sum(nil))
+
+	if err := verifyMailgunSignature(event, key, 5*time.Minute, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := verifyMailgunSignature(event, "other-key", 5*time.Minute, now); err == nil {
+		t.Error("signature accepted with wrong key")
+	}
+	if err := verifyMailgunSignature(event, key, 5*time.Minute, now.Add(time.Hour)); err == nil {
+		t.Error("old signature accepted")
+	}
+	if err := verifyMailgunSignature(event, "", 5*time.Minute, now); err == nil {
+		t.Error("signature accepted without key")
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	ADMIN_CALENDAR_TOKEN_KEY = "admin_calendar_token"
+	ICS_DATE_FORMAT          = "20060102T150405Z"
+	ICS_LINE_LENGTH          = 75
+)
+
+// CalendarToken is stored under ADMIN_CALENDAR_TOKEN_KEY to authorize the admin expiry feed.
+type CalendarToken struct {
+	Token string
+}
+
+// icsEvent is a single event in an iCalendar feed. Alarms are given relative to the start, e.g. -24h for a day before.
+type icsEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	Alarms      []time.Duration
+}
+
+// icsEscape escapes text values as described in RFC 5545 3.3.11.
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsFold splits lines longer than 75 octets, continuing them on the next line with a leading space (RFC 5545 3.1).
+func icsFold(line string) string {
+	if len(line) <= ICS_LINE_LENGTH {
+		return line
+	}
+	var b strings.Builder
+	limit := ICS_LINE_LENGTH
+	start := 0
+	for i, r := range line {
+		// Don't split multi-byte characters.
+		if i+utf8.RuneLen(r)-start > limit {
+			b.WriteString(line[start:i])
+			b.WriteString("\r\n ")
+			start = i
+			limit = ICS_LINE_LENGTH - 1
+		}
+	}
+	b.WriteString(line[start:])
+	return b.String()
+}
+
+// icsDuration formats an alarm trigger offset, e.g. -P3D or -PT12H.
+func icsDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%sP%dD", sign, d/(24*time.Hour))
+	}
+	return fmt.Sprintf("%sPT%dM", sign, d/time.Minute)
+}
+
+// renderICS renders the given events as an iCalendar feed named calName.
+func renderICS(calName string, events []icsEvent, now time.Time) []byte {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//jfa-go//Account Expiry//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icsEscape(calName),
+	}
+	stamp := now.UTC().Format(ICS_DATE_FORMAT)
+	for _, ev := range events {
+		start := ev.Start.UTC()
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+ev.UID,
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+start.Format(ICS_DATE_FORMAT),
+			"DTEND:"+start.Add(30*time.Minute).Format(ICS_DATE_FORMAT),
+			"SUMMARY:"+icsEscape(ev.Summary),
+		)
+		if ev.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+icsEscape(ev.Description))
+		}
+		for _, alarm := range ev.Alarms {
+			lines = append(lines,
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				"TRIGGER:"+icsDuration(alarm),
+				"DESCRIPTION:"+icsEscape(ev.Summary),
+				"END:VALARM",
+			)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(icsFold(line))
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+// calendarAlarms returns alarm offsets matching the expiry reminders sent by the user daemon.
+func (app *appContext) calendarAlarms() []time.Duration {
+	alarms := []time.Duration{}
+	for _, v := range app.config.Section("user_expiry").Key("send_reminder_n_days_before").StringsWithShadows("|") {
+		d, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && d > 0 {
+			alarms = append(alarms, time.Duration(-d)*24*time.Hour)
+		}
+	}
+	slices.Sort(alarms)
+	return alarms
+}
+
+func genCalendarToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// userCalendarToken returns the token for the user's calendar feed, generating one if needed (or if regenerate is set).
+func (app *appContext) userCalendarToken(jfID string, regenerate bool) string {
+	emailStore, _ := app.storage.GetEmailsKey(jfID)
+	if emailStore.CalendarToken == "" || regenerate {
+		emailStore.CalendarToken = genCalendarToken()
+		app.storage.SetEmailsKey(jfID, emailStore)
+	}
+	return emailStore.CalendarToken
+}
+
+// adminCalendarToken returns the token for the admin expiry feed, generating one if needed (or if regenerate is set).
+func (app *appContext) adminCalendarToken(regenerate bool) string {
+	token := CalendarToken{}
+	app.storage.db.Get(ADMIN_CALENDAR_TOKEN_KEY, &token)
+	if token.Token == "" || regenerate {
+		token.Token = genCalendarToken()
+		app.storage.db.Upsert(ADMIN_CALENDAR_TOKEN_KEY, token)
+	}
+	return token.Token
+}
+
+func serveICS(gc *gin.Context, data []byte) {
+	gc.Header("Cache-Control", "no-cache")
+	gc.Data(200, "text/calendar; charset=utf-8", data)
+}
+
+// @Summary Get an iCalendar feed containing the user's account expiry. Authenticated by the token in the URL, as calendar apps can't log in.
+// @Produce text/calendar
+// @Param token path string true "Calendar token, optionally suffixed with \".ics\"."
+// @Success 200 {string} string
+// @Failure 404 {object} boolResponse
+// @Router /calendar/user/{token} [get]
+// @tags User Page
+func (app *appContext) UserCalendar(gc *gin.Context) {
+	token := strings.TrimSuffix(gc.Param("token"), ".ics")
+	var emailStores []EmailAddress
+	err := app.storage.db.Find(&emailStores, badgerhold.Where("CalendarToken").Eq(token))
+	if token == "" || err != nil || len(emailStores) == 0 {
+		app.debug.Printf(lm.InvalidCalendarToken, gc.ClientIP())
+		respondBool(404, false, gc)
+		return
+	}
+	jfID := emailStores[0].JellyfinID
+	user, err := app.jf.UserByID(jfID, false)
+	if err != nil {
+		app.err.Printf(lm.FailedGetUser, jfID, lm.Jellyfin, err)
+		respondBool(404, false, gc)
+		return
+	}
+	events := []icsEvent{}
+	if expiry, ok := app.storage.GetUserExpiryKey(jfID); ok && !expiry.Downgraded {
+		events = append(events, icsEvent{
+			UID:         "expiry-" + jfID + "@jfa-go",
+			Summary:     app.email.lang.Strings.get("calendarYourExpiry"),
+			Description: app.email.lang.Strings.template("calendarYourExpiryDescription", tmpl{"username": user.Name}),
+			Start:       expiry.Expiry,
+			Alarms:      app.calendarAlarms(),
+		})
+	}
+	serveICS(gc, renderICS(app.email.lang.Strings.get("calendarYourExpiry"), events, time.Now()))
+}
+
+// @Summary Get an iCalendar feed of all upcoming account expiries. Authenticated by the token in the URL, as calendar apps can't log in.
+// @Produce text/calendar
+// @Param token path string true "Admin calendar token, optionally suffixed with \".ics\"."
+// @Success 200 {string} string
+// @Failure 404 {object} boolResponse
+// @Router /calendar/expiries/{token} [get]
+// @tags Users
+func (app *appContext) AdminCalendar(gc *gin.Context) {
+	token := strings.TrimSuffix(gc.Param("token"), ".ics")
+	stored := CalendarToken{}
+	app.storage.db.Get(ADMIN_CALENDAR_TOKEN_KEY, &stored)
+	if token == "" || stored.Token == "" || token != stored.Token {
+		app.debug.Printf(lm.InvalidCalendarToken, gc.ClientIP())
+		respondBool(404, false, gc)
+		return
+	}
+	now := time.Now()
+	events := []icsEvent{}
+	for _, expiry := range app.storage.GetUserExpiries() {
+		if expiry.Expiry.Before(now) || expiry.Downgraded {
+			continue
+		}
+		name := expiry.JellyfinID
+		if user, err := app.jf.UserByID(expiry.JellyfinID, false); err == nil {
+			name = user.Name
+		}
+		events = append(events, icsEvent{
+			UID:     "expiry-" + expiry.JellyfinID + "@jfa-go",
+			Summary: app.email.lang.Strings.template("calendarUserExpiry", tmpl{"username": name}),
+			Start:   expiry.Expiry,
+		})
+	}
+	serveICS(gc, renderICS(app.email.lang.Strings.get("calendarExpiries"), events, now))
+}
+
+// @Summary Get the token for the admin expiry calendar feed, generating one if needed.
+// @Produce json
+// @Success 200 {object} CalendarTokenDTO
+// @Router /calendar/token [get]
+// @Security Bearer
+// @tags Users
+func (app *appContext) GetAdminCalendarToken(gc *gin.Context) {
+	gc.JSON(200, CalendarTokenDTO{Token: app.adminCalendarToken(false)})
+}
+
+// @Summary Generate a new token for the admin expiry calendar feed, invalidating the old one.
+// @Produce json
+// @Success 200 {object} CalendarTokenDTO
+// @Router /calendar/token [post]
+// @Security Bearer
+// @tags Users
+func (app *appContext) ResetAdminCalendarToken(gc *gin.Context) {
+	gc.JSON(200, CalendarTokenDTO{Token: app.adminCalendarToken(true)})
+}
+
+// @Summary Generate a new token for your expiry calendar feed, invalidating the old one.
+// @Produce json
+// @Success 200 {object} CalendarTokenDTO
+// @Router /my/calendar [post]
+// @Security Bearer
+// @tags User Page
+func (app *appContext) ResetMyCalendarToken(gc *gin.Context) {
+	gc.JSON(200, CalendarTokenDTO{Token: app.userCalendarToken(gc.GetString("jfId"), true)})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests lines are folded at 75 octets without splitting multi-byte characters.
+func TestICSFold(t *testing.T) {
+	short := "SUMMARY:Short"
+	if icsFold(short) != short {
+		t.Fatalf("short line was folded: %q", icsFold(short))
+	}
+	long := "DESCRIPTION:" + strings.Repeat("é", 60)
+	folded := icsFold(long)
+	for _, line := range strings.Split(folded, "\r\n") {
+		if len(line) > ICS_LINE_LENGTH {
+			t.Errorf("line longer than %d octets: %q (%d)", ICS_LINE_LENGTH, line, len(line))
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != long {
+		t.Errorf("unfolded line doesn't match original:\n%q\n%q", unfolded, long)
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	in := "a,b;c\\d\ne"
+	want := `a\,b\;c\\d\ne`
+	if got := icsEscape(in); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestICSDuration(t *testing.T) {
+	cases := map[time.Duration]string{
+		-3 * 24 * time.Hour: "-P3D",
+		-90 * time.Minute:   "-PT90M",
+		24 * time.Hour:      "P1D",
+	}
+	for d, want := range cases {
+		if got := icsDuration(d); got != want {
+			t.Errorf("%v: expected %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestRenderICS(t *testing.T) {
+	now := time.Date(2025, 8, 9, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 9, 1, 9, 30, 0, 0, time.FixedZone("", 3600))
+	out := string(renderICS("Expiries", []icsEvent{{
+		UID:     "expiry-abc@jfa-go",
+		Summary: "user, expires",
+		Start:   start,
+		Alarms:  []time.Duration{-24 * time.Hour},
+	}}, now))
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Expiries\r\n",
+		"UID:expiry-abc@jfa-go\r\n",
+		"DTSTAMP:20250809T120000Z\r\n",
+		"DTSTART:20250901T083000Z\r\n",
+		"SUMMARY:user\\, expires\r\n",
+		"TRIGGER:-P1D\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "DESCRIPTION:\r\n") {
+		t.Errorf("empty description rendered:\n%s", out)
+	}
+}
@@ -30,6 +30,7 @@ var messagesEnabled = false
 var telegramEnabled = false
 var discordEnabled = false
 var matrixEnabled = false
+var pushEnabled = false
 
 // URL subpaths. Ignore the "Current" field, it's populated when in copies of the struct used for page templating.
 // IMPORTANT: When linking straight to a page, rather than appending further to the URL (like accessing an API route), append a /.
@@ -329,17 +330,19 @@ func NewConfig(configPathOrContents any, dataPath string, logs LoggerSet) (*Conf
 	telegramEnabled = config.Section("telegram").Key("enabled").MustBool(false)
 	discordEnabled = config.Section("discord").Key("enabled").MustBool(false)
 	matrixEnabled = config.Section("matrix").Key("enabled").MustBool(false)
+	pushEnabled = config.Section("push").Key("enabled").MustBool(false)
 	if !messagesEnabled {
 		emailEnabled = false
 		telegramEnabled = false
 		discordEnabled = false
 		matrixEnabled = false
+		pushEnabled = false
 	} else if config.Section("email").Key("method").MustString("") == "" {
 		emailEnabled = false
 	} else {
 		emailEnabled = true
 	}
-	if !emailEnabled && !telegramEnabled && !discordEnabled && !matrixEnabled {
+	if !emailEnabled && !telegramEnabled && !discordEnabled && !matrixEnabled && !pushEnabled {
 		messagesEnabled = false
 	}
 
@@ -504,6 +507,29 @@ func (app *appContext) PatchConfigBase() {
 	app.patchedConfig = conf
 }
 
+// PatchConfigProfiles fills in profile selection settings with the current list of profiles.
+func (app *appContext) PatchConfigProfiles() {
+	profiles := app.storage.GetProfiles()
+	options := make([]common.Option, len(profiles)+1)
+	options[0] = common.Option{"", "None"}
+	for i, p := range profiles {
+		options[i+1] = common.Option{p.Name, p.Name}
+	}
+	for i, section := range app.patchedConfig.Sections {
+		if section.Section != "user_expiry" {
+			continue
+		}
+		for j, setting := range section.Settings {
+			if setting.Setting != "downgrade_profile" {
+				continue
+			}
+			setting.Options = options
+			section.Settings[j] = setting
+		}
+		app.patchedConfig.Sections[i] = section
+	}
+}
+
 func (app *appContext) PatchConfigDiscordRoles() {
 	if !discordEnabled {
 		return
@@ -274,6 +274,108 @@ var customContent = map[string]CustomContentInfo{
 			DefaultValue:  "expiry-adjusted",
 		},
 	},
+	"UserAdopted": {
+		Name:        "UserAdopted",
+		ContentType: CustomMessage,
+		DisplayName: func(dict *Lang, lang string) string { return dict.Email[lang].UserAdopted["name"] },
+		Subject: func(config *Config, lang *emailLang) string {
+			return config.Section("user_adoption").Key("subject").MustString(lang.UserAdopted.get("title"))
+		},
+		HeaderText: vendorHeader,
+		FooterText: func(config *Config, lang *emailLang) string {
+			return lang.UserAdopted.get("notificationNotice")
+		},
+		Variables: []string{
+			"name",
+			"profile",
+			"expiry",
+			"label",
+			"time",
+		},
+		Placeholders: map[string]any{
+			"name":    "Subject Username",
+			"profile": "Default User Profile",
+			"expiry":  "01/01/01 00:00",
+			"label":   "Label",
+			"time":    "01/01/01 00:00",
+		},
+		SourceFile: ContentSourceFileInfo{
+			Section:       "user_adoption",
+			SettingPrefix: "email_",
+			DefaultValue:  "user-adopted",
+		},
+	},
+	"AdminDigest": {
+		Name:        "AdminDigest",
+		ContentType: CustomMessage,
+		DisplayName: func(dict *Lang, lang string) string { return dict.Email[lang].AdminDigest["name"] },
+		Subject: func(config *Config, lang *emailLang) string {
+			return config.Section("admin_digest").Key("subject").MustString(lang.AdminDigest.get("title"))
+		},
+		HeaderText: vendorHeader,
+		FooterText: func(config *Config, lang *emailLang) string {
+			return lang.AdminDigest.get("notificationNotice")
+		},
+		Variables: []string{
+			"period",
+			"signupCount",
+			"signups",
+			"invitesUsed",
+			"invitesExpired",
+			"expiringUsers",
+			"disabledUsers",
+			"deletedUsers",
+			"failedDeliveries",
+			"backupStatus",
+		},
+		Placeholders: map[string]any{
+			"period":           "01/01/01 00:00 - 08/01/01 00:00",
+			"signupCount":      "2",
+			"signups":          "- Username (Invite \"Label\", profile \"Default\")\n- Username 2 (Created by an admin)",
+			"invitesUsed":      "1",
+			"invitesExpired":   "1",
+			"expiringUsers":    "- Username: 10/01/01 00:00",
+			"disabledUsers":    "- Username",
+			"deletedUsers":     "- Username",
+			"failedDeliveries": "0",
+			"backupStatus":     "Last backup made 07/01/01 00:00.",
+		},
+		SourceFile: ContentSourceFileInfo{
+			Section:       "admin_digest",
+			SettingPrefix: "email_",
+			DefaultValue:  "admin-digest",
+		},
+	},
+	"AdminUserExpired": adminEventContent("AdminUserExpired", AdminEventUserExpired, "userExpired", map[string]any{
+		"name":   "Subject Username",
+		"action": "disabled",
+	}),
+	"AdminUserDeleted": adminEventContent("AdminUserDeleted", AdminEventUserDeleted, "userDeleted", map[string]any{
+		"name":  "Subject Username",
+		"admin": "Admin Username",
+	}),
+	"AdminPasswordReset": adminEventContent("AdminPasswordReset", AdminEventPasswordReset, "passwordReset", map[string]any{
+		"name": "Subject Username",
+	}),
+	"AdminContactUnlinked": adminEventContent("AdminContactUnlinked", AdminEventContactUnlinked, "contactUnlinked", map[string]any{
+		"name":   "Subject Username",
+		"method": "discord",
+	}),
+	"AdminFailedLogin": adminEventContent("AdminFailedLogin", AdminEventFailedLogin, "failedLogin", map[string]any{
+		"name": "Attempted Username",
+		"ip":   "127.0.0.1",
+	}),
+	"AdminDiscordDeparture": adminEventContent("AdminDiscordDeparture", AdminEventDiscordDeparture, "discordDeparture", map[string]any{
+		"name":    "Subject Username",
+		"discord": "@discorduser",
+		"reason":  "left",
+		"action":  "No action was taken.",
+	}),
+	"AdminPaymentUnapplied": adminEventContent("AdminPaymentUnapplied", AdminEventPaymentUnapplied, "paymentUnapplied", map[string]any{
+		"event":  "evt_123",
+		"payer":  "user@example.com",
+		"reason": "No user could be found for it.",
+	}),
 	"WelcomeEmail": {
 		Name:        "WelcomeEmail",
 		ContentType: CustomMessage,
@@ -399,12 +501,48 @@ var EmptyCustomContent = CustomContentInfo{
 
 var AnnouncementCustomContent = func(subject string) CustomContentInfo {
 	cci := EmptyCustomContent
+	cci.Name = ANNOUNCEMENT_MESSAGE_TYPE
 	cci.Subject = func(config *Config, lang *emailLang) string { return subject }
-	cci.Variables = defaultVars()
-	cci.Placeholders = defaultVals(map[string]any{})
+	cci.Variables = defaultVars("unsubscribeLink", "expiry", "expiryTime", "expiresIn", "label", "email", "referralLink", "myAccountURL", "profile")
+	cci.Placeholders = defaultVals(map[string]any{
+		"unsubscribeLink": "https://example.com/unsubscribe",
+		"expiry":          "01/01/25",
+		"expiryTime":      "00:00",
+		"expiresIn":       "3d 4h 32m",
+		"label":           "Label",
+		"email":           "user@example.com",
+		"referralLink":    "https://example.com/invite/xxxxxx",
+		"myAccountURL":    "https://example.com/my/account",
+		"profile":         "Default User Profile",
+	})
 	return cci
 }
 
+// adminEventContent returns the content of the notification sent to admins for the given event in adminEvents.
+func adminEventContent(name, event, langKey string, placeholders map[string]any) CustomContentInfo {
+	vars := slices.Sorted(maps.Keys(placeholders))
+	placeholders["time"] = "01/01/01 00:00"
+	return CustomContentInfo{
+		Name:        name,
+		ContentType: CustomMessage,
+		DisplayName: func(dict *Lang, lang string) string { return dict.Email[lang].AdminEvents[langKey+"Name"] },
+		Subject: func(config *Config, lang *emailLang) string {
+			return config.Section("admin_events").Key(event + "_subject").MustString(lang.AdminEvents.get(langKey + "Title"))
+		},
+		HeaderText: vendorHeader,
+		FooterText: func(config *Config, lang *emailLang) string {
+			return lang.AdminEvents.get("notificationNotice")
+		},
+		Variables:    append(vars, "time"),
+		Placeholders: placeholders,
+		SourceFile: ContentSourceFileInfo{
+			Section:       "admin_events",
+			SettingPrefix: event + "_email_",
+			DefaultValue:  "admin-event",
+		},
+	}
+}
+
 // Validates customContent and sets default fields if needed.
 var _runtimeValidation = func() bool {
 	for name, cc := range customContent {
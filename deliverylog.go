@@ -0,0 +1,161 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/lithammer/shortuuid/v3"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	ANNOUNCEMENT_MESSAGE_TYPE = "Announcement"
+	USER_DELIVERIES_SHOWN     = 20
+)
+
+// logDelivery records an attempt to send msg to a single recipient. jfID can be blank if the recipient isn't a user.
+func (st *Storage) logDelivery(msg *Message, channel, jfID, destination string, err error) {
+	entry := MessageLogEntry{
+		ID:          shortuuid.New(),
+		JellyfinID:  jfID,
+		Type:        msg.Type,
+		Channel:     channel,
+		Destination: destination,
+		Subject:     msg.Subject,
+		Time:        time.Now(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	st.db.Insert(entry.ID, entry)
+}
+
+// userByAddress returns the Jellyfin ID of the user with the given email address, if any.
+func (st *Storage) userByAddress(address string) string {
+	result := []EmailAddress{}
+	err := st.db.Find(&result, badgerhold.Where("Addr").Eq(address).Limit(1))
+	if err != nil || len(result) == 0 {
+		return ""
+	}
+	return result[0].JellyfinID
+}
+
+func (app *appContext) messageLogEntryDTO(entry MessageLogEntry) MessageLogEntryDTO {
+	dto := MessageLogEntryDTO{
+		ID:          entry.ID,
+		UserID:      entry.JellyfinID,
+		Type:        entry.Type,
+		Channel:     entry.Channel,
+		Destination: entry.Destination,
+		Subject:     entry.Subject,
+		Time:        entry.Time.Unix(),
+		Error:       entry.Error,
+	}
+	if entry.JellyfinID != "" {
+		if user, err := app.jf.UserByID(entry.JellyfinID, false); err == nil {
+			dto.Username = user.Name
+		}
+	}
+	return dto
+}
+
+// userDeliveries returns the most recent messages sent to the given user.
+func (app *appContext) userDeliveries(jfID string) []MessageLogEntryDTO {
+	entries := []MessageLogEntry{}
+	app.storage.db.Find(&entries, badgerhold.Where("JellyfinID").Eq(jfID).SortBy("Time").Reverse().Limit(USER_DELIVERIES_SHOWN))
+	resp := make([]MessageLogEntryDTO, len(entries))
+	for i, entry := range entries {
+		resp[i] = app.messageLogEntryDTO(entry)
+	}
+	return resp
+}
+
+// clearMessageLog deletes delivery log entries older than messages.delivery_log_days.
+func (app *appContext) clearMessageLog() {
+	days := app.config.Section("messages").Key("delivery_log_days").MustInt(90)
+	if days <= 0 {
+		return
+	}
+	app.debug.Println(lm.HousekeepingMessageLog)
+	err := app.storage.db.DeleteMatching(&MessageLogEntry{}, badgerhold.Where("Time").Lt(time.Now().AddDate(0, 0, -days)))
+	if err != nil {
+		app.err.Printf(lm.FailedClearMessageLog, err)
+	}
+}
+
+// @Summary Get a page of the message delivery log, newest first, optionally filtered by user, type, channel or failure.
+// @Produce json
+// @Param MessageLogReqDTO body MessageLogReqDTO true "Filters and pagination"
+// @Success 200 {object} MessageLogDTO
+// @Router /messages/log [post]
+// @Security Bearer
+// @tags Messages
+func (app *appContext) GetMessageLog(gc *gin.Context) {
+	var req MessageLogReqDTO
+	gc.BindJSON(&req)
+	if req.Limit <= 0 {
+		req.Limit = 50
+	}
+	req.Page = max(req.Page, 0)
+	query := &badgerhold.Query{}
+	addCriteria := func(field, value string) {
+		if value == "" {
+			return
+		}
+		if query.IsEmpty() {
+			query = badgerhold.Where(field).Eq(value)
+		} else {
+			query = query.And(field).Eq(value)
+		}
+	}
+	addCriteria("JellyfinID", req.UserID)
+	addCriteria("Type", req.Type)
+	addCriteria("Channel", req.Channel)
+	if req.Failed {
+		if query.IsEmpty() {
+			query = badgerhold.Where("Error").Ne("")
+		} else {
+			query = query.And("Error").Ne("")
+		}
+	}
+	resp := MessageLogDTO{Entries: []MessageLogEntryDTO{}, Types: []string{ANNOUNCEMENT_MESSAGE_TYPE}}
+	for name, info := range customContent {
+		if info.ContentType == CustomMessage {
+			resp.Types = append(resp.Types, name)
+		}
+	}
+	slices.Sort(resp.Types)
+
+	entries := []MessageLogEntry{}
+	search := strings.ToLower(req.Search)
+	if search == "" {
+		// Fetch one more than needed to tell if there's another page.
+		app.storage.db.Find(&entries, query.SortBy("Time").Reverse().Skip(req.Page*req.Limit).Limit(req.Limit+1))
+	} else {
+		app.storage.db.Find(&entries, query.SortBy("Time").Reverse())
+		// Look up every user once, rather than each entry's user individually.
+		usernames := map[string]string{}
+		if users, err := app.jf.GetUsers(false); err == nil {
+			for _, user := range users {
+				usernames[user.ID] = strings.ToLower(user.Name)
+			}
+		}
+		entries = slices.DeleteFunc(entries, func(entry MessageLogEntry) bool {
+			return !strings.Contains(usernames[entry.JellyfinID], search) && !strings.Contains(strings.ToLower(entry.Destination), search)
+		})
+		entries = entries[min(req.Page*req.Limit, len(entries)):]
+	}
+	if len(entries) > req.Limit {
+		entries = entries[:req.Limit]
+	} else {
+		resp.LastPage = true
+	}
+	// Only resolve usernames for the entries on this page.
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, app.messageLogEntryDTO(entry))
+	}
+	gc.JSON(200, resp)
+}
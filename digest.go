@@ -0,0 +1,192 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const DIGEST_STATUS_KEY = "admin_digest_status"
+
+// AdminDigest summarizes what happened over a period, for the admin digest message.
+type AdminDigest struct {
+	Start, End       time.Time
+	Signups          []string // Descriptions of each new user, and how they were created.
+	InvitesUsed      int
+	InvitesExpired   int
+	Expiring         []string // Users expiring soon, and when.
+	Disabled         []string // Users disabled by the daemon on expiry.
+	Deleted          []string // Users deleted by the daemon on expiry.
+	FailedDeliveries int
+	Backup           string // Description of the latest backup.
+}
+
+// nextDigest returns the first time after "after" that the digest should be sent:
+// at the given hour each day, or for weekly digests, the given weekday.
+func nextDigest(after time.Time, frequency string, hour int, weekday time.Weekday) time.Time {
+	t := time.Date(after.Year(), after.Month(), after.Day(), hour, 0, 0, 0, after.Location())
+	for !t.After(after) || (frequency == RecurrenceWeekly && t.Weekday() != weekday) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// inviteName returns an invite's label, or its code if it had none.
+// Deleted invites are looked up in the activity log.
+func (app *appContext) inviteName(code string) string {
+	if inv, ok := app.storage.GetInvitesKey(code); ok {
+		if inv.Label != "" {
+			return inv.Label
+		}
+		return code
+	}
+	acts := []Activity{}
+	app.storage.db.Find(&acts, badgerhold.Where("Type").Eq(ActivityCreateInvite).And("InviteCode").Eq(code).Limit(1))
+	if len(acts) != 0 && acts[0].Value != "" {
+		return acts[0].Value
+	}
+	return code
+}
+
+// usernameOrID returns the given user's username, or the ID if they can't be found.
+func (app *appContext) usernameOrID(jfID string) string {
+	if user, err := app.jf.UserByID(jfID, false); err == nil {
+		return user.Name
+	}
+	return jfID
+}
+
+// backupStatus describes the latest backup, warning if none have been made since "since".
+func (app *appContext) backupStatus(since time.Time) string {
+	lang := app.email.lang.AdminDigest
+	if !app.config.Section("backups").Key("enabled").MustBool(false) {
+		return lang.get("backupsDisabled")
+	}
+	backups := app.getBackups()
+	if backups == nil || backups.count == 0 {
+		return lang.get("noBackups")
+	}
+	latest := time.Time{}
+	for _, b := range backups.info {
+		if b.Date.After(latest) {
+			latest = b.Date
+		}
+	}
+	if latest.Before(since) {
+		return lang.template("noRecentBackup", tmpl{"date": formatDatetime(latest)})
+	}
+	return lang.template("lastBackup", tmpl{"date": formatDatetime(latest)})
+}
+
+// upcomingExpiries returns the expiries of users who haven't yet expired as of "from", and will within the given number of days, soonest first.
+func (app *appContext) upcomingExpiries(from time.Time, days int) []UserExpiry {
+	cutoff := from.AddDate(0, 0, days)
+	expiries := slices.DeleteFunc(app.storage.GetUserExpiries(), func(e UserExpiry) bool {
+		return e.Downgraded || e.DeleteAfterPeriod || !e.Expiry.After(from) || e.Expiry.After(cutoff)
+	})
+	sort.Slice(expiries, func(i, j int) bool { return expiries[i].Expiry.Before(expiries[j].Expiry) })
+	return expiries
+}
+
+// gatherDigest collects the contents of the admin digest for the given period from the activity log,
+// user expiries, message delivery log and backups. Users expiring within expiringDays of the end are listed.
+func (app *appContext) gatherDigest(start, end time.Time, expiringDays int) AdminDigest {
+	digest := AdminDigest{Start: start, End: end}
+	lang := app.email.lang.AdminDigest
+
+	acts := []Activity{}
+	err := app.storage.db.Find(&acts, badgerhold.Where("Time").Ge(start).And("Time").Lt(end).SortBy("Time"))
+	if err != nil {
+		app.err.Printf(lm.FailedDBReadActivities, err)
+	}
+	usedInvites := map[string]bool{}
+	expiredInvites := []string{}
+	for _, act := range acts {
+		switch act.Type {
+		case ActivityCreation:
+			via := lang.get("createdByAdmin")
+			if act.InviteCode != "" {
+				digest.InvitesUsed++
+				usedInvites[act.InviteCode] = true
+				via = lang.template("viaInvite", tmpl{"invite": app.inviteName(act.InviteCode)})
+			}
+			if emailStore, ok := app.storage.GetEmailsKey(act.UserID); ok && emailStore.Profile != "" {
+				via += ", " + lang.template("withProfile", tmpl{"profile": emailStore.Profile})
+			}
+			digest.Signups = append(digest.Signups, act.Value+" ("+via+")")
+		case ActivityDeleteInvite:
+			if act.SourceType == ActivityDaemon {
+				expiredInvites = append(expiredInvites, act.InviteCode)
+			}
+		case ActivityDisabled:
+			if act.SourceType == ActivityDaemon && act.Source == "" {
+				digest.Disabled = append(digest.Disabled, app.usernameOrID(act.UserID))
+			}
+		case ActivityDeletion:
+			if act.SourceType == ActivityDaemon {
+				digest.Deleted = append(digest.Deleted, act.Value)
+			}
+		}
+	}
+	// The daemon also deletes invites once they've been used up, which aren't counted as expired.
+	for _, code := range expiredInvites {
+		if !usedInvites[code] {
+			digest.InvitesExpired++
+		}
+	}
+
+	for _, e := range app.upcomingExpiries(end, expiringDays) {
+		digest.Expiring = append(digest.Expiring, app.usernameOrID(e.JellyfinID)+": "+formatDatetime(e.Expiry))
+	}
+
+	failed, _ := app.storage.db.Count(&MessageLogEntry{}, badgerhold.Where("Time").Ge(start).And("Time").Lt(end).And("Error").Ne(""))
+	digest.FailedDeliveries = int(failed)
+
+	digest.Backup = app.backupStatus(start)
+	return digest
+}
+
+// sendAdminDigest sends the admin digest if it's due. On the first run, it's scheduled but nothing is sent.
+func (app *appContext) sendAdminDigest() {
+	section := app.config.Section("admin_digest")
+	now := time.Now()
+	status, _ := app.storage.GetDigestStatus()
+	if status.LastSent.IsZero() {
+		app.storage.SetDigestStatus(DigestStatus{LastSent: now})
+		return
+	}
+	frequency := section.Key("frequency").In(RecurrenceWeekly, []string{RecurrenceDaily, RecurrenceWeekly})
+	next := nextDigest(status.LastSent, frequency, section.Key("hour").MustInt(9), time.Weekday(section.Key("weekday").MustInt(1)))
+	if now.Before(next) {
+		return
+	}
+	app.info.Printf(lm.SendAdminDigest, formatDatetime(status.LastSent), formatDatetime(now))
+	digest := app.gatherDigest(status.LastSent, now, section.Key("expiring_days").MustInt(7))
+	// Record the send first, so a broken message isn't retried every run.
+	app.storage.SetDigestStatus(DigestStatus{LastSent: now})
+	msg, err := app.email.constructAdminDigest(digest, false)
+	if err != nil {
+		app.err.Printf(lm.FailedConstructDigestAdmin, err)
+		return
+	}
+	app.sendToAdmins(msg, func(recipient string, err error) {
+		if err != nil {
+			app.err.Printf(lm.FailedSendDigestAdmin, recipient, err)
+		} else {
+			app.debug.Printf(lm.SentDigestAdmin, recipient)
+		}
+	})
+}
+
+func newDigestDaemon(interval time.Duration, app *appContext) *GenericDaemon {
+	d := NewGenericDaemon(interval, app,
+		func(app *appContext) {
+			app.sendAdminDigest()
+		},
+	)
+	d.Name("Admin digest")
+	return d
+}
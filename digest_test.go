@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDigest(t *testing.T) {
+	// A Wednesday.
+	last := time.Date(2025, time.January, 1, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		frequency string
+		hour      int
+		weekday   time.Weekday
+		want      time.Time
+	}{
+		// Later the same day.
+		{RecurrenceDaily, 18, time.Monday, time.Date(2025, time.January, 1, 18, 0, 0, 0, time.UTC)},
+		// Hour has passed, so the next day.
+		{RecurrenceDaily, 9, time.Monday, time.Date(2025, time.January, 2, 9, 0, 0, 0, time.UTC)},
+		{RecurrenceWeekly, 9, time.Monday, time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)},
+		{RecurrenceWeekly, 18, time.Wednesday, time.Date(2025, time.January, 1, 18, 0, 0, 0, time.UTC)},
+		{RecurrenceWeekly, 9, time.Wednesday, time.Date(2025, time.January, 8, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := nextDigest(last, c.frequency, c.hour, c.weekday); !got.Equal(c.want) {
+			t.Errorf("nextDigest(%q, %d, %v): got %v, want %v", c.frequency, c.hour, c.weekday, got, c.want)
+		}
+	}
+}
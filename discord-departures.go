@@ -0,0 +1,168 @@
+package main
+
+import (
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	DiscordDepartureNone    = "none"
+	DiscordDepartureNotify  = "notify"
+	DiscordDepartureDisable = "disable"
+	DiscordDepartureGrace   = "grace"
+	// Source of activities for accounts disabled after their Discord user left, so they aren't mistaken for expiries.
+	DISCORD_DEPARTURE_SOURCE = "discordDeparture"
+	// A ban also removes the member, and the two events can arrive in either order, so departures are handled after this delay to merge them.
+	DISCORD_DEPARTURE_DELAY = 5 * time.Second
+)
+
+// discordDepartureAction returns what should be done when a linked user leaves the Discord server.
+func (app *appContext) discordDepartureAction() string {
+	return app.config.Section("discord").Key("departure_action").In(DiscordDepartureNone, []string{
+		DiscordDepartureNone, DiscordDepartureNotify, DiscordDepartureDisable, DiscordDepartureGrace,
+	})
+}
+
+func (d *DiscordDaemon) memberRemoveHandler(s *dg.Session, m *dg.GuildMemberRemove) {
+	if m.GuildID != d.guildID || m.User == nil {
+		return
+	}
+	d.queueDeparture(m.User.ID, false)
+}
+
+func (d *DiscordDaemon) banHandler(s *dg.Session, b *dg.GuildBanAdd) {
+	if b.GuildID != d.guildID || b.User == nil {
+		return
+	}
+	d.queueDeparture(b.User.ID, true)
+}
+
+// memberAddHandler cancels any grace period the rejoining user's accounts are in.
+func (d *DiscordDaemon) memberAddHandler(s *dg.Session, m *dg.GuildMemberAdd) {
+	if m.GuildID != d.guildID || m.User == nil {
+		return
+	}
+	for _, departure := range d.app.storage.GetDiscordDepartures(m.User.ID) {
+		d.app.storage.DeleteDiscordDepartureKey(departure.JellyfinID)
+		d.app.info.Printf(lm.DiscordUserRejoined, d.app.usernameOrID(departure.JellyfinID), m.User.ID)
+	}
+}
+
+// queueDeparture handles the departure of the given user after DISCORD_DEPARTURE_DELAY, merged with any other departure events for them received in the meantime.
+func (d *DiscordDaemon) queueDeparture(userID string, banned bool) {
+	if d.app.discordDepartureAction() == DiscordDepartureNone {
+		return
+	}
+	d.departuresLock.Lock()
+	defer d.departuresLock.Unlock()
+	wasBanned, pending := d.departures[userID]
+	d.departures[userID] = wasBanned || banned
+	if pending {
+		return
+	}
+	time.AfterFunc(DISCORD_DEPARTURE_DELAY, func() {
+		d.departuresLock.Lock()
+		banned := d.departures[userID]
+		delete(d.departures, userID)
+		d.departuresLock.Unlock()
+		d.app.handleDiscordDeparture(userID, banned)
+	})
+}
+
+// handleDiscordDeparture records the departure of the given Discord user for each of their linked accounts, applies the departure action, and notifies admins.
+func (app *appContext) handleDiscordDeparture(discordID string, banned bool) {
+	accounts := []DiscordUser{}
+	app.storage.db.Find(&accounts, badgerhold.Where("ID").Eq(discordID))
+	if len(accounts) == 0 {
+		return
+	}
+	action := app.discordDepartureAction()
+	lang := app.email.lang.AdminEvents
+	value, reason := "left", lang.get("discordLeft")
+	if banned {
+		value, reason = "banned", lang.get("discordBanned")
+	}
+	now := time.Now()
+	for _, account := range accounts {
+		user, err := app.jf.UserByID(account.JellyfinID, false)
+		if err != nil {
+			app.err.Printf(lm.FailedGetUser, account.JellyfinID, lm.Jellyfin, err)
+			continue
+		}
+		app.info.Printf(lm.DiscordUserDeparted, user.Name, discordID, value, action)
+		app.recordActivity(Activity{
+			Type:       ActivityDiscordDeparture,
+			UserID:     user.ID,
+			SourceType: ActivityDaemon,
+			Value:      value,
+			Time:       now,
+		}, nil, false)
+		result := lang.get("discordNoAction")
+		switch action {
+		case DiscordDepartureDisable:
+			if app.disableDepartedUser(user, DISCORD_DEPARTURE_SOURCE) {
+				result = lang.get("discordDisabled")
+			}
+		case DiscordDepartureGrace:
+			if user.Policy.IsDisabled {
+				break
+			}
+			disableAt := now.AddDate(0, 0, app.config.Section("discord").Key("departure_grace_days").MustInt(7))
+			app.storage.SetDiscordDepartureKey(user.ID, DiscordDeparture{
+				DiscordID: discordID,
+				Banned:    banned,
+				Time:      now,
+				DisableAt: disableAt,
+			})
+			result = lang.template("discordGracePeriod", tmpl{"date": formatDatetime(disableAt)})
+		}
+		app.notifyAdmins(AdminEventDiscordDeparture, map[string]any{
+			"name":    user.Name,
+			"discord": RenderDiscordUsername(account),
+			"reason":  reason,
+			"action":  result,
+		}, "")
+	}
+	app.InvalidateUserCaches()
+}
+
+// checkDiscordDepartures disables the accounts of departed users whose grace period is over, unless they've since rejoined or unlinked.
+func (app *appContext) checkDiscordDepartures() {
+	disabled := false
+	for _, departure := range app.storage.GetDueDiscordDepartures(time.Now()) {
+		app.storage.DeleteDiscordDepartureKey(departure.JellyfinID)
+		if dcUser, ok := app.storage.GetDiscordKey(departure.JellyfinID); !ok || dcUser.ID != departure.DiscordID {
+			continue
+		}
+		if app.discord != nil {
+			if _, err := app.discord.bot.GuildMember(app.discord.guildID, departure.DiscordID); err == nil {
+				continue
+			}
+		}
+		user, err := app.jf.UserByID(departure.JellyfinID, false)
+		if err != nil {
+			app.err.Printf(lm.FailedGetUser, departure.JellyfinID, lm.Jellyfin, err)
+			continue
+		}
+		if app.disableDepartedUser(user, DISCORD_DEPARTURE_SOURCE) {
+			app.info.Printf(lm.DisabledDepartedDiscordUser, user.Name)
+			disabled = true
+		}
+	}
+	if disabled {
+		app.InvalidateUserCaches()
+	}
+}
+
+func newDiscordDepartureDaemon(interval time.Duration, app *appContext) *GenericDaemon {
+	d := NewGenericDaemon(interval, app,
+		func(app *appContext) {
+			app.checkDiscordDepartures()
+		},
+	)
+	d.Name("Discord departure grace period")
+	return d
+}
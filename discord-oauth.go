@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+)
+
+const (
+	DISCORD_OAUTH_AUTHORIZE_URL = "https://discord.com/oauth2/authorize"
+	// Holds the OAuth2 state given to the browser along with a PIN, so the flow can only be completed in that browser.
+	DISCORD_OAUTH_COOKIE = "discord-oauth"
+)
+
+var errNotInDiscordGuild = errors.New(lm.NotInDiscordGuild)
+
+// DiscordOAuthState is the PIN an OAuth2 state was issued for.
+type DiscordOAuthState struct {
+	PIN    string
+	Expiry time.Time
+}
+
+// discordOAuthEnabled returns whether users can link their Discord account by logging in with Discord, rather than sending a PIN to the bot.
+func (app *appContext) discordOAuthEnabled() bool {
+	section := app.config.Section("discord")
+	return section.Key("oauth").MustBool(false) && section.Key("client_id").String() != "" && section.Key("client_secret").String() != ""
+}
+
+// discordOAuthRedirectURI returns the callback Discord sends users back to, which must also be set in the Discord Developer Portal.
+func discordOAuthRedirectURI() string {
+	return ExternalURI(nil) + "/discord/oauth"
+}
+
+// discordOAuthStartURL returns the address pages link to to start the OAuth2 flow, minus the "pin" parameter.
+func discordOAuthStartURL() string {
+	return ExternalURI(nil) + "/discord/oauth/start"
+}
+
+// discordOAuthURL returns the Discord authorization URL with the given state.
+func (app *appContext) discordOAuthURL(state string) string {
+	v := url.Values{}
+	v.Set("client_id", app.config.Section("discord").Key("client_id").String())
+	v.Set("response_type", "code")
+	v.Set("scope", "identify")
+	v.Set("redirect_uri", discordOAuthRedirectURI())
+	v.Set("state", state)
+	return DISCORD_OAUTH_AUTHORIZE_URL + "?" + v.Encode()
+}
+
+// newOAuthState generates a random OAuth2 state for the given PIN, valid as long as the PIN is. Expired states are cleared out.
+func (d *DiscordDaemon) newOAuthState(pin string) (string, error) {
+	state, err := generateSecret(32)
+	if err != nil {
+		return "", err
+	}
+	d.oauthStatesLock.Lock()
+	defer d.oauthStatesLock.Unlock()
+	now := time.Now()
+	for k, v := range d.oauthStates {
+		if now.After(v.Expiry) {
+			delete(d.oauthStates, k)
+		}
+	}
+	d.oauthStates[state] = DiscordOAuthState{PIN: pin, Expiry: now.Add(VERIF_TOKEN_EXPIRY_SEC * time.Second)}
+	return state, nil
+}
+
+// oauthStatePIN returns the PIN the given OAuth2 state was issued for, if it hasn't expired. If "consume" is set, the state can't be used again.
+func (d *DiscordDaemon) oauthStatePIN(state string, consume bool) (string, bool) {
+	d.oauthStatesLock.Lock()
+	defer d.oauthStatesLock.Unlock()
+	v, ok := d.oauthStates[state]
+	if !ok {
+		return "", false
+	}
+	expired := time.Now().After(v.Expiry)
+	if consume || expired {
+		delete(d.oauthStates, state)
+	}
+	return v.PIN, !expired
+}
+
+// setDiscordOAuthCookie gives the browser a new OAuth2 state for the given PIN, so only it can use the PIN to link by logging in with Discord.
+func (app *appContext) setDiscordOAuthCookie(gc *gin.Context, pin string) {
+	if !app.discordOAuthEnabled() {
+		return
+	}
+	state, err := app.discord.newOAuthState(pin)
+	if err != nil {
+		app.err.Printf(lm.FailedVerifyDiscordOAuth, err)
+		return
+	}
+	gc.SetCookie(DISCORD_OAUTH_COOKIE, state, VERIF_TOKEN_EXPIRY_SEC, "/", gc.Request.URL.Hostname(), true, true)
+}
+
+// oauthUser exchanges the given authorization code for an access token, and returns the Discord user it belongs to.
+func (d *DiscordDaemon) oauthUser(code string) (*dg.User, error) {
+	section := d.app.config.Section("discord")
+	resp, err := d.oauthClient.PostForm(dg.EndpointOAuth2+"token", url.Values{
+		"client_id":     {section.Key("client_id").String()},
+		"client_secret": {section.Key("client_secret").String()},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {discordOAuthRedirectURI()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token exchange failed: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	session, err := dg.New("Bearer " + token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	session.Client = d.oauthClient
+	return session.User("@me")
+}
+
+// verifyOAuth verifies the given PIN with the Discord user the authorization code belongs to, as /pin would, and opens a DM channel with them.
+// As with /pin, the user must be in the server.
+func (d *DiscordDaemon) verifyOAuth(pin, code string) error {
+	token, ok := d.tokens[pin]
+	if !ok {
+		return fmt.Errorf(lm.InvalidPIN, pin)
+	}
+	if time.Now().After(token.Expiry) {
+		delete(d.tokens, pin)
+		return fmt.Errorf(lm.ExpiredPIN, pin)
+	}
+	user, err := d.oauthUser(code)
+	if err != nil {
+		return err
+	}
+	if _, err := d.bot.GuildMember(d.guildID, user.ID); err != nil {
+		return errNotInDiscordGuild
+	}
+	channel, err := d.bot.UserChannelCreate(user.ID)
+	if err != nil {
+		return fmt.Errorf(lm.FailedCreateDiscordDMChannel, user.ID, err)
+	}
+	dcUser := d.MustGetUser(channel.ID, user.ID, user.Discriminator, user.Username)
+	if dcUser.ChannelID == "" {
+		dcUser.ChannelID = channel.ID
+	}
+	d.users[user.ID] = dcUser
+	dcUser.JellyfinID = token.JellyfinID
+	d.verifiedTokens[pin] = dcUser
+	delete(d.tokens, pin)
+	d.app.debug.Printf(lm.VerifiedDiscordOAuth, RenderDiscordUsername(dcUser), pin)
+	return nil
+}
+
+// discordOAuthPage renders the page shown at the end of the OAuth2 flow, with the given string as its description.
+func (app *appContext) discordOAuthPage(gc *gin.Context, code int, key string) {
+	lang := app.getLang(gc, UserPage, app.storage.lang.chosenUserLang)
+	strs := app.storage.lang.User[lang].Strings
+	app.gcHTML(gc, code, "discord-oauth.html", OtherPage, lang, gin.H{
+		"contactMessage": app.config.Section("ui").Key("contact_message").String(),
+		"strings":        strs,
+		"description":    strs.get(key),
+		"success":        code == http.StatusOK,
+	})
+}
+
+// @Summary Starts linking a Discord account through OAuth2, redirecting to Discord. Only works in the browser the PIN was given to.
+// @Produce html
+// @Param pin query string true "PIN being verified."
+// @Success 302 {string} string
+// @Failure 400 {string} string
+// @Router /discord/oauth/start [get]
+// @tags Other
+func (app *appContext) DiscordOAuthStart(gc *gin.Context) {
+	state, err := gc.Cookie(DISCORD_OAUTH_COOKIE)
+	if err != nil {
+		app.info.Printf(lm.FailedVerifyDiscordOAuth, lm.InvalidDiscordOAuthState)
+		app.discordOAuthPage(gc, 400, "discordOAuthFailed")
+		return
+	}
+	if pin, ok := app.discord.oauthStatePIN(state, false); !ok || pin != gc.Query("pin") {
+		app.info.Printf(lm.FailedVerifyDiscordOAuth, lm.InvalidDiscordOAuthState)
+		app.discordOAuthPage(gc, 400, "discordOAuthFailed")
+		return
+	}
+	gc.Redirect(http.StatusFound, app.discordOAuthURL(state))
+}
+
+// @Summary Completes linking a Discord account through OAuth2. Discord redirects users here after they authorize jfa-go, with the state given by /discord/oauth/start.
+// @Produce html
+// @Param code query string false "Authorization code from Discord. Missing if the user declined."
+// @Param state query string true "OAuth2 state, which must match the browser's cookie."
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Router /discord/oauth [get]
+// @tags Other
+func (app *appContext) DiscordOAuthCallback(gc *gin.Context) {
+	code := gc.Query("code")
+	if code == "" {
+		app.info.Printf(lm.FailedVerifyDiscordOAuth, gc.Query("error"))
+		app.discordOAuthPage(gc, 400, "discordOAuthDeclined")
+		return
+	}
+	state := gc.Query("state")
+	cookie, err := gc.Cookie(DISCORD_OAUTH_COOKIE)
+	if err != nil || cookie != state {
+		app.info.Printf(lm.FailedVerifyDiscordOAuth, lm.InvalidDiscordOAuthState)
+		app.discordOAuthPage(gc, 400, "discordOAuthFailed")
+		return
+	}
+	gc.SetCookie(DISCORD_OAUTH_COOKIE, "invalid", -1, "/", gc.Request.URL.Hostname(), true, true)
+	pin, ok := app.discord.oauthStatePIN(state, true)
+	if !ok {
+		app.info.Printf(lm.FailedVerifyDiscordOAuth, lm.InvalidDiscordOAuthState)
+		app.discordOAuthPage(gc, 400, "discordOAuthFailed")
+		return
+	}
+	if err := app.discord.verifyOAuth(pin, code); err != nil {
+		app.info.Printf(lm.FailedVerifyDiscordOAuth, err)
+		key := "discordOAuthFailed"
+		if errors.Is(err, errNotInDiscordGuild) {
+			key = "discordOAuthNotMember"
+		}
+		app.discordOAuthPage(gc, 400, key)
+		return
+	}
+	app.discordOAuthPage(gc, http.StatusOK, "discordOAuthSuccess")
+}
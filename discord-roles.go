@@ -0,0 +1,245 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Maximum number of guild members Discord returns per request.
+const DISCORD_MEMBER_PAGE_SIZE = 1000
+
+// parseRoleMapping parses entries of the form "<name>=<role>, <role>" into a map of names to roles.
+// Also used for mapping profiles to Matrix rooms.
+func parseRoleMapping(entries []string) map[string][]string {
+	mapping := map[string][]string{}
+	for _, entry := range entries {
+		name, roles, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		for _, role := range strings.Split(roles, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				mapping[name] = append(mapping[name], role)
+			}
+		}
+	}
+	return mapping
+}
+
+// discordRoleMapping holds the IDs of the roles mapped to each profile and user label.
+type discordRoleMapping struct {
+	Profiles map[string][]string
+	Labels   map[string][]string
+	Managed  map[string]bool // Every mapped role. Roles not in here are never added or removed.
+}
+
+// wanted returns the roles mapped to the given profile and label.
+func (m discordRoleMapping) wanted(profile, label string) []string {
+	roles := append([]string{}, m.Profiles[profile]...)
+	return append(roles, m.Labels[label]...)
+}
+
+// discordRolesMapped returns whether any profiles or labels have been mapped to Discord roles.
+func (app *appContext) discordRolesMapped() bool {
+	section := app.config.Section("discord")
+	return len(parseRoleMapping(section.Key("profile_roles").StringsWithShadows("|"))) != 0 ||
+		len(parseRoleMapping(section.Key("label_roles").StringsWithShadows("|"))) != 0
+}
+
+// roleMapping reads the profile and label role mappings from the config, resolving role names to IDs.
+func (d *DiscordDaemon) roleMapping() (m discordRoleMapping, ok bool) {
+	section := d.app.config.Section("discord")
+	profiles := parseRoleMapping(section.Key("profile_roles").StringsWithShadows("|"))
+	labels := parseRoleMapping(section.Key("label_roles").StringsWithShadows("|"))
+	if len(profiles) == 0 && len(labels) == 0 {
+		return
+	}
+	roles, err := d.bot.GuildRoles(d.guildID)
+	if err != nil {
+		d.app.err.Printf(lm.FailedGetDiscordRoles, err)
+		return
+	}
+	ids := map[string]string{}
+	for _, role := range roles {
+		ids[strings.ToLower(role.Name)] = role.ID
+	}
+	for _, role := range roles {
+		ids[role.ID] = role.ID
+	}
+	m = discordRoleMapping{
+		Profiles: map[string][]string{},
+		Labels:   map[string][]string{},
+		Managed:  map[string]bool{},
+	}
+	resolve := func(mapping map[string][]string, out map[string][]string) {
+		for name, roles := range mapping {
+			for _, role := range roles {
+				id, ok := ids[role]
+				if !ok {
+					id, ok = ids[strings.ToLower(role)]
+				}
+				if !ok {
+					d.app.err.Printf(lm.UnknownDiscordRole, role)
+					continue
+				}
+				out[name] = append(out[name], id)
+				m.Managed[id] = true
+			}
+		}
+	}
+	resolve(profiles, m.Profiles)
+	resolve(labels, m.Labels)
+	ok = true
+	return
+}
+
+// setMemberRoles adds the wanted roles the member doesn't have, and removes any other mapped roles they have.
+func (d *DiscordDaemon) setMemberRoles(m discordRoleMapping, userID string, current, wanted []string) (added, removed int) {
+	has := map[string]bool{}
+	for _, role := range current {
+		has[role] = true
+	}
+	want := map[string]bool{}
+	for _, role := range wanted {
+		if want[role] {
+			continue
+		}
+		want[role] = true
+		if has[role] {
+			continue
+		}
+		if err := d.bot.GuildMemberRoleAdd(d.guildID, userID, role); err != nil {
+			d.app.err.Printf(lm.FailedSetDiscordMemberRole, err)
+		} else {
+			added++
+		}
+	}
+	for _, role := range current {
+		if !m.Managed[role] || want[role] {
+			continue
+		}
+		if err := d.bot.GuildMemberRoleRemove(d.guildID, userID, role); err != nil {
+			d.app.err.Printf(lm.FailedSetDiscordMemberRole, err)
+		} else {
+			removed++
+		}
+	}
+	return
+}
+
+// wantedDiscordRoles returns the mapped roles wanted by the given linked accounts. Disabled or deleted accounts don't want any.
+// "disabled" overrides whether the given accounts are disabled, otherwise it's looked up.
+func (app *appContext) wantedDiscordRoles(m discordRoleMapping, accounts []DiscordUser, disabled map[string]bool) []string {
+	downgradeProfile := app.config.Section("user_expiry").Key("downgrade_profile").String()
+	roles := []string{}
+	for _, account := range accounts {
+		isDisabled, ok := disabled[account.JellyfinID]
+		if !ok {
+			user, err := app.jf.UserByID(account.JellyfinID, false)
+			isDisabled = err != nil || user.Policy.IsDisabled
+		}
+		if isDisabled {
+			continue
+		}
+		emailStore, _ := app.storage.GetEmailsKey(account.JellyfinID)
+		profile := emailStore.Profile
+		if expiry, ok := app.storage.GetUserExpiryKey(account.JellyfinID); ok && expiry.Downgraded {
+			profile = downgradeProfile
+		}
+		roles = append(roles, m.wanted(profile, emailStore.Label)...)
+	}
+	return roles
+}
+
+// syncDiscordMember gives the Discord user the roles mapped to the profiles and labels of the accounts linked to it, and removes any other mapped roles.
+// "disabled" overrides whether the given accounts are disabled, otherwise it's looked up.
+func (app *appContext) syncDiscordMember(discordID string, disabled map[string]bool) {
+	if app.discord == nil || !app.discordRolesMapped() {
+		return
+	}
+	m, ok := app.discord.roleMapping()
+	if !ok {
+		return
+	}
+	member, err := app.discord.bot.GuildMember(app.discord.guildID, discordID)
+	if err != nil {
+		app.err.Printf(lm.FailedGetDiscordGuildMember, discordID, err)
+		return
+	}
+	accounts := []DiscordUser{}
+	app.storage.db.Find(&accounts, badgerhold.Where("ID").Eq(discordID))
+	app.discord.setMemberRoles(m, discordID, member.Roles, app.wantedDiscordRoles(m, accounts, disabled))
+}
+
+// syncDiscordRoles syncs the mapped roles of the Discord account linked to the given user, if there is one.
+func (app *appContext) syncDiscordRoles(jfID string, disabled bool) {
+	if app.discord == nil || !app.discordRolesMapped() {
+		return
+	}
+	if dcUser, ok := app.storage.GetDiscordKey(jfID); ok {
+		app.syncDiscordMember(dcUser.ID, map[string]bool{jfID: disabled})
+	}
+}
+
+// syncDiscordRolesForUser is syncDiscordRoles for when the user's disabled state isn't known.
+func (app *appContext) syncDiscordRolesForUser(jfID string) {
+	if app.discord == nil || !app.discordRolesMapped() {
+		return
+	}
+	user, err := app.jf.UserByID(jfID, false)
+	app.syncDiscordRoles(jfID, err != nil || user.Policy.IsDisabled)
+}
+
+// reconcileDiscordRoles corrects the mapped roles of every guild member: linked members are given the roles they should have,
+// and mapped roles are removed from anyone who shouldn't have them, including members with no linked account.
+func (app *appContext) reconcileDiscordRoles() {
+	if app.discord == nil || !app.discordRolesMapped() {
+		return
+	}
+	m, ok := app.discord.roleMapping()
+	if !ok {
+		return
+	}
+	linked := map[string][]DiscordUser{}
+	for _, account := range app.storage.GetDiscord() {
+		linked[account.ID] = append(linked[account.ID], account)
+	}
+	added, removed := 0, 0
+	after := ""
+	for {
+		members, err := app.discord.bot.GuildMembers(app.discord.guildID, after, DISCORD_MEMBER_PAGE_SIZE)
+		if err != nil {
+			app.err.Printf(lm.FailedGetDiscordGuildMembers, err)
+			return
+		}
+		for _, member := range members {
+			if member.User == nil || member.User.Bot {
+				continue
+			}
+			a, r := app.discord.setMemberRoles(m, member.User.ID, member.Roles, app.wantedDiscordRoles(m, linked[member.User.ID], nil))
+			added += a
+			removed += r
+		}
+		if len(members) < DISCORD_MEMBER_PAGE_SIZE {
+			break
+		}
+		after = members[len(members)-1].User.ID
+	}
+	if added != 0 || removed != 0 {
+		app.info.Printf(lm.ReconciledDiscordRoles, added, removed)
+	}
+}
+
+func newDiscordRoleDaemon(interval time.Duration, app *appContext) *GenericDaemon {
+	d := NewGenericDaemon(interval, app,
+		func(app *appContext) {
+			app.reconcileDiscordRoles()
+		},
+	)
+	d.Name("Discord role reconciliation")
+	return d
+}
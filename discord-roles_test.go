@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRoleMapping(t *testing.T) {
+	got := parseRoleMapping([]string{
+		"4K=4K Members, 123456789",
+		" Trial = Trial ",
+		"Trial=Extra",
+		"NoRoles=",
+		"=Orphan",
+		"Invalid",
+	})
+	want := map[string][]string{
+		"4K":    {"4K Members", "123456789"},
+		"Trial": {"Trial", "Extra"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDiscordRoleMappingWanted(t *testing.T) {
+	m := discordRoleMapping{
+		Profiles: map[string][]string{"4K": {"a", "b"}},
+		Labels:   map[string][]string{"trial": {"c"}},
+	}
+	if got := m.wanted("4K", "trial"); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("got %v", got)
+	}
+	if got := m.wanted("Default", ""); len(got) != 0 {
+		t.Errorf("expected no roles, got %v", got)
+	}
+	// The mapping itself mustn't be modified.
+	m.wanted("4K", "trial")
+	if len(m.Profiles["4K"]) != 2 {
+		t.Errorf("mapping was modified: %v", m.Profiles["4K"])
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// userCommandDescriptions returns the commands available to users with a linked Discord account.
+func (d *DiscordDaemon) userCommandDescriptions() []*dg.ApplicationCommand {
+	if !d.app.config.Section("discord").Key("user_commands").MustBool(true) {
+		return nil
+	}
+	commands := []*dg.ApplicationCommand{
+		{
+			Name:        "account",
+			Description: "Show details of your linked account.",
+		},
+	}
+	if d.app.config.Section("user_page").Key("enabled").MustBool(true) && d.app.config.Section("ui").Key("jellyfin_login").MustBool(true) {
+		commands = append(commands, &dg.ApplicationCommand{
+			Name:        "resetpassword",
+			Description: "Receive a link to reset your password by DM.",
+		})
+		if d.app.config.Section("user_page").Key("referrals").MustBool(false) {
+			commands = append(commands, &dg.ApplicationCommand{
+				Name:        "referral",
+				Description: "Get your referral link to invite others.",
+			})
+		}
+	}
+	return commands
+}
+
+// respondEphemeral responds to the interaction with a message only visible to the user.
+func (d *DiscordDaemon) respondEphemeral(s *dg.Session, i *dg.InteractionCreate, content string) {
+	err := s.InteractionRespond(i.Interaction, &dg.InteractionResponse{
+		Type: dg.InteractionResponseChannelMessageWithSource,
+		Data: &dg.InteractionResponseData{
+			Content: content,
+			Flags:   64, // Ephemeral
+		},
+	})
+	if err != nil {
+		d.app.err.Printf(lm.FailedReply, lm.Discord, i.Interaction.Member.User.ID, err)
+	}
+}
+
+// linkedJellyfinID returns the Jellyfin ID of the account the user who sent the interaction has linked their Discord to.
+// If they aren't linked, they're told so.
+func (d *DiscordDaemon) linkedJellyfinID(s *dg.Session, i *dg.InteractionCreate, lang string) (string, bool) {
+	users := []DiscordUser{}
+	err := d.app.storage.db.Find(&users, badgerhold.Where("ID").Eq(i.Interaction.Member.User.ID).Limit(1))
+	if err != nil || len(users) == 0 || users[0].JellyfinID == "" {
+		d.respondEphemeral(s, i, d.app.storage.lang.Telegram[lang].Strings.get("discordNotLinked"))
+		return "", false
+	}
+	return users[0].JellyfinID, true
+}
+
+func (d *DiscordDaemon) cmdAccount(s *dg.Session, i *dg.InteractionCreate, lang string) {
+	jfID, ok := d.linkedJellyfinID(s, i, lang)
+	if !ok {
+		return
+	}
+	strs := d.app.storage.lang.Telegram[lang].Strings
+	jfUser, err := d.app.jf.UserByID(jfID, false)
+	if err != nil {
+		d.app.err.Printf(lm.FailedGetUser, jfID, lm.Jellyfin, err)
+		d.respondEphemeral(s, i, strs.get("commandFailed"))
+		return
+	}
+	user := d.app.GetUserSummary(jfUser)
+	expiry := strs.get("never")
+	if user.Expiry != 0 {
+		expiry = formatDatetime(time.Unix(user.Expiry, 0))
+	}
+	content := strs.get("username") + ": " + user.Name + "\n" + strs.get("expiry") + ": " + expiry
+	if user.Label != "" {
+		content += "\n" + strs.get("label") + ": " + user.Label
+	}
+	d.respondEphemeral(s, i, content)
+}
+
+func (d *DiscordDaemon) cmdResetPassword(s *dg.Session, i *dg.InteractionCreate, lang string) {
+	jfID, ok := d.linkedJellyfinID(s, i, lang)
+	if !ok {
+		return
+	}
+	strs := d.app.storage.lang.Telegram[lang].Strings
+	pwr, msg, err := d.app.newInternalReset(jfID)
+	if err != nil {
+		d.respondEphemeral(s, i, strs.get("resetPasswordFailure"))
+		return
+	}
+	username := RenderDiscordUsername(i.Interaction.Member.User)
+	if err := d.SendDM(msg, i.Interaction.Member.User.ID); err != nil {
+		d.app.err.Printf(lm.FailedSendPWRMessage, pwr.Username, username, err)
+		d.respondEphemeral(s, i, strs.get("resetPasswordFailure"))
+		return
+	}
+	d.app.info.Printf(lm.SentPWRMessage, pwr.Username, username)
+	d.respondEphemeral(s, i, strs.get("resetPasswordSent"))
+}
+
+func (d *DiscordDaemon) cmdReferral(s *dg.Session, i *dg.InteractionCreate, lang string) {
+	jfID, ok := d.linkedJellyfinID(s, i, lang)
+	if !ok {
+		return
+	}
+	strs := d.app.storage.lang.Telegram[lang].Strings
+	if !d.app.config.Section("user_page").Key("referrals").MustBool(false) {
+		d.respondEphemeral(s, i, strs.get("noReferral"))
+		return
+	}
+	inv, ok := d.app.getOrCreateReferral(jfID)
+	if !ok {
+		d.respondEphemeral(s, i, strs.get("noReferral"))
+		return
+	}
+	d.app.InvalidateWebUserCache()
+	d.respondEphemeral(s, i, strs.template("referralLink", tmpl{
+		"link":   ExternalURI(nil) + PAGES.Form + "/" + inv.Code,
+		"expiry": formatDatetime(inv.ValidTill),
+	}))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	dg "github.com/bwmarrin/discordgo"
@@ -30,6 +31,11 @@ type DiscordDaemon struct {
 	commandIDs                    []string
 	commandDescriptions           []*dg.ApplicationCommand
 	retryOpts                     *common.MustAuthenticateOptions
+	departures                    map[string]bool // Map of departing user IDs to whether they were banned, while waiting for DISCORD_DEPARTURE_DELAY.
+	departuresLock                sync.Mutex
+	oauthClient                   *http.Client
+	oauthStates                   map[string]DiscordOAuthState // Map of OAuth2 states to the PINs they were issued for.
+	oauthStatesLock               sync.Mutex
 }
 
 func EmptyDiscordUser() *DiscordUser {
@@ -63,11 +69,17 @@ func newDiscordDaemon(app *appContext) (*DiscordDaemon, error) {
 		roleID:          app.config.Section("discord").Key("apply_role").String(),
 		commandHandlers: map[string]func(s *dg.Session, i *dg.InteractionCreate, lang string){},
 		commandIDs:      []string{},
+		departures:      map[string]bool{},
+		oauthClient:     &http.Client{Timeout: 10 * time.Second},
+		oauthStates:     map[string]DiscordOAuthState{},
 	}
 	dd.commandHandlers[app.config.Section("discord").Key("start_command").MustString("start")] = dd.cmdStart
 	dd.commandHandlers["lang"] = dd.cmdLang
 	dd.commandHandlers["pin"] = dd.cmdPIN
 	dd.commandHandlers["inv"] = dd.cmdInvite
+	dd.commandHandlers["account"] = dd.cmdAccount
+	dd.commandHandlers["resetpassword"] = dd.cmdResetPassword
+	dd.commandHandlers["referral"] = dd.cmdReferral
 	for _, user := range app.storage.GetDiscord() {
 		dd.users[user.ID] = user
 	}
@@ -80,7 +92,10 @@ func newDiscordDaemon(app *appContext) (*DiscordDaemon, error) {
 
 	dd.bot.AddHandler(dd.commandHandler)
 
-	dd.bot.Identify.Intents = dg.IntentsGuildMessages | dg.IntentsDirectMessages | dg.IntentsGuildMembers | dg.IntentsGuildInvites
+	dd.bot.AddHandler(dd.memberRemoveHandler)
+	dd.bot.AddHandler(dd.banHandler)
+	dd.bot.AddHandler(dd.memberAddHandler)
+	dd.bot.Identify.Intents = dg.IntentsGuildMessages | dg.IntentsDirectMessages | dg.IntentsGuildMembers | dg.IntentsGuildInvites | dg.IntentsGuildBans
 
 	return dd, nil
 }
@@ -88,6 +103,7 @@ func newDiscordDaemon(app *appContext) (*DiscordDaemon, error) {
 // SetTransport sets the http.Transport to use for requests. Can be used to set a proxy.
 func (d *DiscordDaemon) SetTransport(t *http.Transport) {
 	d.bot.Client.Transport = t
+	d.oauthClient.Transport = t
 }
 
 // NewAuthToken generates an 8-character pin in the form "A1-2B-CD".
@@ -463,6 +479,8 @@ func (d *DiscordDaemon) registerCommands(retry *common.MustAuthenticateOptions)
 		}
 	}
 
+	d.commandDescriptions = append(d.commandDescriptions, d.userCommandDescriptions()...)
+
 	// d.deregisterCommands()
 
 	d.commandIDs = make([]string, len(d.commandDescriptions))
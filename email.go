@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
@@ -14,6 +15,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	textTemplate "text/template"
@@ -29,6 +32,7 @@ import (
 	"github.com/itchyny/timefmt-go"
 	"github.com/mailgun/mailgun-go/v4"
 	"github.com/timshannon/badgerhold/v4"
+	"github.com/toorop/go-dkim"
 	sMail "github.com/xhit/go-simple-mail/v2"
 )
 
@@ -55,6 +59,10 @@ type Message struct {
 	HTML     string `json:"html"`
 	Text     string `json:"text"`
 	Markdown string `json:"markdown"`
+	Type     string `json:"type"`     // CustomContentInfo.Name of the content the message was built from.
+	Category string `json:"category"` // Message category given to sendByID, used for unsubscribe links.
+	// One-click unsubscribe link, added to email headers if set.
+	UnsubscribeURL string `json:"-"`
 }
 
 func (emailer *Emailer) formatExpiry(expiry time.Time, tzaware bool) (d, t, expiresIn string) {
@@ -112,8 +120,38 @@ func NewEmailer(config *Config, storage *Storage, logs LoggerSet) *Emailer {
 		if err != nil {
 			emailer.err.Printf(lm.FailedInitMailer, lm.SMTP, err)
 		}
+		if selector, keyPath := emailer.config.Section("smtp").Key("dkim_selector").String(), emailer.config.Section("smtp").Key("dkim_private_key").String(); selector != "" && keyPath != "" {
+			domain := emailer.config.Section("smtp").Key("dkim_domain").String()
+			if domain == "" {
+				domain = emailer.fromAddr[strings.LastIndex(emailer.fromAddr, "@")+1:]
+			}
+			if sender, ok := emailer.sender.(*SMTP); ok {
+				if err := sender.SetDKIM(keyPath, domain, selector); err != nil {
+					emailer.err.Printf(lm.FailedInitDKIM, err)
+				} else {
+					emailer.info.Printf(lm.InitDKIM, domain, selector)
+				}
+			}
+		}
 	} else if method == "mailgun" {
 		emailer.NewMailgun(emailer.config.Section("mailgun").Key("api_url").String(), emailer.config.Section("mailgun").Key("api_key").String(), emailer.config.proxyTransport)
+	} else if method == "sendmail" {
+		if err := emailer.NewSendmail(emailer.config.Section("sendmail").Key("path").MustString("sendmail")); err != nil {
+			emailer.err.Printf(lm.FailedInitMailer, lm.Sendmail, err)
+		}
+	} else if method == "http" {
+		section := emailer.config.Section("http_email")
+		err := emailer.NewHTTPMailer(
+			section.Key("url").String(),
+			section.Key("method").In("POST", []string{"POST", "PUT"}),
+			section.Key("body").String(),
+			section.Key("headers").String(),
+			section.Key("token").String(),
+			emailer.config.proxyTransport,
+		)
+		if err != nil {
+			emailer.err.Printf(lm.FailedInitMailer, lm.HTTPMail, err)
+		}
 	} else if method == "dummy" {
 		emailer.sender = &DummyClient{}
 	}
@@ -131,6 +169,38 @@ func (dc *DummyClient) Send(fromName, fromAddr string, email *Message, address .
 // SMTP supports SSL/TLS and STARTTLS; implements EmailClient.
 type SMTP struct {
 	Client *sMail.SMTPServer
+	// DKIM signing details, no signing is done if dkimKey is empty.
+	dkimKey, dkimDomain, dkimSelector string
+}
+
+// Headers signed with DKIM, if present.
+var dkimHeaders = []string{"from", "to", "subject", "date", "mime-version", "content-type", "list-unsubscribe", "list-unsubscribe-post"}
+
+// SetDKIM loads the PEM-encoded RSA private key at keyPath, and enables DKIM signing of sent messages for the given domain and selector.
+func (sm *SMTP) SetDKIM(keyPath, domain, selector string) error {
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	// Sign a test message, so an invalid key is reported now rather than on every send.
+	opts := sm.dkimOptions(string(key), domain, selector)
+	test := []byte("From: test@" + domain + "\r\n\r\ntest\r\n")
+	if err := dkim.Sign(&test, opts); err != nil {
+		return err
+	}
+	sm.dkimKey, sm.dkimDomain, sm.dkimSelector = string(key), domain, selector
+	return nil
+}
+
+// dkimOptions returns fresh signing options, as dkim.Sign modifies them.
+func (sm *SMTP) dkimOptions(key, domain, selector string) dkim.SigOptions {
+	opts := dkim.NewSigOptions()
+	opts.PrivateKey = []byte(key)
+	opts.Domain = domain
+	opts.Selector = selector
+	opts.Canonicalization = "relaxed/relaxed"
+	opts.Headers = slices.Clone(dkimHeaders)
+	return opts
 }
 
 // NewSMTP returns an SMTP emailClient.
@@ -184,8 +254,24 @@ func (emailer *Emailer) NewSMTP(server string, port int, username, password stri
 	return
 }
 
+// newMIMEMessage builds the RFC 5322 message sent by the SMTP and sendmail clients.
+func newMIMEMessage(fromName, fromAddr string, email *Message, address ...string) *sMail.Email {
+	e := sMail.NewMSG()
+	e.SetFrom(fmt.Sprintf("%s <%s>", fromName, fromAddr))
+	e.SetSubject(email.Subject)
+	e.AddTo(address...)
+	e.SetBody(sMail.TextPlain, email.Text)
+	if email.HTML != "" {
+		e.AddAlternative(sMail.TextHTML, email.HTML)
+	}
+	if email.UnsubscribeURL != "" {
+		e.AddHeader("List-Unsubscribe", "<"+email.UnsubscribeURL+">")
+		e.AddHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	return e
+}
+
 func (sm *SMTP) Send(fromName, fromAddr string, email *Message, address ...string) error {
-	from := fmt.Sprintf("%s <%s>", fromName, fromAddr)
 	var cli *sMail.SMTPClient
 	var err error
 	cli, err = sm.Client.Connect()
@@ -193,18 +279,44 @@ func (sm *SMTP) Send(fromName, fromAddr string, email *Message, address ...strin
 		return err
 	}
 	defer cli.Close()
-	e := sMail.NewMSG()
-	e.SetFrom(from)
-	e.SetSubject(email.Subject)
-	e.AddTo(address...)
-	e.SetBody(sMail.TextPlain, email.Text)
-	if email.HTML != "" {
-		e.AddAlternative(sMail.TextHTML, email.HTML)
+	e := newMIMEMessage(fromName, fromAddr, email, address...)
+	if sm.dkimKey != "" {
+		e.SetDkim(sm.dkimOptions(sm.dkimKey, sm.dkimDomain, sm.dkimSelector))
 	}
 	err = e.Send(cli)
 	return err
 }
 
+// Sendmail pipes messages to a local sendmail-compatible binary; implements EmailClient.
+type Sendmail struct {
+	path string
+}
+
+// NewSendmail returns a Sendmail emailClient, using the binary at the given path (or found in $PATH).
+func (emailer *Emailer) NewSendmail(path string) error {
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return err
+	}
+	emailer.sender = &Sendmail{path: path}
+	return nil
+}
+
+func (sm *Sendmail) Send(fromName, fromAddr string, email *Message, address ...string) error {
+	e := newMIMEMessage(fromName, fromAddr, email, address...)
+	if e.Error != nil {
+		return e.Error
+	}
+	// -i stops a line containing only "." ending the message early.
+	cmd := exec.Command(sm.path, append([]string{"-i", "-f", fromAddr, "--"}, address...)...)
+	cmd.Stdin = strings.NewReader(e.GetMessage())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 // Mailgun client implements EmailClient.
 type Mailgun struct {
 	client *mailgun.MailgunImpl
@@ -241,12 +353,109 @@ func (mg *Mailgun) Send(fromName, fromAddr string, email *Message, address ...st
 		message.AddRecipientAndVariables(a, map[string]interface{}{"unique_id": a})
 	}
 	message.SetHtml(email.HTML)
+	if email.UnsubscribeURL != "" {
+		message.AddHeader("List-Unsubscribe", "<"+email.UnsubscribeURL+">")
+		message.AddHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 	_, _, err := mg.client.Send(ctx, message)
 	return err
 }
 
+// HTTPMailer posts each message as JSON to a mail provider's API, with the body and headers templated from the config; implements EmailClient.
+type HTTPMailer struct {
+	url, method, body, token string
+	headers                  [][2]string
+	client                   *http.Client
+}
+
+// NewHTTPMailer returns an HTTPMailer emailClient. headers are given as "Name: value" pairs separated by "|", as values can contain semicolons.
+func (emailer *Emailer) NewHTTPMailer(url, method, body, headers, token string, transport *http.Transport) error {
+	if url == "" {
+		return errors.New("no API URL set")
+	}
+	sender := &HTTPMailer{
+		url:     url,
+		method:  method,
+		body:    body,
+		token:   token,
+		headers: parseHTTPMailHeaders(headers),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	if transport != nil {
+		sender.client.Transport = transport
+	}
+	emailer.sender = sender
+	return nil
+}
+
+func parseHTTPMailHeaders(headers string) [][2]string {
+	out := [][2]string{}
+	for _, header := range strings.Split(headers, "|") {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		out = append(out, [2]string{name, strings.TrimSpace(value)})
+	}
+	return out
+}
+
+// httpMailVars returns the variables available to the body and header templates. Values are JSON-encoded (so strings include quotes) if forJSON is set.
+func httpMailVars(fromName, fromAddr, token string, email *Message, address string, forJSON bool) tmpl {
+	vars := map[string]string{
+		"from":        fmt.Sprintf("%s <%s>", fromName, fromAddr),
+		"fromName":    fromName,
+		"fromAddress": fromAddr,
+		"to":          address,
+		"subject":     email.Subject,
+		"html":        email.HTML,
+		"text":        email.Text,
+		"unsubscribe": email.UnsubscribeURL,
+		"token":       token,
+	}
+	out := tmpl{}
+	for k, v := range vars {
+		if forJSON {
+			encoded, _ := json.Marshal(v)
+			out[k] = string(encoded)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func (hm *HTTPMailer) Send(fromName, fromAddr string, email *Message, address ...string) error {
+	var errs []error
+	// Sent individually, so users don't see other recipients.
+	for _, addr := range address {
+		body := templateString(hm.body, httpMailVars(fromName, fromAddr, hm.token, email, addr, true))
+		req, err := http.NewRequest(hm.method, hm.url, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		headerVars := httpMailVars(fromName, fromAddr, hm.token, email, addr, false)
+		for _, header := range hm.headers {
+			req.Header.Set(header[0], templateString(header[1], headerVars))
+		}
+		resp, err := hm.client.Do(req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			errs = append(errs, fmt.Errorf(lm.FailedGenericWithCode, resp.StatusCode))
+		}
+		resp.Body.Close()
+	}
+	return errors.Join(errs...)
+}
+
 type templ interface {
 	Execute(wr io.Writer, data interface{}) error
 }
@@ -254,6 +463,7 @@ type templ interface {
 func (emailer *Emailer) construct(contentInfo CustomContentInfo, cc CustomContent, data map[string]any) (*Message, error) {
 	msg := &Message{
 		Subject: contentInfo.Subject(emailer.config, &emailer.lang),
+		Type:    contentInfo.Name,
 	}
 	// Template the subject for bonus points
 	if subject, err := sTemplate.Template(msg.Subject, data); err == nil {
@@ -608,42 +818,262 @@ func (emailer *Emailer) constructUserExpired(username string, placeholders bool)
 	return emailer.construct(contentInfo, cc, template)
 }
 
-// calls the send method in the underlying emailClient.
+func (emailer *Emailer) constructAdopted(username, profile, label string, expiry, when time.Time, placeholders bool) (*Message, error) {
+	none := emailer.lang.Strings.get("none")
+	exp := none
+	if !expiry.IsZero() {
+		exp = formatDatetime(expiry)
+	}
+	if profile == "" {
+		profile = none
+	}
+	if label == "" {
+		label = none
+	}
+	contentInfo, template := emailer.baseValues("UserAdopted", username, placeholders, map[string]any{
+		"aUserWasAdopted": emailer.lang.UserAdopted.get("aUserWasAdopted"),
+		"nameString":      emailer.lang.Strings.get("name"),
+		"profileString":   emailer.lang.UserAdopted.get("profile"),
+		"expiryString":    emailer.lang.Strings.get("expiry"),
+		"labelString":     emailer.lang.UserAdopted.get("label"),
+		"timeString":      emailer.lang.UserAdopted.get("time"),
+		"name":            username,
+		"profile":         profile,
+		"expiry":          exp,
+		"label":           label,
+		"time":            formatDatetime(when),
+	})
+	cc := emailer.storage.MustGetCustomContentKey(contentInfo.Name)
+	return emailer.construct(contentInfo, cc, template)
+}
+
+func (emailer *Emailer) constructAdminDigest(digest AdminDigest, placeholders bool) (*Message, error) {
+	none := emailer.lang.Strings.get("none")
+	list := func(items []string) string {
+		if len(items) == 0 {
+			return none
+		}
+		return "- " + strings.Join(items, "\n- ")
+	}
+	period := formatDatetime(digest.Start) + " - " + formatDatetime(digest.End)
+	summary := emailer.lang.AdminDigest.template("summary", tmpl{"period": period})
+	if placeholders {
+		summary = emailer.lang.AdminDigest.template("summary", tmpl{"period": "{period}"})
+	}
+	contentInfo, template := emailer.baseValues("AdminDigest", "", placeholders, map[string]any{
+		"summary":                summary,
+		"signupsString":          emailer.lang.AdminDigest.get("signups"),
+		"invitesUsedString":      emailer.lang.AdminDigest.get("invitesUsed"),
+		"invitesExpiredString":   emailer.lang.AdminDigest.get("invitesExpired"),
+		"expiringUsersString":    emailer.lang.AdminDigest.get("expiringUsers"),
+		"disabledUsersString":    emailer.lang.AdminDigest.get("disabledUsers"),
+		"deletedUsersString":     emailer.lang.AdminDigest.get("deletedUsers"),
+		"failedDeliveriesString": emailer.lang.AdminDigest.get("failedDeliveries"),
+		"backupsString":          emailer.lang.AdminDigest.get("backups"),
+		"period":                 period,
+		"signupCount":            strconv.Itoa(len(digest.Signups)),
+		"signups":                list(digest.Signups),
+		"invitesUsed":            strconv.Itoa(digest.InvitesUsed),
+		"invitesExpired":         strconv.Itoa(digest.InvitesExpired),
+		"expiringUsers":          list(digest.Expiring),
+		"disabledUsers":          list(digest.Disabled),
+		"deletedUsers":           list(digest.Deleted),
+		"failedDeliveries":       strconv.Itoa(digest.FailedDeliveries),
+		"backupStatus":           digest.Backup,
+	})
+	cc := emailer.storage.MustGetCustomContentKey(contentInfo.Name)
+	return emailer.construct(contentInfo, cc, template)
+}
+
+// constructAdminEvent constructs the admin notification for the given event in adminEvents, with the given values of its variables.
+func (emailer *Emailer) constructAdminEvent(event string, vars map[string]any, when time.Time, placeholders bool) (*Message, error) {
+	info := adminEvents[event]
+	if placeholders {
+		vars = map[string]any{}
+		for _, v := range customContent[info.Content].Variables {
+			vars[v] = "{" + v + "}"
+		}
+	}
+	values := map[string]any{
+		"message":    emailer.lang.AdminEvents.template(info.Lang, vars),
+		"timeString": emailer.lang.AdminEvents.get("time"),
+		"time":       formatDatetime(when),
+	}
+	maps.Copy(values, vars)
+	contentInfo, template := emailer.baseValues(info.Content, "", placeholders, values)
+	cc := emailer.storage.MustGetCustomContentKey(contentInfo.Name)
+	return emailer.construct(contentInfo, cc, template)
+}
+
+// send sends the message to the given addresses, recording it in the delivery log.
 func (emailer *Emailer) send(email *Message, address ...string) error {
-	return emailer.sender.Send(emailer.fromName, emailer.fromAddr, email, address...)
+	err := emailer.sender.Send(emailer.fromName, emailer.fromAddr, email, address...)
+	for _, addr := range address {
+		emailer.storage.logDelivery(email, ChannelEmail, emailer.storage.userByAddress(addr), addr, err)
+	}
+	return err
+}
+
+// Categories of message users can choose to receive on each channel.
+const (
+	MessageCategoryRequired      = "" // Always sent, e.g. password resets.
+	MessageCategoryAnnouncements = "announcements"
+	MessageCategoryExpiry        = "expiry"
+	MessageCategoryAccount       = "account"
+)
+
+var messageCategories = []string{MessageCategoryAnnouncements, MessageCategoryExpiry, MessageCategoryAccount}
+
+// wantsMessage returns whether the user wants messages of the given category sent through the given channel.
+func (app *appContext) wantsMessage(jfID, category, channel string) bool {
+	if category == MessageCategoryRequired {
+		return true
+	}
+	prefs, ok := app.storage.GetMessagePreferencesKey(jfID)
+	return !ok || !prefs.Disabled[category][channel]
+}
+
+// messagePreferences returns whether each category of message is sent on each channel for the given user.
+func (app *appContext) messagePreferences(jfID string) MessagePreferencesDTO {
+	prefs, _ := app.storage.GetMessagePreferencesKey(jfID)
+	resp := MessagePreferencesDTO{}
+	for _, category := range messageCategories {
+		resp[category] = map[string]bool{}
+		for _, channel := range queueChannels {
+			resp[category][channel] = !prefs.Disabled[category][channel]
+		}
+	}
+	return resp
+}
+
+// sendByID queues the message to be sent to each of the given users, through each contact method they've enabled and want messages of the given category on.
+// Returned errors are from queueing only, failed deliveries are retried by app.messageQueue.
+func (app *appContext) sendByID(email *Message, category string, ID ...string) error {
+	return app.sendByIDVia(email, category, nil, ID...)
 }
 
-func (app *appContext) sendByID(email *Message, ID ...string) (err error) {
+// sendByIDVia is sendByID, but only sends through the given channels. If none are given, any can be used.
+func (app *appContext) sendByIDVia(email *Message, category string, channels []string, ID ...string) error {
+	via := func(channel string) bool { return len(channels) == 0 || slices.Contains(channels, channel) }
+	var errs []error
+	msg := *email
+	msg.Category = category
+	queued := 0
+	queue := func(channel, id, destination string) {
+		if err := app.messageQueue.Enqueue(&msg, channel, id, "", destination); err != nil {
+			app.err.Printf(lm.FailedQueueMessage, channel, id, err)
+			errs = append(errs, err)
+			return
+		}
+		queued++
+	}
 	for _, id := range ID {
-		if tgChat, ok := app.storage.GetTelegramKey(id); ok && tgChat.Contact && telegramEnabled {
-			err = app.telegram.Send(email, tgChat.ChatID)
-			// if err != nil {
-			// 	return err
-			// }
-		}
-		if dcChat, ok := app.storage.GetDiscordKey(id); ok && dcChat.Contact && discordEnabled {
-			err = app.discord.Send(email, dcChat.ChannelID)
-			// if err != nil {
-			// 	return err
-			// }
-		}
-		if mxChat, ok := app.storage.GetMatrixKey(id); ok && mxChat.Contact && matrixEnabled {
-			err = app.matrix.Send(email, mxChat)
-			// if err != nil {
-			// 	return err
-			// }
-		}
-		if address, ok := app.storage.GetEmailsKey(id); ok && address.Contact && emailEnabled {
-			err = app.email.send(email, address.Addr)
-			// if err != nil {
-			// 	return err
-			// }
-		}
-		// if err != nil {
-		// 	return err
-		// }
+		queued = 0
+		if tgChat, ok := app.storage.GetTelegramKey(id); ok && tgChat.Contact && telegramEnabled && via(ChannelTelegram) && app.wantsMessage(id, category, ChannelTelegram) {
+			queue(ChannelTelegram, id, "@"+tgChat.Username)
+		}
+		if dcChat, ok := app.storage.GetDiscordKey(id); ok && dcChat.Contact && discordEnabled && via(ChannelDiscord) && app.wantsMessage(id, category, ChannelDiscord) {
+			queue(ChannelDiscord, id, RenderDiscordUsername(dcChat))
+		}
+		if mxChat, ok := app.storage.GetMatrixKey(id); ok && mxChat.Contact && matrixEnabled && via(ChannelMatrix) && app.wantsMessage(id, category, ChannelMatrix) {
+			queue(ChannelMatrix, id, mxChat.UserID)
+		}
+		if pushUser, ok := app.storage.GetPushKey(id); ok && pushUser.Contact && pushEnabled && via(ChannelPush) && app.wantsMessage(id, category, ChannelPush) {
+			queue(ChannelPush, id, pushUser.Topic)
+		}
+		address, ok := app.storage.GetEmailsKey(id)
+		if !ok || !emailEnabled || !via(ChannelEmail) || !app.wantsMessage(id, category, ChannelEmail) {
+			continue
+		}
+		if !address.Undeliverable {
+			if address.Contact {
+				queue(ChannelEmail, id, address.Addr)
+			}
+			continue
+		}
+		// Email would've been sent if it hadn't bounced, so fall back to another linked channel.
+		if queued == 0 {
+			if channel, destination, ok := app.fallbackChannel(id, category); ok && via(channel) {
+				app.debug.Printf(lm.FallbackFromUndeliverable, address.Addr, channel)
+				queue(channel, id, destination)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fallbackChannel returns the first linked channel the user could be messaged through, regardless of their contact preference.
+func (app *appContext) fallbackChannel(jfID, category string) (channel, destination string, ok bool) {
+	if tgChat, ok := app.storage.GetTelegramKey(jfID); ok && telegramEnabled && app.wantsMessage(jfID, category, ChannelTelegram) {
+		return ChannelTelegram, "@" + tgChat.Username, true
+	}
+	if dcChat, ok := app.storage.GetDiscordKey(jfID); ok && discordEnabled && app.wantsMessage(jfID, category, ChannelDiscord) {
+		return ChannelDiscord, RenderDiscordUsername(dcChat), true
+	}
+	if mxChat, ok := app.storage.GetMatrixKey(jfID); ok && matrixEnabled && app.wantsMessage(jfID, category, ChannelMatrix) {
+		return ChannelMatrix, mxChat.UserID, true
+	}
+	if pushUser, ok := app.storage.GetPushKey(jfID); ok && pushEnabled && app.wantsMessage(jfID, category, ChannelPush) {
+		return ChannelPush, pushUser.Topic, true
+	}
+	return "", "", false
+}
+
+// adminRecipients returns the addresses/Jellyfin IDs admin notifications should be sent to:
+// The admin email address when Jellyfin login is disabled, or otherwise
+// the IDs of all users with admin access that have a contact method.
+func (app *appContext) adminRecipients() []string {
+	if !app.config.Section("ui").Key("jellyfin_login").MustBool(false) {
+		if addr := app.config.Section("ui").Key("email").String(); addr != "" {
+			return []string{addr}
+		}
+		return []string{}
+	}
+	users, err := app.jf.GetUsers(false)
+	if err != nil {
+		app.err.Printf(lm.FailedGetUsers, lm.Jellyfin, err)
+		return []string{}
+	}
+	adminOnly := app.config.Section("ui").Key("admin_only").MustBool(true)
+	recipients := []string{}
+	for _, user := range users {
+		emailStore, _ := app.storage.GetEmailsKey(user.ID)
+		if !(emailStore.Admin || (adminOnly && user.Policy.IsAdministrator)) {
+			continue
+		}
+		if app.getAddressOrName(user.ID) == "" {
+			continue
+		}
+		recipients = append(recipients, user.ID)
+	}
+	return recipients
+}
+
+// sendToAdmins sends the given message to each of adminRecipients(), calling onSend with the result of each.
+func (app *appContext) sendToAdmins(msg *Message, onSend func(recipient string, err error)) {
+	app.sendToAdminsVia(msg, nil, "", onSend)
+}
+
+// sendToAdminsVia is sendToAdmins, but only sends through the given channels (or any, if none are given), and skips the recipient "exclude".
+func (app *appContext) sendToAdminsVia(msg *Message, channels []string, exclude string, onSend func(recipient string, err error)) {
+	for _, recipient := range app.adminRecipients() {
+		if recipient == exclude {
+			continue
+		}
+		var err error
+		// Check whether recipient is an email address of Jellyfin ID
+		if strings.Contains(recipient, "@") {
+			if len(channels) != 0 && !slices.Contains(channels, ChannelEmail) {
+				continue
+			}
+			err = app.messageQueue.Enqueue(msg, ChannelEmail, "", recipient, recipient)
+		} else {
+			err = app.sendByIDVia(msg, MessageCategoryRequired, channels, recipient)
+		}
+		if onSend != nil {
+			onSend(recipient, err)
+		}
 	}
-	return
 }
 
 func (app *appContext) getAddressOrName(jfID string) string {
@@ -659,6 +1089,9 @@ func (app *appContext) getAddressOrName(jfID string) string {
 	if mxChat, ok := app.storage.GetMatrixKey(jfID); ok && mxChat.Contact && matrixEnabled {
 		return mxChat.UserID
 	}
+	if pushUser, ok := app.storage.GetPushKey(jfID); ok && pushUser.Contact && pushEnabled {
+		return pushUser.Topic
+	}
 	return ""
 }
 
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -489,3 +493,263 @@ func TestWelcome(t *testing.T) {
 		})
 	})
 }
+
+// constructAdopted(username, profile, label string, expiry, when time.Time, placeholders bool)
+func TestAdopted(t *testing.T) {
+	e := testDummyEmailerInit(t)
+	defer dbClose(e)
+	if db == nil {
+		t.Fatalf("db nil")
+	}
+	// Fix date/time format
+	datePattern = "%d/%m/%y"
+	timePattern = "%H:%M"
+	testContent(e, customContent["UserAdopted"], t, func(t *testing.T) {
+		username := shortuuid.New()
+		profile := shortuuid.New()
+		label := shortuuid.New()
+		expiry := time.Date(2025, 1, 2, 8, 37, 1, 1, time.UTC)
+		msg, err := e.constructAdopted(username, profile, label, expiry, time.Now(), false)
+		if err != nil {
+			t.Fatalf("failed construct: %+v", err)
+		}
+		for _, content := range []string{msg.Text, msg.HTML} {
+			if !strings.Contains(content, username) {
+				t.Fatalf("username not found in output: %s", content)
+			}
+			if !strings.Contains(content, profile) {
+				t.Fatalf("profile not found in output: %s", content)
+			}
+			if !strings.Contains(content, label) {
+				t.Fatalf("label not found in output: %s", content)
+			}
+			if !strings.Contains(content, "02/01/25") || !strings.Contains(content, "08:37") {
+				t.Fatalf("expiry not found in output: %s", content)
+			}
+		}
+	})
+}
+
+// constructAdminDigest(digest AdminDigest, placeholders bool)
+func TestAdminDigest(t *testing.T) {
+	e := testDummyEmailerInit(t)
+	defer dbClose(e)
+	if db == nil {
+		t.Fatalf("db nil")
+	}
+	testContent(e, customContent["AdminDigest"], t, func(t *testing.T) {
+		signup := shortuuid.New()
+		expiring := shortuuid.New()
+		deleted := shortuuid.New()
+		backup := shortuuid.New()
+		msg, err := e.constructAdminDigest(AdminDigest{
+			Start:            time.Now().AddDate(0, 0, -7),
+			End:              time.Now(),
+			Signups:          []string{signup},
+			InvitesExpired:   4,
+			Expiring:         []string{expiring},
+			Deleted:          []string{deleted},
+			FailedDeliveries: 3,
+			Backup:           backup,
+		}, false)
+		if err != nil {
+			t.Fatalf("failed construct: %+v", err)
+		}
+		for _, content := range []string{msg.Text, msg.HTML} {
+			for _, v := range []string{signup, expiring, deleted, backup, "3", "4"} {
+				if !strings.Contains(content, v) {
+					t.Fatalf("\"%s\" not found in output: %s", v, content)
+				}
+			}
+		}
+	})
+}
+
+// constructAdminEvent(event string, vars map[string]any, when time.Time, placeholders bool)
+func TestAdminEvents(t *testing.T) {
+	e := testDummyEmailerInit(t)
+	defer dbClose(e)
+	if db == nil {
+		t.Fatalf("db nil")
+	}
+	for event, info := range adminEvents {
+		testContent(e, customContent[info.Content], t, func(t *testing.T) {
+			vars := map[string]any{}
+			for _, v := range customContent[info.Content].Variables {
+				vars[v] = shortuuid.New()
+			}
+			delete(vars, "time")
+			msg, err := e.constructAdminEvent(event, vars, time.Now(), false)
+			if err != nil {
+				t.Fatalf("failed construct: %+v", err)
+			}
+			for _, content := range []string{msg.Text, msg.HTML} {
+				for v, value := range vars {
+					if !strings.Contains(content, value.(string)) {
+						t.Fatalf("%s not found in output: %s", v, content)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseHTTPMailHeaders(t *testing.T) {
+	headers := parseHTTPMailHeaders("Authorization: Bearer {token} | X-Custom:value; with semicolon|invalid| : empty")
+	want := [][2]string{{"Authorization", "Bearer {token}"}, {"X-Custom", "value; with semicolon"}}
+	if len(headers) != len(want) {
+		t.Fatalf("expected %d headers, got %d: %v", len(want), len(headers), headers)
+	}
+	for i := range want {
+		if headers[i] != want[i] {
+			t.Errorf("header %d: expected %v, got %v", i, want[i], headers[i])
+		}
+	}
+}
+
+func TestHTTPMailBody(t *testing.T) {
+	msg := &Message{
+		Subject: "Quotes \" and\nnewlines",
+		HTML:    "<p>{username}</p>",
+		Text:    "Hi {username}",
+	}
+	body := templateString(
+		`{"from": {from}, "to": [{to}], "subject": {subject}, "html": {html}, "text": {text}}`,
+		httpMailVars("Jellyfin", "jellyfin@example.com", "", msg, "user@example.com", true),
+	)
+	var parsed struct {
+		From    string   `json:"from"`
+		To      []string `json:"to"`
+		Subject string   `json:"subject"`
+		HTML    string   `json:"html"`
+		Text    string   `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		t.Fatalf("body isn't valid JSON: %v\n%s", err, body)
+	}
+	if parsed.From != "Jellyfin <jellyfin@example.com>" || len(parsed.To) != 1 || parsed.To[0] != "user@example.com" {
+		t.Errorf("wrong sender/recipient: %+v", parsed)
+	}
+	if parsed.Subject != msg.Subject || parsed.HTML != msg.HTML || parsed.Text != msg.Text {
+		t.Errorf("content changed by templating: %+v", parsed)
+	}
+}
+
+func TestHTTPMailerSend(t *testing.T) {
+	type request struct {
+		method, auth, custom, contentType string
+		body                              map[string]any
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{
+			method:      r.Method,
+			auth:        r.Header.Get("Authorization"),
+			custom:      r.Header.Get("X-Recipient"),
+			contentType: r.Header.Get("Content-Type"),
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+			t.Errorf("body isn't valid JSON: %v", err)
+		}
+		requests = append(requests, req)
+		if req.body["to"] == "bad@example.com" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	hm := &HTTPMailer{
+		url:     server.URL,
+		method:  http.MethodPut,
+		body:    `{"from": {from}, "to": {to}, "subject": {subject}, "html": {html}, "text": {text}}`,
+		token:   "secret",
+		headers: parseHTTPMailHeaders("Authorization: Bearer {token}|X-Recipient: {to}"),
+		client:  server.Client(),
+	}
+	msg := &Message{Subject: "A \"subject\"", HTML: "<p>Hi</p>", Text: "Hi"}
+
+	if err := hm.Send("Jellyfin", "jellyfin@example.com", msg, "user@example.com"); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	req := requests[0]
+	if req.method != http.MethodPut {
+		t.Errorf("expected method %s, got %s", http.MethodPut, req.method)
+	}
+	if req.contentType != "application/json" {
+		t.Errorf("expected JSON content type, got \"%s\"", req.contentType)
+	}
+	if req.auth != "Bearer secret" || req.custom != "user@example.com" {
+		t.Errorf("headers not templated: Authorization \"%s\", X-Recipient \"%s\"", req.auth, req.custom)
+	}
+	want := map[string]string{
+		"from":    "Jellyfin <jellyfin@example.com>",
+		"to":      "user@example.com",
+		"subject": msg.Subject,
+		"html":    msg.HTML,
+		"text":    msg.Text,
+	}
+	for k, v := range want {
+		if req.body[k] != v {
+			t.Errorf("body \"%s\": expected \"%s\", got \"%v\"", k, v, req.body[k])
+		}
+	}
+
+	// Each recipient gets their own request, and one failing shouldn't stop the others.
+	requests = nil
+	err := hm.Send("Jellyfin", "jellyfin@example.com", msg, "bad@example.com", "user@example.com")
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected error with status code 400, got %v", err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected 2 requests, got %d", len(requests))
+	}
+}
+
+func TestSendmailSend(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args")
+	stdinPath := filepath.Join(dir, "stdin")
+	script := filepath.Join(dir, "sendmail")
+	err := os.WriteFile(script, []byte(fmt.Sprintf("#!/bin/sh\nprintf '%%s\\n' \"$@\" > %s\ncat > %s\n", argsPath, stdinPath)), 0700)
+	if err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	sm := &Sendmail{path: script}
+	msg := &Message{Subject: "Test subject", HTML: "<p>Hi</p>", Text: "Hi\n.\nstill here"}
+	if err := sm.Send("Jellyfin", "jellyfin@example.com", msg, "user@example.com"); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	args, err := os.ReadFile(argsPath)
+	if err != nil {
+		t.Fatalf("failed to read args: %v", err)
+	}
+	if want := "-i\n-f\njellyfin@example.com\n--\nuser@example.com\n"; string(args) != want {
+		t.Errorf("expected args %q, got %q", want, string(args))
+	}
+	stdin, err := os.ReadFile(stdinPath)
+	if err != nil {
+		t.Fatalf("failed to read stdin: %v", err)
+	}
+	for _, want := range []string{"Subject: Test subject", "To: <user@example.com>", "jellyfin@example.com", "still here"} {
+		if !strings.Contains(string(stdin), want) {
+			t.Errorf("message missing \"%s\":\n%s", want, stdin)
+		}
+	}
+
+	// Output of a failing binary should end up in the error.
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho 'no such user' >&2\nexit 1\n"), 0700); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	err = sm.Send("Jellyfin", "jellyfin@example.com", msg, "user@example.com")
+	if err == nil || !strings.Contains(err.Error(), "no such user") {
+		t.Errorf("expected error containing script output, got %v", err)
+	}
+}
@@ -40,6 +40,7 @@ func (app *appContext) clearDiscord() {
 			// Remove role in case their account was deleted oustide of jfa-go
 			app.discord.RemoveRole(discordUser.MethodID().(string))
 			app.storage.DeleteDiscordKey(discordUser.JellyfinID)
+			app.syncDiscordMember(discordUser.ID, nil)
 		default:
 			if removeRoleOnDisable && user.Policy.IsDisabled {
 				app.discord.RemoveRole(discordUser.MethodID().(string))
@@ -65,6 +66,21 @@ func (app *appContext) clearMatrix() {
 	}
 }
 
+// clearPush does the same as clearEmails, but for push notification users.
+func (app *appContext) clearPush() {
+	app.debug.Println(lm.HousekeepingPush)
+	for _, pushUser := range app.storage.GetPush() {
+		_, err := app.jf.UserByID(pushUser.JellyfinID, false)
+		// Make sure the user doesn't exist, and no other error has occured
+		switch err.(type) {
+		case mediabrowser.ErrUserNotFound:
+			app.storage.DeletePushKey(pushUser.JellyfinID)
+		default:
+			continue
+		}
+	}
+}
+
 // clearTelegram does the same as clearEmails, but for Telegram Users.
 func (app *appContext) clearTelegram() {
 	app.debug.Println(lm.HousekeepingTelegram)
@@ -129,7 +145,12 @@ func newHousekeepingDaemon(interval time.Duration, app *appContext) *GenericDaem
 			app.debug.Println(lm.HousekeepingInvites)
 			app.checkInvites()
 		},
+		func(app *appContext) {
+			app.debug.Println(lm.HousekeepingRenewals)
+			app.checkRenewalCodes()
+		},
 		func(app *appContext) { app.clearActivities() },
+		func(app *appContext) { app.clearMessageLog() },
 	)
 
 	d.Name("Housekeeping")
@@ -138,12 +159,17 @@ func newHousekeepingDaemon(interval time.Duration, app *appContext) *GenericDaem
 	clearDiscord := discordEnabled && (app.config.Section("discord").Key("require_unique").MustBool(false) || app.config.Section("discord").Key("disable_enable_role").MustBool(false))
 	clearTelegram := telegramEnabled && (app.config.Section("telegram").Key("require_unique").MustBool(false))
 	clearMatrix := matrixEnabled && (app.config.Section("matrix").Key("require_unique").MustBool(false))
+	clearPush := pushEnabled && (app.config.Section("push").Key("require_unique").MustBool(false))
 	clearPWR := app.config.Section("captcha").Key("enabled").MustBool(false) && !app.config.Section("captcha").Key("recaptcha").MustBool(false)
 
-	if clearEmail || clearDiscord || clearTelegram || clearMatrix {
+	if clearEmail || clearDiscord || clearTelegram || clearMatrix || clearPush {
 		d.appendJobs(func(app *appContext) { app.InvalidateJellyfinCache() })
 	}
 
+	if app.config.Section("payment_webhook").Key("enabled").MustBool(false) {
+		d.appendJobs(func(app *appContext) { app.clearPaymentEvents() })
+	}
+
 	if clearEmail {
 		d.appendJobs(func(app *appContext) { app.clearEmails() })
 	}
@@ -156,6 +182,9 @@ func newHousekeepingDaemon(interval time.Duration, app *appContext) *GenericDaem
 	if clearMatrix {
 		d.appendJobs(func(app *appContext) { app.clearMatrix() })
 	}
+	if clearPush {
+		d.appendJobs(func(app *appContext) { app.clearPush() })
+	}
 	if clearPWR {
 		d.appendJobs(func(app *appContext) { app.clearPWRCaptchas() })
 	}
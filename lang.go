@@ -113,6 +113,9 @@ type emailLang struct {
 	EmailConfirmation  langSection `json:"emailConfirmation"`
 	UserExpired        langSection `json:"userExpired"`
 	ExpiryReminder     langSection `json:"expiryReminder"`
+	UserAdopted        langSection `json:"userAdopted"`
+	AdminDigest        langSection `json:"adminDigest"`
+	AdminEvents        langSection `json:"adminEvents"`
 }
 
 type setupLangs map[string]setupLang
@@ -14,6 +14,7 @@ const (
 	Discord    = "Discord"
 	Telegram   = "Telegram"
 	Matrix     = "Matrix"
+	Push       = "Push"
 	Email      = "Email"
 
 	// main.go
@@ -72,6 +73,8 @@ const (
 	FailedInitMatrix    = "Failed to initialize Matrix daemon: %v"
 	InitingMatrixCrypto = "Initializing Matrix encryption store"
 	InitMatrixCrypto    = "Initialized Matrix encryption store"
+	InitPush            = "Initialized Push notifier"
+	FailedInitPush      = "Failed to initialize Push notifier: %v"
 
 	InitRouter = "Initializing router"
 	LoadRoutes = "Loading Routes"
@@ -168,6 +171,53 @@ const (
 	DeleteOldReferral         = "Deleting old referral \"%s\""
 	RenewOldReferral          = "Renewing old referral \"%s\""
 
+	// calendar.go
+	InvalidCalendarToken = "Invalid calendar token from %s"
+
+	// unsubscribe.go
+	InvalidUnsubscribeToken = "Invalid unsubscribe token from %s"
+	Unsubscribed            = "User \"%s\" unsubscribed from %s emails"
+
+	// api-payments.go
+	FailedVerifyPayment      = "Failed to verify payment event from %s: %v"
+	FailedParsePayment       = "Failed to parse payment event: %v"
+	IgnorePaymentEvent       = "Ignoring payment event \"%s\" of type \"%s\""
+	DuplicatePaymentEvent    = "Ignoring already-processed payment event \"%s\""
+	FailedMatchPayment       = "Failed to match payment event \"%s\" (customer \"%s\", email \"%s\") to a user"
+	PaymentNoExpiry          = "Payment event \"%s\" for user \"%s\" recorded, but user has no expiry"
+	FailedApplyPayment       = "Failed to apply payment event \"%s\" to user \"%s\": %s"
+	ApplyPayment             = "Applied payment event \"%s\" to user \"%s\", new expiry %v"
+	EnabledPaidUser          = "Re-enabled expired user \"%s\" after payment event \"%s\""
+	FailedClearPaymentEvents = "Failed to clear old payment events: %v"
+
+	// bounces.go
+	FailedVerifyBounce        = "Failed to verify bounce notification from %s: %v"
+	FailedParseBounce         = "Failed to parse bounce notification: %v"
+	IgnoreBounce              = "Ignoring temporary failure or complaint for \"%s\": %s"
+	FailedMatchBounce         = "Failed to match bounced address \"%s\" to a user"
+	MarkEmailUndeliverable    = "Marked email address \"%s\" of user \"%s\" as undeliverable: %s"
+	FallbackFromUndeliverable = "Address \"%s\" is undeliverable, falling back to %s"
+
+	// api-renewals.go
+	GenerateRenewalCodes = "Generating %d new renewal code(s)"
+	DeleteRenewalCode    = "Deleting renewal code \"%s\""
+	DeleteOldRenewalCode = "Deleting old renewal code \"%s\""
+	RedeemRenewalCode    = "User \"%s\" redeemed renewal code \"%s\""
+	FailedRedeemRenewal  = "Failed to redeem renewal code \"%s\" for user \"%s\": %v"
+	InvalidRenewalCode   = "Invalid renewal code \"%s\""
+	RenewalCodeNoExpiry  = "user has no expiry"
+
+	// messagequeue.go
+	FailedQueueMessage      = "Failed to queue %s message for \"%s\": %v"
+	SentQueuedMessage       = "Sent queued %s message to \"%s\""
+	RetryQueuedMessage      = "Failed to send %s message to \"%s\" (attempt %d), retrying at %v: %v"
+	FailedSendQueuedMessage = "Giving up on %s message to \"%s\" after %d attempt(s): %v"
+	DropQueuedMessage       = "Dropping %s message to \"%s\": Recipient no longer linked"
+	RequeueMessage          = "Re-queued dead %s message to \"%s\""
+
+	// deliverylog.go
+	FailedClearMessageLog = "Failed to clear old message delivery log entries: %v"
+
 	// api-users.go
 	CreateUser                 = "Created %s user \"%s\""
 	FailedCreateUser           = "Failed to create new %s user \"%s\": %v"
@@ -193,6 +243,8 @@ const (
 	FoundPreviousExpiryLog  = "Found most recent previous expiry in activity log @ %v"
 	ExpiryWouldBeInPast     = "Expiry would've been in the past, using current time base"
 	PreviousExpiryNotExpiry = "Last user disable was not an expiry, using current time base"
+	RestoreDowngradedUser   = "Restoring previous policy for downgraded user \"%s\""
+	FailedRestoreDowngrade  = "Failed to restore previous policy for downgraded user \"%s\": %v"
 
 	UserEmailAdjusted = "Email for user \"%s\" adjusted"
 	UserAdminAdjusted = "Admin state for user \"%s\" set to %t"
@@ -234,12 +286,22 @@ const (
 	FailedGetDiscordGuildMembers     = "Failed to get " + Discord + " guild members: %v"
 	FailedGetDiscordGuild            = "Failed to get " + Discord + " guild: %v"
 	FailedGetDiscordRoles            = "Failed to get " + Discord + " roles: %v"
+	FailedGetDiscordGuildMember      = "Failed to get " + Discord + " guild member \"%s\": %v"
+	UnknownDiscordRole               = "Unknown " + Discord + " role \"%s\" in role mapping"
+	ReconciledDiscordRoles           = "Reconciled " + Discord + " roles: %d added, %d removed"
+	DiscordUserDeparted              = "User \"%s\"'s " + Discord + " account \"%s\" %s the server, action: %s"
+	DiscordUserRejoined              = "User \"%s\"'s " + Discord + " account \"%s\" rejoined the server, cancelled grace period"
+	DisabledDepartedDiscordUser      = "Disabled user \"%s\" after " + Discord + " departure grace period"
 	FailedCreateDiscordInviteChannel = "Failed to create " + Discord + " invite channel: %v"
 	InviteChannelEmpty               = "no invite channel set in settings"
 	FailedGetDiscordChannels         = "Failed to get " + Discord + " channel(s): %v"
 	FailedGetDiscordChannel          = "Failed to get " + Discord + " channel \"%s\": %v"
 	MonitorAllDiscordChannels        = "Will monitor all " + Discord + " channels"
 	FailedCreateDiscordDMChannel     = "Failed to create " + Discord + " private DM channel with \"%s\": %v"
+	VerifiedDiscordOAuth             = "Verified " + Discord + " user \"%s\" through OAuth2 with PIN \"%s\""
+	FailedVerifyDiscordOAuth         = "Failed to verify " + Discord + " user through OAuth2: %v"
+	InvalidDiscordOAuthState         = "state doesn't match this browser, or has expired"
+	NotInDiscordGuild                = "not a member of the " + Discord + " server"
 	RegisterDiscordChoice            = "Registered " + Discord + " %s choice \"%s\""
 	FailedRegisterDiscordChoices     = "Failed to register " + Discord + " %s choices: %v"
 	FailedDeregDiscordChoice         = "Failed to deregister " + Discord + " %s choice \"%s\": %v"
@@ -255,24 +317,42 @@ const (
 
 	FailedGenerateDiscordInvite = "Failed to generate " + Discord + " invite: %v"
 
+	// telegram-membership.go
+	FailedGetTelegramChatMember = "Failed to check " + Telegram + " user \"%s\" is in the required chat: %v"
+	NotInRequiredChat           = "not a member of the required " + Telegram + " chat"
+	TelegramUserLeftChat        = Telegram + " user \"%s\" left the required chat, action: %s"
+	TelegramUserRejoined        = Telegram + " user \"%s\" rejoined the required chat"
+
+	// botadmin.go
+	BotAdminCommand       = "Running %s admin command from \"%s\": %s"
+	FailedBotAdminCommand = "Failed to run %s admin command \"%s\" from \"%s\": %s"
+
 	// email.go
 	SMTP                  = "SMTP"
 	Mailgun               = "Mailgun"
+	Sendmail              = "sendmail"
+	HTTPMail              = "HTTP"
 	FailedInitMailer      = "Failed to initalize %s mailer: %v"
 	FailedGeneratePWRLink = "Failed to generate PWR link: %v"
 	InvalidFromAddress    = "invalid from address: \"%s\""
+	FailedInitDKIM        = "Failed to load DKIM key, mail will be sent unsigned: %v"
+	InitDKIM              = "Signing mail with DKIM for domain \"%s\", selector \"%s\""
 
 	// housekeeping-d.go
-	hk                   = "Housekeeping: "
-	hkcu                 = hk + "cleaning up "
-	HousekeepingEmail    = hkcu + Email + " addresses"
-	HousekeepingDiscord  = hkcu + Discord + " IDs"
-	HousekeepingTelegram = hkcu + Telegram + " IDs"
-	HousekeepingMatrix   = hkcu + Matrix + " IDs"
-	HousekeepingCaptcha  = hkcu + "PWR Captchas"
-	HousekeepingActivity = hkcu + "Activity log"
-	HousekeepingInvites  = hkcu + "Invites"
-	ActivityLogTxnTooBig = hk + "Activity log delete transaction was too big, going one-by-one"
+	hk                     = "Housekeeping: "
+	hkcu                   = hk + "cleaning up "
+	HousekeepingEmail      = hkcu + Email + " addresses"
+	HousekeepingDiscord    = hkcu + Discord + " IDs"
+	HousekeepingTelegram   = hkcu + Telegram + " IDs"
+	HousekeepingMatrix     = hkcu + Matrix + " IDs"
+	HousekeepingPush       = hkcu + Push + " topics"
+	HousekeepingCaptcha    = hkcu + "PWR Captchas"
+	HousekeepingActivity   = hkcu + "Activity log"
+	HousekeepingInvites    = hkcu + "Invites"
+	HousekeepingRenewals   = hkcu + "Renewal codes"
+	HousekeepingPayments   = hkcu + "Payment events"
+	HousekeepingMessageLog = hkcu + "Message delivery log"
+	ActivityLogTxnTooBig   = hk + "Activity log delete transaction was too big, going one-by-one"
 
 	// matrix*.go
 	FailedSyncMatrix             = "Failed to sync " + Matrix + " daemon: %v"
@@ -281,6 +361,11 @@ const (
 	MatrixOLMTraceLog            = "Matrix/OLM [TRACE]:"
 	FailedDecryptMatrixMessage   = "Failed to decrypt " + Matrix + " E2EE'd message: %v"
 	FailedEnableMatrixEncryption = "Failed to enable encryption in " + Matrix + " room \"%s\": %v"
+	FailedResolveMatrixRoom      = "Failed to resolve " + Matrix + " room \"%s\": %v"
+	InvitedMatrixRoom            = "Invited " + Matrix + " user \"%s\" to room \"%s\""
+	FailedInviteMatrixRoom       = "Failed to invite " + Matrix + " user \"%s\" to room \"%s\": %v"
+	RemovedMatrixRoom            = "Removed " + Matrix + " user \"%s\" from room \"%s\""
+	FailedRemoveMatrixRoom       = "Failed to remove " + Matrix + " user \"%s\" from room \"%s\": %v"
 
 	// NOTE: "migrations.go" is the one file where log messages are not part of logmessages/logmessages.go.
 
@@ -320,8 +405,18 @@ const (
 	DeleteExpiryForOldUser           = "Deleting expiry for old user \"%s\""
 	DeleteExpiredUser                = "Deleting expired user \"%s\""
 	DisableExpiredUser               = "Disabling expired user \"%s\""
+	DowngradeExpiredUser             = "Downgrading expired user \"%s\" to profile \"%s\""
 	FailedDeleteOrDisableExpiredUser = "Failed to delete/disable expired user \"%s\": %v"
 
+	// adopt.go
+	CheckUnknownUsers     = "Checking for users created outside of jfa-go"
+	MarkExistingUsers     = "Marking %d existing users as known, they won't be adopted"
+	AdoptUser             = "Adopting user \"%s\""
+	FailedGetAdoptionData = "Failed to check records for adoption: %v"
+
+	// digest.go
+	SendAdminDigest = "Sending admin digest for %s - %s"
+
 	// views.go
 	FailedServerPush      = "Failed to use HTTP/2 Server Push: %v"
 	IgnoreBotPWR          = "Ignore PWR magic link visit from bot"
@@ -377,6 +472,18 @@ const (
 	FailedSendCreationAdmin      = "Failed to send creation notification for \"%s\" to \"%s\": %v"
 	SentCreationAdmin            = "Sent creation notification for \"%s\" to \"%s\""
 
+	FailedConstructAdoptionAdmin = "Failed to construct adoption notification for \"%s\": %v"
+	FailedSendAdoptionAdmin      = "Failed to send adoption notification for \"%s\" to \"%s\": %v"
+	SentAdoptionAdmin            = "Sent adoption notification for \"%s\" to \"%s\""
+
+	FailedConstructDigestAdmin = "Failed to construct admin digest: %v"
+	FailedSendDigestAdmin      = "Failed to send admin digest to \"%s\": %v"
+	SentDigestAdmin            = "Sent admin digest to \"%s\""
+
+	FailedConstructAdminEvent = "Failed to construct \"%s\" admin notification: %v"
+	FailedSendAdminEvent      = "Failed to send \"%s\" admin notification to \"%s\": %v"
+	SentAdminEvent            = "Sent \"%s\" admin notification to \"%s\""
+
 	FailedConstructInviteMessage = "Failed to construct invite message for \"%s\": %v"
 	FailedSendInviteMessage      = "Failed to send invite message for \"%s\" to \"%s\": %v"
 	SentInviteMessage            = "Sent invite message for \"%s\" to \"%s\""
@@ -417,4 +524,10 @@ const (
 	FailedConstructAnnouncementMessage = "Failed to construct announcement message for \"%s\": %v"
 	FailedSendAnnouncementMessage      = "Failed to send announcement message for \"%s\" to \"%s\": %v"
 	SentAnnouncementMessage            = "Sent announcement message for \"%s\" to \"%s\""
+
+	// announcements.go
+	ScheduleAnnouncement        = "Scheduled announcement \"%s\" for %v (repeats: \"%s\")"
+	SendScheduledAnnouncement   = "Sending scheduled announcement \"%s\" (\"%s\")"
+	CancelScheduledAnnouncement = "Cancelled scheduled announcement \"%s\""
+	InvalidRecurrence           = "Invalid announcement recurrence \"%s\""
 )
@@ -129,6 +129,9 @@ type appContext struct {
 	telegram                                         *TelegramDaemon
 	discord                                          *DiscordDaemon
 	matrix                                           *MatrixDaemon
+	push                                             *PushDaemon
+	messageQueue                                     *MessageQueue
+	unsubscribeSecret                                []byte
 	housekeepingDaemon, userDaemon, jellyseerrDaemon *GenericDaemon
 	contactMethods                                   []ContactMethodLinker
 	LoggerSet
@@ -144,7 +147,13 @@ type appContext struct {
 	pwrCaptchas          map[string]Captcha
 	ConfirmationKeys     map[string]map[string]ConfirmationKey // Map of invite code to jwt to request
 	confirmationKeysLock sync.Mutex
+	paymentEventsLock    sync.Mutex
+	renewalCodesLock     sync.Mutex
 	userCache            *UserCache
+
+	// When admins were last notified of a failed login, see notifyAdminsOfFailedLogin.
+	failedLoginNotified   time.Time
+	failedLoginNotifyLock sync.Mutex
 }
 
 func generateSecret(length int) (string, error) {
@@ -409,6 +418,7 @@ func start(asDaemon, firstCall bool) {
 		}
 		app.info.Printf(lm.ConnectDB, dbPath)
 		defer app.storage.Close()
+		app.unsubscribeSecret = app.storage.loadUnsubscribeSecret()
 
 		// copy it to app.patchedConfig, and patch in settings from app.config, and language stuff.
 		app.PatchConfigBase()
@@ -518,6 +528,8 @@ func start(asDaemon, firstCall bool) {
 			os.Exit(0)
 		}
 
+		app.messageQueue = newMessageQueue(app)
+
 		app.housekeepingDaemon = newHousekeepingDaemon(time.Duration(60*time.Second), app)
 		go app.housekeepingDaemon.run()
 		defer app.housekeepingDaemon.Shutdown()
@@ -566,6 +578,16 @@ func start(asDaemon, firstCall bool) {
 				go app.discord.Run()
 				defer app.discord.Shutdown()
 				app.contactMethods = append(app.contactMethods, app.discord)
+				if app.discordRolesMapped() {
+					roleDaemon := newDiscordRoleDaemon(time.Duration(app.config.Section("discord").Key("role_sync_interval").MustInt(60))*time.Minute, app)
+					go roleDaemon.run()
+					defer roleDaemon.Shutdown()
+				}
+				if app.discordDepartureAction() == DiscordDepartureGrace {
+					departureDaemon := newDiscordDepartureDaemon(15*time.Minute, app)
+					go departureDaemon.run()
+					defer departureDaemon.Shutdown()
+				}
 			}
 		}
 		if telegramEnabled {
@@ -578,6 +600,11 @@ func start(asDaemon, firstCall bool) {
 				go app.telegram.run()
 				defer app.telegram.Shutdown()
 				app.contactMethods = append(app.contactMethods, app.telegram)
+				if app.telegramChatRequired() && app.config.Section("telegram").Key("left_chat_action").MustString(TelegramLeftNotify) != TelegramLeftNone {
+					membershipDaemon := newTelegramMembershipDaemon(time.Duration(app.config.Section("telegram").Key("chat_check_interval").MustInt(60))*time.Minute, app)
+					go membershipDaemon.run()
+					defer membershipDaemon.Shutdown()
+				}
 			}
 		}
 		if matrixEnabled {
@@ -592,6 +619,32 @@ func start(asDaemon, firstCall bool) {
 				app.contactMethods = append(app.contactMethods, app.matrix)
 			}
 		}
+		if pushEnabled {
+			app.push, err = newPushDaemon(app)
+			if err != nil {
+				app.err.Printf(lm.FailedInitPush, err)
+				pushEnabled = false
+			} else {
+				app.debug.Println(lm.InitPush)
+				app.contactMethods = append(app.contactMethods, app.push)
+			}
+		}
+
+		// Messages may have been queued already, but can only be sent now the contact methods are ready.
+		app.messageQueue.run()
+		defer app.messageQueue.Shutdown()
+
+		if messagesEnabled {
+			announcementDaemon := newAnnouncementDaemon(time.Duration(60*time.Second), app)
+			go announcementDaemon.run()
+			defer announcementDaemon.Shutdown()
+
+			if app.config.Section("admin_digest").Key("enabled").MustBool(false) {
+				digestDaemon := newDigestDaemon(time.Duration(5*time.Minute), app)
+				go digestDaemon.run()
+				defer digestDaemon.Shutdown()
+			}
+		}
 
 		// Non-consequential if we don't need it
 		app.webhooks = NewWebhookSender(
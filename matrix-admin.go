@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+	"maunium.net/go/mautrix/event"
+)
+
+const (
+	// Default and maximum number of entries listed by !activity.
+	MATRIX_ACTIVITY_COUNT     = 10
+	MATRIX_ACTIVITY_MAX_COUNT = 50
+)
+
+// matrixAdminCommand handles an admin command sent to the Matrix bot, where sects is the split message.
+type matrixAdminCommand func(d *MatrixDaemon, evt *event.Event, sects []string, lang, adminID string)
+
+// matrixAdminCommands maps admin commands to their handlers.
+var matrixAdminCommands = map[string]matrixAdminCommand{
+	"!help":     (*MatrixDaemon).commandAdminHelp,
+	"!invite":   (*MatrixDaemon).commandInvite,
+	"!extend":   (*MatrixDaemon).commandExtend,
+	"!user":     (*MatrixDaemon).commandUser,
+	"!activity": (*MatrixDaemon).commandActivity,
+}
+
+// htmlTemplate fills in the given string as HTML. The string and values are escaped, except values given in "raw".
+func htmlTemplate(text string, vals, raw tmpl) string {
+	escaped := tmpl{}
+	for k, v := range vals {
+		escaped[k] = html.EscapeString(fmt.Sprint(v))
+	}
+	for k, v := range raw {
+		escaped[k] = v
+	}
+	return strings.ReplaceAll(templateString(html.EscapeString(text), escaped), "\n", "<br>")
+}
+
+// replyHTML sends a message with the given plain text and HTML bodies to the room the event was sent in.
+func (d *MatrixDaemon) replyHTML(evt *event.Event, text, formatted string) {
+	err := d.sendToRoom(&event.MessageEventContent{
+		MsgType:       event.MsgText,
+		Body:          text,
+		Format:        event.FormatHTML,
+		FormattedBody: formatted,
+	}, evt.RoomID)
+	if err != nil {
+		d.app.err.Printf(lm.FailedReply, lm.Matrix, evt.Sender, err)
+	}
+}
+
+// replyString replies with the named string.
+func (d *MatrixDaemon) replyString(evt *event.Event, lang, key string, vals tmpl) {
+	text := d.app.storage.lang.Telegram[lang].Strings.get(key)
+	d.replyHTML(evt, templateString(text, vals), htmlTemplate(text, vals, nil))
+}
+
+// senderAdmin returns the Jellyfin ID of the jfa-go admin linked to the sender of the given event.
+func (d *MatrixDaemon) senderAdmin(evt *event.Event) (string, bool) {
+	users := []MatrixUser{}
+	err := d.app.storage.db.Find(&users, badgerhold.Where("UserID").Eq(string(evt.Sender)))
+	if err != nil {
+		return "", false
+	}
+	for _, user := range users {
+		if d.app.canAccessAdminPageByID(user.JellyfinID) {
+			return user.JellyfinID, true
+		}
+	}
+	return "", false
+}
+
+// commandAdmin runs the given admin command if the sender is linked to a jfa-go admin, and records it in the activity log.
+func (d *MatrixDaemon) commandAdmin(evt *event.Event, sects []string, lang string, cmd matrixAdminCommand) {
+	adminID, ok := d.senderAdmin(evt)
+	if !ok {
+		d.app.info.Printf(lm.FailedBotAdminCommand, lm.Matrix, sects[0], evt.Sender, fmt.Sprintf(lm.NonAdminUser, evt.Sender))
+		d.replyString(evt, lang, "noPermission", nil)
+		return
+	}
+	d.app.info.Printf(lm.BotAdminCommand, lm.Matrix, evt.Sender, sects[0])
+	d.app.recordAdminCommand(adminID, strings.Join(sects, " "))
+	cmd(d, evt, sects, lang, adminID)
+}
+
+func (d *MatrixDaemon) commandAdminHelp(evt *event.Event, sects []string, lang, adminID string) {
+	d.replyString(evt, lang, "matrixAdminHelp", nil)
+}
+
+// commandInvite creates an invite, with options given as "expiry=<duration>", "profile=<name>" and "label=<user label>".
+func (d *MatrixDaemon) commandInvite(evt *event.Event, sects []string, lang, adminID string) {
+	opts, errKey, errVals := d.app.parseBotInviteOptions(sects[1:])
+	if errKey != "" {
+		d.replyString(evt, lang, errKey, errVals)
+		return
+	}
+	invite := d.app.createBotInvite(opts, fmt.Sprintf("%s: %s", lm.Matrix, evt.Sender), adminID)
+	link := ExternalURI(nil) + PAGES.Form + "/" + invite.Code
+	vals := tmpl{"expiry": formatDatetime(invite.ValidTill), "profile": invite.Profile}
+	text := d.app.storage.lang.Telegram[lang].Strings.get("createdInvite")
+	d.replyHTML(
+		evt,
+		templateString(text, tmpl{"expiry": vals["expiry"], "profile": vals["profile"], "link": link}),
+		htmlTemplate(text, vals, tmpl{"link": fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), html.EscapeString(link))}),
+	)
+}
+
+// commandExtend extends a user's expiry: "!extend <user> <duration>".
+func (d *MatrixDaemon) commandExtend(evt *event.Event, sects []string, lang, adminID string) {
+	if len(sects) < 3 {
+		d.replyString(evt, lang, "matrixAdminHelp", nil)
+		return
+	}
+	user, err := d.app.jf.UserByName(sects[1], false)
+	if err != nil {
+		d.replyString(evt, lang, "userNotFound", tmpl{"user": sects[1]})
+		return
+	}
+	months, days, hours, minutes, ok := parseDurationString(strings.Join(sects[2:], ""))
+	if !ok {
+		d.replyString(evt, lang, "invalidDuration", tmpl{"duration": strings.Join(sects[2:], " ")})
+		return
+	}
+	expiry := d.app.ExtendUserExpiry(user.ID, ExpiryExtension{Months: months, Days: days, Hours: hours, Minutes: minutes})
+	d.app.InvalidateUserCaches()
+	d.replyString(evt, lang, "extendedExpiry", tmpl{"user": user.Name, "expiry": formatDatetime(expiry.Expiry)})
+}
+
+// commandUser shows a summary of a user's account: "!user <name>".
+func (d *MatrixDaemon) commandUser(evt *event.Event, sects []string, lang, adminID string) {
+	if len(sects) < 2 {
+		d.replyString(evt, lang, "matrixAdminHelp", nil)
+		return
+	}
+	jfUser, err := d.app.jf.UserByName(sects[1], false)
+	if err != nil {
+		d.replyString(evt, lang, "userNotFound", tmpl{"user": sects[1]})
+		return
+	}
+	lines := d.app.botUserSummary(jfUser, d.app.storage.lang.Telegram[lang].Strings)
+	formatted := "<strong>" + html.EscapeString(jfUser.Name) + "</strong><ul>"
+	for _, line := range lines {
+		formatted += "<li>" + html.EscapeString(line) + "</li>"
+	}
+	formatted += "</ul>"
+	d.replyHTML(evt, jfUser.Name+"\n"+strings.Join(lines, "\n"), formatted)
+}
+
+// commandActivity lists the most recent entries in the activity log: "!activity [count]".
+func (d *MatrixDaemon) commandActivity(evt *event.Event, sects []string, lang, adminID string) {
+	count := MATRIX_ACTIVITY_COUNT
+	if len(sects) > 1 {
+		if n, err := strconv.Atoi(sects[1]); err == nil && n > 0 {
+			count = min(n, MATRIX_ACTIVITY_MAX_COUNT)
+		}
+	}
+	acts := []Activity{}
+	err := d.app.storage.db.Find(&acts, (&badgerhold.Query{}).SortBy("Time").Reverse().Limit(count))
+	if err != nil {
+		d.app.err.Printf(lm.FailedDBReadActivities, err)
+		d.replyString(evt, lang, "commandFailed", nil)
+		return
+	}
+	if len(acts) == 0 {
+		d.replyString(evt, lang, "noActivity", nil)
+		return
+	}
+	strs := d.app.storage.lang.Telegram[lang].Strings
+	header := strs.template("recentActivity", tmpl{"n": len(acts)})
+	text := header
+	formatted := "<strong>" + html.EscapeString(header) + "</strong><ul>"
+	for _, act := range acts {
+		line := formatDatetime(act.Time) + ": " + d.app.botActivityDescription(act, strs)
+		text += "\n- " + line
+		formatted += "<li>" + html.EscapeString(line) + "</li>"
+	}
+	formatted += "</ul>"
+	d.replyHTML(evt, text, formatted)
+}
+
+// handleAdminCommand runs the admin command in the given message if there is one, returning whether there was.
+func (d *MatrixDaemon) handleAdminCommand(evt *event.Event, sects []string, lang string) bool {
+	cmd, ok := matrixAdminCommands[sects[0]]
+	if !ok {
+		return false
+	}
+	d.commandAdmin(evt, sects, lang, cmd)
+	return true
+}
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestHTMLTemplate(t *testing.T) {
+	got := htmlTemplate(
+		"Created <{profile}>:\n{link}",
+		tmpl{"profile": "a&b"},
+		tmpl{"link": `<a href="x">x</a>`},
+	)
+	want := `Created &lt;a&amp;b&gt;:<br><a href="x">x</a>`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/id"
+)
+
+// matrixRooms returns the rooms and spaces users with the given profile are invited to, as room IDs or aliases.
+func (app *appContext) matrixRooms(profile string) []string {
+	return parseRoleMapping(app.config.Section("matrix").Key("profile_rooms").StringsWithShadows("|"))[profile]
+}
+
+// resolveRoom returns the ID of the given room, which can be given as an ID or an alias.
+func (d *MatrixDaemon) resolveRoom(room string) (id.RoomID, error) {
+	if !strings.HasPrefix(room, "#") {
+		return id.RoomID(room), nil
+	}
+	resp, err := d.bot.ResolveAlias(context.TODO(), id.RoomAlias(room))
+	if err != nil {
+		return "", err
+	}
+	return resp.RoomID, nil
+}
+
+// linkedMatrixRooms returns the given user's linked Matrix account, and the rooms mapped to their profile.
+func (app *appContext) linkedMatrixRooms(jfID string) (mxUser MatrixUser, rooms []string, ok bool) {
+	if app.matrix == nil {
+		return
+	}
+	mxUser, ok = app.storage.GetMatrixKey(jfID)
+	if !ok || mxUser.UserID == "" {
+		ok = false
+		return
+	}
+	emailStore, _ := app.storage.GetEmailsKey(jfID)
+	rooms = app.matrixRooms(emailStore.Profile)
+	ok = len(rooms) != 0
+	return
+}
+
+// inviteToMatrixRooms invites the given user's linked Matrix account to the rooms and spaces mapped to their profile.
+func (app *appContext) inviteToMatrixRooms(jfID string) {
+	mxUser, rooms, ok := app.linkedMatrixRooms(jfID)
+	if !ok {
+		return
+	}
+	for _, room := range rooms {
+		roomID, err := app.matrix.resolveRoom(room)
+		if err != nil {
+			app.err.Printf(lm.FailedResolveMatrixRoom, room, err)
+			continue
+		}
+		_, err = app.matrix.bot.InviteUser(context.TODO(), roomID, &mautrix.ReqInviteUser{UserID: id.UserID(mxUser.UserID)})
+		if err == nil {
+			app.debug.Printf(lm.InvitedMatrixRoom, mxUser.UserID, room)
+		} else if errors.Is(err, mautrix.MForbidden) {
+			// Most likely they're already in it.
+			app.debug.Printf(lm.FailedInviteMatrixRoom, mxUser.UserID, room, err)
+		} else {
+			app.err.Printf(lm.FailedInviteMatrixRoom, mxUser.UserID, room, err)
+		}
+	}
+}
+
+// removeFromMatrixRooms kicks the given user's linked Matrix account from the rooms and spaces mapped to their profile,
+// which also revokes any invites they haven't accepted. Rooms in "keep" are left alone.
+func (app *appContext) removeFromMatrixRooms(jfID string, keep []string) {
+	mxUser, rooms, ok := app.linkedMatrixRooms(jfID)
+	if !ok {
+		return
+	}
+	lang := mxUser.Lang
+	if _, ok := app.storage.lang.Telegram[lang]; !ok {
+		lang = app.storage.lang.chosenTelegramLang
+	}
+	reason := app.storage.lang.Telegram[lang].Strings.get("matrixRoomRemoved")
+	for _, room := range rooms {
+		if slices.Contains(keep, room) {
+			continue
+		}
+		roomID, err := app.matrix.resolveRoom(room)
+		if err != nil {
+			app.err.Printf(lm.FailedResolveMatrixRoom, room, err)
+			continue
+		}
+		_, err = app.matrix.bot.KickUser(context.TODO(), roomID, &mautrix.ReqKickUser{UserID: id.UserID(mxUser.UserID), Reason: reason})
+		if err == nil {
+			app.debug.Printf(lm.RemovedMatrixRoom, mxUser.UserID, room)
+		} else if errors.Is(err, mautrix.MForbidden) {
+			// Most likely they've already left.
+			app.debug.Printf(lm.FailedRemoveMatrixRoom, mxUser.UserID, room, err)
+		} else {
+			app.err.Printf(lm.FailedRemoveMatrixRoom, mxUser.UserID, room, err)
+		}
+	}
+}
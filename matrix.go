@@ -184,6 +184,8 @@ func (d *MatrixDaemon) handleMessage(ctx context.Context, evt *event.Event) {
 		} else {
 			d.commandLang(evt, "", lang)
 		}
+	default:
+		d.handleAdminCommand(evt, sects, lang)
 	}
 }
 
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/lithammer/shortuuid/v3"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	QUEUE_POLL_INTERVAL = 10 * time.Second
+	QUEUE_BATCH_SIZE    = 100
+	QUEUE_MAX_BACKOFF   = 6 * time.Hour
+)
+
+// Channels messages can be queued for, named as in contact preferences.
+const (
+	ChannelEmail    = "email"
+	ChannelDiscord  = "discord"
+	ChannelTelegram = "telegram"
+	ChannelMatrix   = "matrix"
+	ChannelPush     = "push"
+)
+
+var queueChannels = []string{ChannelEmail, ChannelDiscord, ChannelTelegram, ChannelMatrix, ChannelPush}
+
+// errRecipientGone is returned when the user a message was queued for has since unlinked the channel.
+var errRecipientGone = errors.New("recipient no longer linked")
+
+// MessageQueue delivers messages stored in the database, with a worker for each channel so a slow one doesn't hold up the others.
+// Failed sends are retried with exponential backoff, and after too many attempts are kept as "dead" for admins to retry or delete.
+type MessageQueue struct {
+	app         *appContext
+	maxAttempts int
+	retryDelay  time.Duration
+	intervals   map[string]time.Duration // Minimum time between sends on each channel.
+	wake        map[string]chan bool
+	shutdown    chan bool
+	wg          sync.WaitGroup
+}
+
+func newMessageQueue(app *appContext) *MessageQueue {
+	section := app.config.Section("messages")
+	q := &MessageQueue{
+		app:         app,
+		maxAttempts: section.Key("max_attempts").MustInt(5),
+		retryDelay:  time.Duration(section.Key("retry_delay").MustInt(60)) * time.Second,
+		intervals:   map[string]time.Duration{},
+		wake:        map[string]chan bool{},
+		shutdown:    make(chan bool),
+	}
+	for _, channel := range queueChannels {
+		if perMinute := section.Key("rate_limit_" + channel).MustInt(0); perMinute > 0 {
+			q.intervals[channel] = time.Minute / time.Duration(perMinute)
+		}
+		q.wake[channel] = make(chan bool, 1)
+	}
+	return q
+}
+
+// queueBackoff returns the time to wait before the next attempt, doubling the base delay each attempt.
+func queueBackoff(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= QUEUE_MAX_BACKOFF {
+			return QUEUE_MAX_BACKOFF
+		}
+	}
+	return min(delay, QUEUE_MAX_BACKOFF)
+}
+
+func (q *MessageQueue) run() {
+	q.app.info.Printf(lm.StartDaemon, "Message queue")
+	for _, channel := range queueChannels {
+		q.wg.Add(1)
+		go q.worker(channel)
+	}
+}
+
+func (q *MessageQueue) Shutdown() {
+	close(q.shutdown)
+	q.wg.Wait()
+}
+
+// Wake makes the given channel's worker check for messages now, rather than on its next poll.
+func (q *MessageQueue) Wake(channel string) {
+	select {
+	case q.wake[channel] <- true:
+	default:
+	}
+}
+
+// Enqueue stores a message to be sent to the given user or email address through the given channel.
+func (q *MessageQueue) Enqueue(msg *Message, channel, jfID, address, destination string) error {
+	now := time.Now()
+	id := shortuuid.New()
+	err := q.app.storage.db.Insert(id, QueuedMessage{
+		ID:          id,
+		Channel:     channel,
+		JellyfinID:  jfID,
+		Address:     address,
+		Destination: destination,
+		Message:     *msg,
+		Created:     now,
+		NextAttempt: now,
+	})
+	if err != nil {
+		return err
+	}
+	q.Wake(channel)
+	return nil
+}
+
+// due returns the messages for the given channel ready to be (re)tried, oldest first.
+func (q *MessageQueue) due(channel string) []QueuedMessage {
+	result := []QueuedMessage{}
+	q.app.storage.db.Find(&result, badgerhold.Where("Channel").Eq(channel).And("Dead").Eq(false).And("NextAttempt").Le(time.Now()).SortBy("Created").Limit(QUEUE_BATCH_SIZE))
+	return result
+}
+
+func (q *MessageQueue) worker(channel string) {
+	defer q.wg.Done()
+	for {
+		for _, msg := range q.due(channel) {
+			select {
+			case <-q.shutdown:
+				return
+			default:
+			}
+			q.deliver(msg)
+			if interval, ok := q.intervals[channel]; ok {
+				select {
+				case <-q.shutdown:
+					return
+				case <-time.After(interval):
+				}
+			}
+		}
+		select {
+		case <-q.shutdown:
+			return
+		case <-q.wake[channel]:
+		case <-time.After(QUEUE_POLL_INTERVAL):
+		}
+	}
+}
+
+// deliver attempts to send the message, deleting it if successful, and otherwise scheduling a retry or marking it dead.
+func (q *MessageQueue) deliver(msg QueuedMessage) {
+	err := q.send(&msg)
+	// Emails are logged by Emailer.send, as they can also be sent outside the queue.
+	if msg.Channel != ChannelEmail && !errors.Is(err, errRecipientGone) {
+		q.app.storage.logDelivery(&msg.Message, msg.Channel, msg.JellyfinID, msg.Destination, err)
+	}
+	if err == nil {
+		q.app.debug.Printf(lm.SentQueuedMessage, msg.Channel, msg.Destination)
+		q.app.storage.DeleteQueuedMessageKey(msg.ID)
+		return
+	}
+	if errors.Is(err, errRecipientGone) {
+		q.app.debug.Printf(lm.DropQueuedMessage, msg.Channel, msg.Destination)
+		q.app.storage.DeleteQueuedMessageKey(msg.ID)
+		return
+	}
+	msg.Attempts++
+	msg.LastError = err.Error()
+	if msg.Attempts >= q.maxAttempts {
+		q.app.err.Printf(lm.FailedSendQueuedMessage, msg.Channel, msg.Destination, msg.Attempts, err)
+		msg.Dead = true
+	} else {
+		msg.NextAttempt = time.Now().Add(queueBackoff(q.retryDelay, msg.Attempts))
+		q.app.info.Printf(lm.RetryQueuedMessage, msg.Channel, msg.Destination, msg.Attempts, msg.NextAttempt, err)
+	}
+	q.app.storage.SetQueuedMessageKey(msg.ID, msg)
+}
+
+// send sends the message through its channel, looking up the user's current details.
+func (q *MessageQueue) send(msg *QueuedMessage) error {
+	app := q.app
+	switch msg.Channel {
+	case ChannelEmail:
+		if !emailEnabled {
+			return errors.New("email disabled")
+		}
+		addr := msg.Address
+		if msg.JellyfinID != "" {
+			email, ok := app.storage.GetEmailsKey(msg.JellyfinID)
+			if !ok || email.Addr == "" || email.Undeliverable {
+				return errRecipientGone
+			}
+			addr = email.Addr
+			if msg.Message.Category != MessageCategoryRequired {
+				msg.Message.UnsubscribeURL = app.unsubscribeURL(msg.JellyfinID, msg.Message.Category)
+			}
+		}
+		return app.email.send(&msg.Message, addr)
+	case ChannelDiscord:
+		if !discordEnabled {
+			return errors.New("discord disabled")
+		}
+		dcChat, ok := app.storage.GetDiscordKey(msg.JellyfinID)
+		if !ok {
+			return errRecipientGone
+		}
+		return app.discord.Send(&msg.Message, dcChat.ChannelID)
+	case ChannelTelegram:
+		if !telegramEnabled {
+			return errors.New("telegram disabled")
+		}
+		tgChat, ok := app.storage.GetTelegramKey(msg.JellyfinID)
+		if !ok {
+			return errRecipientGone
+		}
+		return app.telegram.Send(&msg.Message, tgChat.ChatID)
+	case ChannelMatrix:
+		if !matrixEnabled {
+			return errors.New("matrix disabled")
+		}
+		mxChat, ok := app.storage.GetMatrixKey(msg.JellyfinID)
+		if !ok {
+			return errRecipientGone
+		}
+		return app.matrix.Send(&msg.Message, mxChat)
+	case ChannelPush:
+		if !pushEnabled {
+			return errors.New("push disabled")
+		}
+		pushUser, ok := app.storage.GetPushKey(msg.JellyfinID)
+		if !ok {
+			return errRecipientGone
+		}
+		return app.push.Send(&msg.Message, pushUser)
+	}
+	return errors.New("unknown channel \"" + msg.Channel + "\"")
+}
+
+// requeue resets a dead message so it's sent again.
+func (q *MessageQueue) requeue(msg QueuedMessage) {
+	msg.Dead = false
+	msg.Attempts = 0
+	msg.NextAttempt = time.Now()
+	q.app.storage.SetQueuedMessageKey(msg.ID, msg)
+	q.app.info.Printf(lm.RequeueMessage, msg.Channel, msg.Destination)
+	q.Wake(msg.Channel)
+}
+
+// @Summary Get messages which failed to send after all retries, and the number still waiting to be sent.
+// @Produce json
+// @Success 200 {object} GetDeadMessagesDTO
+// @Router /messages/failed [get]
+// @Security Bearer
+// @tags Messages
+func (app *appContext) GetDeadMessages(gc *gin.Context) {
+	dead := app.storage.GetDeadMessages()
+	pending, _ := app.storage.db.Count(&QueuedMessage{}, badgerhold.Where("Dead").Eq(false))
+	resp := GetDeadMessagesDTO{Messages: make([]QueuedMessageDTO, len(dead)), Pending: int(pending)}
+	for i, msg := range dead {
+		resp.Messages[i] = QueuedMessageDTO{
+			ID:          msg.ID,
+			Channel:     msg.Channel,
+			UserID:      msg.JellyfinID,
+			Destination: msg.Destination,
+			Subject:     msg.Message.Subject,
+			Created:     msg.Created.Unix(),
+			Attempts:    msg.Attempts,
+			LastError:   msg.LastError,
+		}
+		if msg.JellyfinID != "" {
+			if user, err := app.jf.UserByID(msg.JellyfinID, false); err == nil {
+				resp.Messages[i].Username = user.Name
+			}
+		}
+	}
+	gc.JSON(200, resp)
+}
+
+// @Summary Re-queue a failed message to be sent again.
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} boolResponse
+// @Failure 404 {object} boolResponse
+// @Router /messages/failed/{id} [post]
+// @Security Bearer
+// @tags Messages
+func (app *appContext) RetryDeadMessage(gc *gin.Context) {
+	msg, ok := app.storage.GetQueuedMessageKey(gc.Param("id"))
+	if !ok || !msg.Dead {
+		respondBool(404, false, gc)
+		return
+	}
+	app.messageQueue.requeue(msg)
+	respondBool(200, true, gc)
+}
+
+// @Summary Re-queue all failed messages to be sent again.
+// @Produce json
+// @Success 200 {object} boolResponse
+// @Router /messages/failed [post]
+// @Security Bearer
+// @tags Messages
+func (app *appContext) RetryDeadMessages(gc *gin.Context) {
+	for _, msg := range app.storage.GetDeadMessages() {
+		app.messageQueue.requeue(msg)
+	}
+	respondBool(200, true, gc)
+}
+
+// @Summary Delete a failed message.
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} boolResponse
+// @Failure 404 {object} boolResponse
+// @Router /messages/failed/{id} [delete]
+// @Security Bearer
+// @tags Messages
+func (app *appContext) DeleteDeadMessage(gc *gin.Context) {
+	msg, ok := app.storage.GetQueuedMessageKey(gc.Param("id"))
+	if !ok || !msg.Dead {
+		respondBool(404, false, gc)
+		return
+	}
+	app.storage.DeleteQueuedMessageKey(msg.ID)
+	respondBool(200, true, gc)
+}
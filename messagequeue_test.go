@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueBackoff(t *testing.T) {
+	base := time.Minute
+	cases := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		3:  4 * time.Minute,
+		5:  16 * time.Minute,
+		9:  256 * time.Minute,
+		10: QUEUE_MAX_BACKOFF,
+		50: QUEUE_MAX_BACKOFF,
+	}
+	for attempts, expected := range cases {
+		if got := queueBackoff(base, attempts); got != expected {
+			t.Errorf("attempt %d: expected %v, got %v", attempts, expected, got)
+		}
+	}
+	if got := queueBackoff(24*time.Hour, 1); got != QUEUE_MAX_BACKOFF {
+		t.Errorf("large base: expected %v, got %v", QUEUE_MAX_BACKOFF, got)
+	}
+}
@@ -396,6 +396,17 @@ func intialiseCustomContent(app *appContext) {
 	if _, ok := app.storage.GetCustomContentKey("ExpiryReminder"); !ok {
 		app.storage.SetCustomContentKey("ExpiryReminder", emptyCC)
 	}
+	if _, ok := app.storage.GetCustomContentKey("UserAdopted"); !ok {
+		app.storage.SetCustomContentKey("UserAdopted", emptyCC)
+	}
+	if _, ok := app.storage.GetCustomContentKey("AdminDigest"); !ok {
+		app.storage.SetCustomContentKey("AdminDigest", emptyCC)
+	}
+	for _, event := range adminEvents {
+		if _, ok := app.storage.GetCustomContentKey(event.Content); !ok {
+			app.storage.SetCustomContentKey(event.Content, emptyCC)
+		}
+	}
 	if _, ok := app.storage.GetCustomContentKey("PostSignupCard"); !ok {
 		app.storage.SetCustomContentKey("PostSignupCard", emptyCC)
 
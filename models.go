@@ -29,6 +29,8 @@ type newUserDTO struct {
 	DiscordContact  bool   `json:"discord_contact"`                             // Whether or not to use discord for notifications/pwrs
 	MatrixPIN       string `json:"matrix_pin" example:"A1-B2-3C"`               // Matrix verification PIN (if used)
 	MatrixContact   bool   `json:"matrix_contact"`                              // Whether or not to use matrix for notifications/pwrs
+	PushPIN         string `json:"push_pin" example:"A1-B2-3C"`                 // Push notification verification PIN (if used)
+	PushContact     bool   `json:"push_contact"`                                // Whether or not to use push notifications for notifications/pwrs
 	CaptchaID       string `json:"captcha_id"`                                  // Captcha ID (if enabled)
 	CaptchaText     string `json:"captcha_text"`                                // Captcha text (if enabled)
 	Profile         string `json:"profile"`                                     // Profile (for admins only)
@@ -154,24 +156,28 @@ type deleteInviteDTO struct {
 }
 
 type respUser struct {
-	ID                    string `json:"id" example:"fdgsdfg45534fa"`              // userID of user
-	Name                  string `json:"name" example:"jeff"`                      // Username of user
-	Email                 string `json:"email,omitempty" example:"jeff@jellyf.in"` // Email address of user (if available)
-	NotifyThroughEmail    bool   `json:"notify_email"`
-	LastActive            int64  `json:"last_active" example:"1617737207510"` // Time of last activity on Jellyfin
-	Admin                 bool   `json:"admin" example:"false"`               // Whether or not the user is Administrator
-	Expiry                int64  `json:"expiry" example:"1617737207510"`      // Expiry time of user as Epoch/Unix time.
-	Disabled              bool   `json:"disabled"`                            // Whether or not the user is disabled.
-	Telegram              string `json:"telegram"`                            // Telegram username (if known)
-	NotifyThroughTelegram bool   `json:"notify_telegram"`
-	Discord               string `json:"discord"`    // Discord username (if known)
-	DiscordID             string `json:"discord_id"` // Discord user ID for creating links.
-	NotifyThroughDiscord  bool   `json:"notify_discord"`
-	Matrix                string `json:"matrix"` // Matrix ID (if known)
-	NotifyThroughMatrix   bool   `json:"notify_matrix"`
-	Label                 string `json:"label"`          // Label of user, shown next to their name.
-	AccountsAdmin         bool   `json:"accounts_admin"` // Whether or not the user is a jfa-go admin.
-	ReferralsEnabled      bool   `json:"referrals_enabled"`
+	ID                    string               `json:"id" example:"fdgsdfg45534fa"`              // userID of user
+	Name                  string               `json:"name" example:"jeff"`                      // Username of user
+	Email                 string               `json:"email,omitempty" example:"jeff@jellyf.in"` // Email address of user (if available)
+	NotifyThroughEmail    bool                 `json:"notify_email"`
+	EmailUndeliverable    bool                 `json:"email_undeliverable"`                 // Whether or not mail to the address has bounced.
+	LastActive            int64                `json:"last_active" example:"1617737207510"` // Time of last activity on Jellyfin
+	Admin                 bool                 `json:"admin" example:"false"`               // Whether or not the user is Administrator
+	Expiry                int64                `json:"expiry" example:"1617737207510"`      // Expiry time of user as Epoch/Unix time.
+	Disabled              bool                 `json:"disabled"`                            // Whether or not the user is disabled.
+	Telegram              string               `json:"telegram"`                            // Telegram username (if known)
+	NotifyThroughTelegram bool                 `json:"notify_telegram"`
+	Discord               string               `json:"discord"`    // Discord username (if known)
+	DiscordID             string               `json:"discord_id"` // Discord user ID for creating links.
+	NotifyThroughDiscord  bool                 `json:"notify_discord"`
+	Matrix                string               `json:"matrix"` // Matrix ID (if known)
+	NotifyThroughMatrix   bool                 `json:"notify_matrix"`
+	Push                  string               `json:"push"` // ntfy topic/Gotify token (if known)
+	NotifyThroughPush     bool                 `json:"notify_push"`
+	Label                 string               `json:"label"`          // Label of user, shown next to their name.
+	AccountsAdmin         bool                 `json:"accounts_admin"` // Whether or not the user is a jfa-go admin.
+	ReferralsEnabled      bool                 `json:"referrals_enabled"`
+	Deliveries            []MessageLogEntryDTO `json:"deliveries,omitempty"` // Recent messages sent to the user, only given by GetUser.
 }
 
 // ServerSearchReqDTO is a usual SortablePaginatedReqDTO with added fields for searching and filtering.
@@ -231,15 +237,41 @@ type userSettingsDTO struct {
 }
 
 type announcementDTO struct {
-	Users   []string `json:"users"`   // List of User IDs to send announcement to
-	Subject string   `json:"subject"` // Email subject
-	Message string   `json:"message"` // Email content (markdown supported)
+	Users      []string            `json:"users"`                // List of User IDs to send announcement to
+	Subject    string              `json:"subject"`              // Email subject
+	Message    string              `json:"message"`              // Email content (markdown supported)
+	Filter     *ServerFilterReqDTO `json:"filter,omitempty"`     // If given, send to users matching this search when sent, rather than Users.
+	SendAt     int64               `json:"send_at,omitempty"`    // Time to send at (Unix). If zero or in the past (and not recurring), sent immediately.
+	Recurrence string              `json:"recurrence,omitempty"` // Repeat "daily", "weekly" or "monthly" from SendAt.
 }
 
 type announcementTemplate struct {
-	Name    string `json:"name"`    // Name of template
-	Subject string `json:"subject"` // Email subject
-	Message string `json:"message"` // Email content (markdown supported)
+	Name    string              `json:"name"`             // Name of template
+	Subject string              `json:"subject"`          // Email subject
+	Message string              `json:"message"`          // Email content (markdown supported)
+	Filter  *ServerFilterReqDTO `json:"filter,omitempty"` // Stored user search to send to, if any.
+}
+
+type AnnouncementSendDTO struct {
+	Time       int64 `json:"time"`
+	Recipients int   `json:"recipients"` // Number of users sent to.
+	Failed     int   `json:"failed"`     // Number of those the announcement couldn't be sent to.
+}
+
+type ScheduledAnnouncementDTO struct {
+	ID         string                `json:"id"`
+	Subject    string                `json:"subject"`
+	Message    string                `json:"message"`
+	Users      []string              `json:"users"`
+	Filter     *ServerFilterReqDTO   `json:"filter,omitempty"`
+	Recurrence string                `json:"recurrence"`
+	NextSend   int64                 `json:"next_send"` // Zero if already sent or cancelled.
+	Created    int64                 `json:"created"`
+	History    []AnnouncementSendDTO `json:"history"`
+}
+
+type ScheduledAnnouncementsDTO struct {
+	Announcements []ScheduledAnnouncementDTO `json:"announcements"`
 }
 
 type getAnnouncementsDTO struct {
@@ -310,6 +342,7 @@ type SetContactPreferencesDTO struct {
 	Discord  bool   `json:"discord"`
 	Telegram bool   `json:"telegram"`
 	Matrix   bool   `json:"matrix"`
+	Push     bool   `json:"push"`
 }
 
 type DiscordUserDTO struct {
@@ -345,6 +378,54 @@ type MatrixConnectUserDTO struct {
 	UserID     string `json:"user_id"`
 }
 
+type MessageLogEntryDTO struct {
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	Type        string `json:"type"`
+	Channel     string `json:"channel"`
+	Destination string `json:"destination"`
+	Subject     string `json:"subject"`
+	Time        int64  `json:"time"`
+	Error       string `json:"error"` // Blank if sent successfully.
+}
+
+type MessageLogReqDTO struct {
+	PaginatedReqDTO
+	UserID  string `json:"user_id"`
+	Search  string `json:"search"` // Matched against usernames and destinations.
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	Failed  bool   `json:"failed"` // Only return failed deliveries.
+}
+
+type MessageLogDTO struct {
+	PaginatedDTO
+	Entries []MessageLogEntryDTO `json:"entries"`
+	Types   []string             `json:"types"` // Message types that can be filtered by.
+}
+
+type QueuedMessageDTO struct {
+	ID          string `json:"id"`
+	Channel     string `json:"channel"`
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	Destination string `json:"destination"`
+	Subject     string `json:"subject"`
+	Created     int64  `json:"created"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error"`
+}
+
+type GetDeadMessagesDTO struct {
+	Messages []QueuedMessageDTO `json:"messages"`
+	Pending  int                `json:"pending"` // Number of messages waiting to be sent or retried.
+}
+
+type PushSendPINDTO struct {
+	Topic string `json:"topic"` // ntfy topic or Gotify application token.
+}
+
 type MatrixLoginDTO struct {
 	Homeserver string `json:"homeserver"`
 	Username   string `json:"username"`
@@ -414,9 +495,15 @@ type MyDetailsDTO struct {
 	Discord       *MyDetailsContactMethodsDTO `json:"discord,omitempty"`
 	Telegram      *MyDetailsContactMethodsDTO `json:"telegram,omitempty"`
 	Matrix        *MyDetailsContactMethodsDTO `json:"matrix,omitempty"`
+	Push          *MyDetailsContactMethodsDTO `json:"push,omitempty"`
 	HasReferrals  bool                        `json:"has_referrals,omitempty"`
+	CalendarToken string                      `json:"calendar_token,omitempty"`
+	Preferences   MessagePreferencesDTO       `json:"message_preferences"`
 }
 
+// MessagePreferencesDTO maps message categories to whether they're sent on each channel.
+type MessagePreferencesDTO map[string]map[string]bool
+
 type MyDetailsContactMethodsDTO struct {
 	Value   string `json:"value"`
 	Enabled bool   `json:"enabled"`
@@ -545,3 +632,59 @@ type ActivityLogEntryDTO struct {
 	mediabrowser.ActivityLogEntry
 	Date int64 `json:"Date"`
 }
+
+type GenerateRenewalCodesDTO struct {
+	Count         int    `json:"count" example:"10"`           // Number of codes to generate
+	Months        int    `json:"months" example:"1"`           // Months added to the user's expiry on redemption
+	Days          int    `json:"days" example:"0"`             // Days added to the user's expiry on redemption
+	Hours         int    `json:"hours" example:"0"`            // Hours added to the user's expiry on redemption
+	Minutes       int    `json:"minutes" example:"0"`          // Minutes added to the user's expiry on redemption
+	ValidMonths   int    `json:"valid_months" example:"0"`     // Months the codes are valid for
+	ValidDays     int    `json:"valid_days" example:"30"`      // Days the codes are valid for
+	NoLimit       bool   `json:"no_limit" example:"false"`     // Codes can be redeemed any number of times
+	RemainingUses int    `json:"remaining_uses" example:"1"`   // Number of times each code can be redeemed
+	Label         string `json:"label" example:"Black Friday"` // Optional label for the codes
+}
+
+type RenewalCodeDTO struct {
+	Code          string           `json:"code"`
+	Created       int64            `json:"created"`
+	ValidTill     int64            `json:"valid_till"`
+	NoLimit       bool             `json:"no_limit"`
+	RemainingUses int              `json:"remaining_uses"`
+	Months        int              `json:"months"`
+	Days          int              `json:"days"`
+	Hours         int              `json:"hours"`
+	Minutes       int              `json:"minutes"`
+	Label         string           `json:"label,omitempty"`
+	UsedBy        map[string]int64 `json:"used_by,omitempty"` // Usernames of users who redeemed this code mapped to the time they did so in Epoch/Unix time
+}
+
+type GetRenewalCodesDTO struct {
+	Codes []RenewalCodeDTO `json:"codes"`
+}
+
+type DeleteRenewalCodesDTO struct {
+	Codes []string `json:"codes"`
+}
+
+type CalendarTokenDTO struct {
+	Token string `json:"token"`
+}
+
+type RedeemRenewalCodeDTO struct {
+	Code string `json:"code"`
+}
+
+type PaymentCustomerDTO struct {
+	CustomerID string `json:"customer_id" example:"cus_NffrFeUfNV2Hib"` // Customer ID from the payment provider
+	UserID     string `json:"user_id"`                                  // Jellyfin ID of the user
+}
+
+type GetPaymentCustomersDTO struct {
+	Customers []PaymentCustomerDTO `json:"customers"`
+}
+
+type RedeemRenewalCodeRespDTO struct {
+	Expiry int64 `json:"expiry"` // New expiry of the user in Epoch/Unix time
+}
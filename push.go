@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	PushNtfy   = "ntfy"
+	PushGotify = "gotify"
+)
+
+// Both ntfy topics and Gotify app tokens fit this, and it stops users pointing requests at other paths on the server.
+var pushTopicPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// PushDaemon sends notifications to ntfy topics or Gotify applications. There's nothing to run, but it's named as such for consistency with the other contact methods.
+type PushDaemon struct {
+	provider    string
+	server      string
+	token       string   // Access token for ntfy, if the server requires one.
+	tokenTopics []string // Topics the access token is sent to, see useToken.
+	priority    int
+	client      *http.Client
+	tokens      map[string]UnverifiedPushUser // Map of PINs to users.
+	tokensLock  sync.Mutex
+	app         *appContext
+}
+
+type UnverifiedPushUser struct {
+	Verified bool
+	Expiry   time.Time
+	User     *PushUser
+}
+
+// ValidPushTopic returns whether the given topic/token is in a form that can be sent to.
+func ValidPushTopic(topic string) bool {
+	return pushTopicPattern.MatchString(topic)
+}
+
+func newPushDaemon(app *appContext) (*PushDaemon, error) {
+	push := app.config.Section("push")
+	d := &PushDaemon{
+		provider:    push.Key("provider").In(PushNtfy, []string{PushNtfy, PushGotify}),
+		server:      strings.TrimSuffix(push.Key("server").String(), "/"),
+		token:       push.Key("token").String(),
+		tokenTopics: push.Key("token_topics").StringsWithShadows("|"),
+		priority:    push.Key("priority").MustInt(0),
+		client:      &http.Client{Timeout: 10 * time.Second},
+		tokens:      map[string]UnverifiedPushUser{},
+		app:         app,
+	}
+	if d.server == "" {
+		if d.provider != PushNtfy {
+			return nil, errors.New("no server URL set")
+		}
+		d.server = "https://ntfy.sh"
+	}
+	return d, nil
+}
+
+// SetTransport sets the http.Transport to use for requests. Can be used to set a proxy.
+func (d *PushDaemon) SetTransport(t *http.Transport) {
+	d.client.Transport = t
+}
+
+// useToken returns whether the access token should be sent with notifications to the given topic.
+// As users choose their own topics, it's only sent to those the admin has listed, either exactly or as a prefix ending in "*".
+func (d *PushDaemon) useToken(topic string) bool {
+	if d.token == "" {
+		return false
+	}
+	for _, t := range d.tokenTopics {
+		t = strings.TrimSpace(t)
+		if t == topic || (strings.HasSuffix(t, "*") && strings.HasPrefix(topic, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// send delivers a single notification to the given ntfy topic or Gotify app token.
+func (d *PushDaemon) send(topic, title, body string, markdown bool) error {
+	if !ValidPushTopic(topic) {
+		return fmt.Errorf("invalid topic \"%s\"", topic)
+	}
+	var req *http.Request
+	var err error
+	switch d.provider {
+	case PushGotify:
+		msg := map[string]any{
+			"title":   title,
+			"message": body,
+		}
+		if d.priority != 0 {
+			msg["priority"] = d.priority
+		}
+		if markdown {
+			msg["extras"] = map[string]any{
+				"client::display": map[string]string{"contentType": "text/markdown"},
+			}
+		}
+		var data []byte
+		data, err = json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		req, err = http.NewRequest("POST", d.server+"/message", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gotify-Key", topic)
+	default:
+		req, err = http.NewRequest("POST", d.server+"/"+topic, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if title != "" {
+			// Headers must be ASCII, ntfy decodes RFC 2047 encoded ones.
+			req.Header.Set("Title", mime.QEncoding.Encode("utf-8", title))
+		}
+		if markdown {
+			req.Header.Set("Markdown", "yes")
+		}
+		if d.priority != 0 {
+			req.Header.Set("Priority", fmt.Sprint(d.priority))
+		}
+		if d.useToken(topic) {
+			req.Header.Set("Authorization", "Bearer "+d.token)
+		}
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf(lm.FailedGenericWithCode, resp.StatusCode)
+	}
+	return nil
+}
+
+// Send sends the given message to each of the given users, in markdown if available.
+func (d *PushDaemon) Send(message *Message, users ...PushUser) (err error) {
+	body, markdown := message.Text, false
+	if message.Markdown != "" {
+		body, markdown = message.Markdown, true
+	}
+	for _, user := range users {
+		err = d.send(user.Topic, message.Subject, body, markdown)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// SendStart sends a verification PIN to the given topic.
+func (d *PushDaemon) SendStart(topic string) (ok bool) {
+	// Chatbot messages share the language set in the "telegram" section.
+	lang := d.app.config.Section("telegram").Key("language").MustString("en-us")
+	if _, ok := d.app.storage.lang.Telegram[lang]; !ok {
+		lang = "en-us"
+	}
+	pin := genAuthToken()
+	err := d.send(topic, "Jellyfin", d.app.storage.lang.Telegram[lang].Strings.get("pushStartMessage")+"\n\n"+pin, false)
+	if err != nil {
+		d.app.err.Printf(lm.FailedMessage, lm.Push, topic, err)
+		return
+	}
+	d.tokensLock.Lock()
+	// Nothing else clears out PINs that were never used, so do it here.
+	for k, token := range d.tokens {
+		if time.Now().After(token.Expiry) {
+			delete(d.tokens, k)
+		}
+	}
+	d.tokens[pin] = UnverifiedPushUser{
+		false,
+		time.Now().Add(VERIF_TOKEN_EXPIRY_SEC * time.Second),
+		&PushUser{Topic: topic},
+	}
+	d.tokensLock.Unlock()
+	ok = true
+	return
+}
+
+// VerifyPIN marks the token with the given PIN as verified, returning it if it existed.
+func (d *PushDaemon) VerifyPIN(pin string) (user *PushUser, ok bool) {
+	d.tokensLock.Lock()
+	defer d.tokensLock.Unlock()
+	token, ok := d.tokens[pin]
+	if !ok {
+		return
+	}
+	if time.Now().After(token.Expiry) {
+		delete(d.tokens, pin)
+		return nil, false
+	}
+	token.Verified = true
+	d.tokens[pin] = token
+	return token.User, true
+}
+
+// UserExists returns whether or not a user with the given topic exists.
+func (d *PushDaemon) UserExists(topic string) bool {
+	c, err := d.app.storage.db.Count(&PushUser{}, badgerhold.Where("Topic").Eq(topic))
+	return err != nil || c > 0
+}
+
+// Exists returns whether or not the given user exists.
+func (d *PushDaemon) Exists(user ContactMethodUser) bool {
+	return d.UserExists(user.Name())
+}
+
+func (d *PushDaemon) PIN(req newUserDTO) string { return req.PushPIN }
+
+func (d *PushDaemon) Name() string { return lm.Push }
+
+func (d *PushDaemon) Required() bool {
+	return d.app.config.Section("push").Key("required").MustBool(false)
+}
+
+func (d *PushDaemon) UniqueRequired() bool {
+	return d.app.config.Section("push").Key("require_unique").MustBool(false)
+}
+
+// DeleteVerifiedToken removes the token with the given PIN.
+func (d *PushDaemon) DeleteVerifiedToken(PIN string) {
+	d.tokensLock.Lock()
+	delete(d.tokens, PIN)
+	d.tokensLock.Unlock()
+}
+
+// UserVerified returns the user linked to the given PIN, if it's been verified.
+func (d *PushDaemon) UserVerified(PIN string) (ContactMethodUser, bool) {
+	d.tokensLock.Lock()
+	token, ok := d.tokens[PIN]
+	d.tokensLock.Unlock()
+	if !ok || !token.Verified || time.Now().After(token.Expiry) {
+		return &PushUser{}, false
+	}
+	return token.User, true
+}
+
+func (d *PushDaemon) PostVerificationTasks(string, ContactMethodUser) error { return nil }
+
+func (p *PushUser) Name() string                          { return p.Topic }
+func (p *PushUser) SetMethodID(id any)                    { p.Topic = id.(string) }
+func (p *PushUser) MethodID() any                         { return p.Topic }
+func (p *PushUser) SetJellyfin(id string)                 { p.JellyfinID = id }
+func (p *PushUser) Jellyfin() string                      { return p.JellyfinID }
+func (p *PushUser) SetAllowContactFromDTO(req newUserDTO) { p.Contact = req.PushContact }
+func (p *PushUser) SetAllowContact(contact bool)          { p.Contact = contact }
+func (p *PushUser) AllowContact() bool                    { return p.Contact }
+func (p *PushUser) Store(st *Storage) {
+	st.SetPushKey(p.Jellyfin(), *p)
+}
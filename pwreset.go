@@ -34,6 +34,31 @@ func (app *appContext) GenInternalReset(userID string) (InternalPWR, error) {
 	return pwr, nil
 }
 
+// newInternalReset generates and stores a local password reset for the given user, and constructs the message containing its link.
+func (app *appContext) newInternalReset(userID string) (pwr InternalPWR, msg *Message, err error) {
+	pwr, err = app.GenInternalReset(userID)
+	if err != nil {
+		app.err.Printf(lm.FailedGetUsers, lm.Jellyfin, err)
+		return
+	}
+	if app.internalPWRs == nil {
+		app.internalPWRs = map[string]InternalPWR{}
+	}
+	app.internalPWRs[pwr.PIN] = pwr
+	msg, err = app.email.constructReset(
+		PasswordReset{
+			Pin:      pwr.PIN,
+			Username: pwr.Username,
+			Expiry:   pwr.Expiry,
+			Internal: true,
+		}, false,
+	)
+	if err != nil {
+		app.err.Printf(lm.FailedConstructPWRMessage, pwr.Username, err)
+	}
+	return
+}
+
 // GenResetLink generates and returns a password reset link.
 func GenResetLink(pin string) (string, error) {
 	url := ExternalURI(nil)
@@ -128,7 +153,7 @@ func validatePWR(app *appContext, fname string, attempt int) {
 
 		if err != nil {
 			app.err.Printf(lm.FailedConstructPWRMessage, pwr.Username, err)
-		} else if err := app.sendByID(msg, user.ID); err != nil {
+		} else if err := app.sendByID(msg, MessageCategoryRequired, user.ID); err != nil {
 			app.err.Printf(lm.FailedSendPWRMessage, pwr.Username, name, err)
 		} else {
 			app.err.Printf(lm.SentPWRMessage, pwr.Username, name)
@@ -163,6 +163,10 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 		}
 		if discordEnabled {
 			router.GET(p+PAGES.Form+"/:invCode/discord/verified/:pin", app.DiscordVerifiedInvite)
+			if app.discordOAuthEnabled() {
+				router.GET(p+"/discord/oauth/start", app.DiscordOAuthStart)
+				router.GET(p+"/discord/oauth", app.DiscordOAuthCallback)
+			}
 			if app.config.Section("discord").Key("provide_invite").MustBool(false) {
 				router.GET(p+PAGES.Form+"/:invCode/discord/invite", app.DiscordServerInvite)
 			}
@@ -172,6 +176,22 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 			router.POST(p+PAGES.Form+"/:invCode/matrix/user", app.MatrixSendPIN)
 			router.POST(p+"/users/matrix", app.MatrixConnect)
 		}
+		if pushEnabled {
+			router.GET(p+PAGES.Form+"/:invCode/push/verified/:pin", app.PushCheckPIN)
+			router.POST(p+PAGES.Form+"/:invCode/push/user", app.PushSendPIN)
+		}
+		if app.config.Section("payment_webhook").Key("enabled").MustBool(false) {
+			router.POST(p+"/webhooks/payment", app.PaymentWebhook)
+		}
+		if app.config.Section("email_bounces").Key("enabled").MustBool(false) {
+			router.POST(p+"/webhooks/bounce", app.BounceWebhook)
+		}
+		router.GET(p+"/unsubscribe/:token", app.UnsubscribePage)
+		router.POST(p+"/unsubscribe/:token", app.Unsubscribe)
+		if app.config.Section("user_expiry").Key("calendar_feed").MustBool(false) {
+			router.GET(p+"/calendar/user/:token", app.UserCalendar)
+			router.GET(p+"/calendar/expiries/:token", app.AdminCalendar)
+		}
 		if userPageEnabled {
 			router.GET(p+PAGES.MyAccount, app.MyUserPage)
 			router.GET(p+PAGES.MyAccount+"/password/reset", app.MyUserPage)
@@ -206,6 +226,10 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 		api.POST(p+"/user", app.NewUserFromAdmin)
 		api.POST(p+"/users/extend", app.ExtendExpiry)
 		api.DELETE(p+"/users/:id/expiry", app.RemoveExpiry)
+		if app.config.Section("user_expiry").Key("calendar_feed").MustBool(false) {
+			api.GET(p+"/calendar/token", app.GetAdminCalendarToken)
+			api.POST(p+"/calendar/token", app.ResetAdminCalendarToken)
+		}
 		api.GET(p+"/users/:id", app.GetUser)
 		api.GET(p+"/users/:id/activities/jellyfin", app.GetJFActivitesForUser)
 		api.GET(p+"/users/:id/activities/jellyfin/count", app.CountJFActivitesForUser)
@@ -218,6 +242,19 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 		api.DELETE(p+"/invites", app.DeleteInvite)
 		api.POST(p+"/invites/send", app.SendInvite)
 		api.PATCH(p+"/invites/edit", app.EditInvite)
+		api.POST(p+"/renewals", app.GenerateRenewalCodes)
+		api.GET(p+"/renewals", app.GetRenewalCodes)
+		api.DELETE(p+"/renewals", app.DeleteRenewalCodes)
+		if app.config.Section("payment_webhook").Key("enabled").MustBool(false) {
+			api.GET(p+"/payments/customers", app.GetPaymentCustomers)
+			api.POST(p+"/payments/customers", app.SetPaymentCustomer)
+			api.DELETE(p+"/payments/customers/:id", app.DeletePaymentCustomer)
+		}
+		api.POST(p+"/messages/log", app.GetMessageLog)
+		api.GET(p+"/messages/failed", app.GetDeadMessages)
+		api.POST(p+"/messages/failed", app.RetryDeadMessages)
+		api.POST(p+"/messages/failed/:id", app.RetryDeadMessage)
+		api.DELETE(p+"/messages/failed/:id", app.DeleteDeadMessage)
 		api.GET(p+"/profiles", app.GetProfiles)
 		api.GET(p+"/profiles/names", app.GetProfileNames)
 		api.GET(p+"/profiles/raw/:name", app.GetRawProfile)
@@ -234,6 +271,8 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 
 		api.GET(p+"/users/announce", app.GetAnnounceTemplates)
 		api.POST(p+"/users/announce/template", app.SaveAnnounceTemplate)
+		api.GET(p+"/users/announce/scheduled", app.GetScheduledAnnouncements)
+		api.DELETE(p+"/users/announce/scheduled/:id", app.CancelScheduledAnnouncement)
 		api.GET(p+"/users/announce/:name", app.GetAnnounceTemplate)
 		api.DELETE(p+"/users/announce/:name", app.DeleteAnnounceTemplate)
 
@@ -268,6 +307,9 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 			api.DELETE(p+"/users/discord", app.UnlinkDiscord)
 			api.DELETE(p+"/users/matrix", app.UnlinkMatrix)
 		}
+		if pushEnabled {
+			api.DELETE(p+"/users/push", app.UnlinkPush)
+		}
 		if emailEnabled {
 			api.POST(p+"/users/contact", app.SetContactMethods)
 		}
@@ -301,6 +343,7 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 		if userPageEnabled {
 			user.GET("/details", app.MyDetails)
 			user.POST("/contact", app.SetMyContactMethods)
+			user.POST("/preferences", app.SetMyMessagePreferences)
 			user.POST("/logout", app.LogoutUser)
 			user.POST("/email", app.ModifyMyEmail)
 			user.GET("/discord/invite", app.MyDiscordServerInvite)
@@ -312,10 +355,21 @@ func (app *appContext) loadRoutes(router *gin.Engine) {
 			user.DELETE("/discord", app.UnlinkMyDiscord)
 			user.DELETE("/telegram", app.UnlinkMyTelegram)
 			user.DELETE("/matrix", app.UnlinkMyMatrix)
+			if pushEnabled {
+				user.POST("/push/user", app.PushSendMyPIN)
+				user.GET("/push/verified/:pin", app.PushCheckMyPIN)
+				user.DELETE("/push", app.UnlinkMyPush)
+			}
 			user.POST("/password", app.ChangeMyPassword)
 			if app.config.Section("user_page").Key("referrals").MustBool(false) {
 				user.GET("/referral", app.GetMyReferral)
 			}
+			if app.config.Section("user_page").Key("renewal_codes").MustBool(false) {
+				user.POST("/renewal", app.RedeemMyRenewalCode)
+			}
+			if app.config.Section("user_expiry").Key("calendar_feed").MustBool(false) {
+				user.POST("/calendar", app.ResetMyCalendarToken)
+			}
 		}
 	}
 }
@@ -37,6 +37,13 @@ const (
 	ActivityResetPassword
 	ActivityCreateInvite
 	ActivityDeleteInvite
+	ActivityAdopted
+	ActivityDowngraded
+	ActivityRenewed
+	ActivityPayment
+	ActivityEmailUndeliverable
+	ActivityAdminCommand
+	ActivityDiscordDeparture
 	ActivityUnknown
 )
 
@@ -46,7 +53,7 @@ const (
 	ActivityUser   ActivitySource = iota // Source = UserID. For ActivityCreation, this would mean the referrer.
 	ActivityAdmin                        // Source = Admin's UserID, or blank if jellyfin login isn't on.
 	ActivityAnon                         // Source = Blank, or potentially browser info. For ActivityCreation, this would be via an invite
-	ActivityDaemon                       // Source = Blank, was deleted/disabled due to expiry by daemon
+	ActivityDaemon                       // Source = Blank, was deleted/disabled due to expiry by daemon, or DISCORD_DEPARTURE_SOURCE/TELEGRAM_CHAT_SOURCE if disabled/enabled after leaving/rejoining the Discord server/Telegram chat
 )
 
 type Activity struct {
@@ -56,7 +63,7 @@ type Activity struct {
 	SourceType ActivitySource
 	Source     string
 	InviteCode string // Set for ActivityCreation, create/deleteInvite
-	Value      string // Used for ActivityContactLinked where it's "email/discord/telegram/matrix", Create/DeleteInvite, where it's the label, and Creation/Deletion, where it's the Username.
+	Value      string // Used for ActivityContactLinked where it's "email/discord/telegram/matrix", Create/DeleteInvite, where it's the label, Creation/Deletion/Adopted, where it's the Username, Renewed/Payment, where it's the renewal code/event ID, EmailUndeliverable, where it's the bounce reason, AdminCommand, where it's the command run through a chat bot, and DiscordDeparture, where it's "left" or "banned".
 	Time       time.Time
 	IP         string
 }
@@ -64,8 +71,18 @@ type Activity struct {
 type UserExpiry struct {
 	JellyfinID        string `badgerhold:"key"`
 	Expiry            time.Time
-	DeleteAfterPeriod bool      // Whether or not to further disable the user later on
-	LastNotified      time.Time // Last time an expiry notification/reminder was sent to the user.
+	DeleteAfterPeriod bool                // Whether or not to further disable the user later on
+	LastNotified      time.Time           // Last time an expiry notification/reminder was sent to the user.
+	Downgraded        bool                // Whether the user was downgraded to the fallback profile on expiry, rather than disabled/deleted.
+	PreviousPolicy    mediabrowser.Policy // Policy the user had before being downgraded, restored when their expiry is extended.
+}
+
+// KnownUser marks a Jellyfin user as having been seen by the adoption daemon,
+// so it isn't adopted again once its other records have been cleared.
+type KnownUser struct {
+	JellyfinID string    `badgerhold:"key"`
+	Seen       time.Time // When the user was first seen.
+	Adopted    bool      // Whether the user was adopted, rather than being created by jfa-go or already existing.
 }
 
 type DebugLogAction int
@@ -153,6 +170,8 @@ const (
 	StoredExpiries
 	StoredProfiles
 	StoredCustomContent
+	StoredRenewalCodes
+	StoredPush
 )
 
 // DebugWatch logs database writes according on the advanced debugging settings in the Advanced section
@@ -180,6 +199,10 @@ func (st *Storage) DebugWatch(storeType StoreType, key, mainData string) {
 		actionKey = "profiles"
 	case StoredCustomContent:
 		actionKey = "custom_content"
+	case StoredRenewalCodes:
+		actionKey = "renewal_codes"
+	case StoredPush:
+		actionKey = "push"
 	}
 
 	logAction := st.logActions(actionKey)
@@ -197,7 +220,7 @@ func (st *Storage) DebugWatch(storeType StoreType, key, mainData string) {
 
 func generateLogActions(c *Config) func(k string) DebugLogAction {
 	m := map[string]DebugLogAction{}
-	for _, v := range []string{"emails", "discord", "telegram", "matrix", "invites", "announcements", "expirires", "profiles", "custom_content"} {
+	for _, v := range []string{"emails", "discord", "telegram", "matrix", "invites", "announcements", "expirires", "profiles", "custom_content", "renewal_codes", "push"} {
 		switch c.Section("advanced").Key("debug_log_" + v).MustString("none") {
 		case "none":
 			m[v] = NoLog
@@ -286,6 +309,66 @@ func (st *Storage) DeleteDiscordKey(k string) {
 	st.db.Delete(k, DiscordUser{})
 }
 
+// GetDiscordDepartures returns the departures recorded for accounts linked to the given Discord user.
+func (st *Storage) GetDiscordDepartures(discordID string) []DiscordDeparture {
+	result := []DiscordDeparture{}
+	err := st.db.Find(&result, badgerhold.Where("DiscordID").Eq(discordID))
+	if err != nil {
+		// fmt.Printf("Failed to find departures: %v\n", err)
+	}
+	return result
+}
+
+// GetDueDiscordDepartures returns the departures whose grace period ended before the given time.
+func (st *Storage) GetDueDiscordDepartures(now time.Time) []DiscordDeparture {
+	result := []DiscordDeparture{}
+	err := st.db.Find(&result, badgerhold.Where("DisableAt").Le(now))
+	if err != nil {
+		// fmt.Printf("Failed to find departures: %v\n", err)
+	}
+	return result
+}
+
+// SetDiscordDepartureKey stores value v in key k.
+func (st *Storage) SetDiscordDepartureKey(k string, v DiscordDeparture) {
+	v.JellyfinID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set departure: %v\n", err)
+	}
+}
+
+// DeleteDiscordDepartureKey deletes value at key k.
+func (st *Storage) DeleteDiscordDepartureKey(k string) {
+	st.db.Delete(k, DiscordDeparture{})
+}
+
+// GetTelegramDepartureKey returns the value stored in the store's key.
+func (st *Storage) GetTelegramDepartureKey(k string) (TelegramDeparture, bool) {
+	result := TelegramDeparture{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find departure: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetTelegramDepartureKey stores value v in key k.
+func (st *Storage) SetTelegramDepartureKey(k string, v TelegramDeparture) {
+	v.JellyfinID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set departure: %v\n", err)
+	}
+}
+
+// DeleteTelegramDepartureKey deletes value at key k.
+func (st *Storage) DeleteTelegramDepartureKey(k string) {
+	st.db.Delete(k, TelegramDeparture{})
+}
+
 // GetTelegram returns a copy of the store.
 func (st *Storage) GetTelegram() []TelegramUser {
 	result := []TelegramUser{}
@@ -362,6 +445,70 @@ func (st *Storage) DeleteMatrixKey(k string) {
 	st.db.Delete(k, MatrixUser{})
 }
 
+// GetPush returns a copy of the store.
+func (st *Storage) GetPush() []PushUser {
+	result := []PushUser{}
+	err := st.db.Find(&result, &badgerhold.Query{})
+	if err != nil {
+		// fmt.Printf("Failed to find users: %v\n", err)
+	}
+	return result
+}
+
+// GetPushKey returns the value stored in the store's key.
+func (st *Storage) GetPushKey(k string) (PushUser, bool) {
+	result := PushUser{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find user: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetPushKey stores value v in key k.
+func (st *Storage) SetPushKey(k string, v PushUser) {
+	st.DebugWatch(StoredPush, k, v.Topic)
+	v.JellyfinID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set user: %v\n", err)
+	}
+}
+
+// DeletePushKey deletes value at key k.
+func (st *Storage) DeletePushKey(k string) {
+	st.DebugWatch(StoredPush, k, "")
+	st.db.Delete(k, PushUser{})
+}
+
+// GetMessagePreferencesKey returns the value stored in the store's key.
+func (st *Storage) GetMessagePreferencesKey(k string) (MessagePreferences, bool) {
+	result := MessagePreferences{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find preferences: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetMessagePreferencesKey stores value v in key k.
+func (st *Storage) SetMessagePreferencesKey(k string, v MessagePreferences) {
+	v.JellyfinID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set preferences: %v\n", err)
+	}
+}
+
+// DeleteMessagePreferencesKey deletes value at key k.
+func (st *Storage) DeleteMessagePreferencesKey(k string) {
+	st.db.Delete(k, MessagePreferences{})
+}
+
 // GetInvites returns a copy of the store.
 func (st *Storage) GetInvites() []Invite {
 	result := []Invite{}
@@ -437,6 +584,42 @@ func (st *Storage) DeleteAnnouncementsKey(k string) {
 	st.db.Delete(k, announcementTemplate{})
 }
 
+// GetScheduledAnnouncements returns a copy of the store.
+func (st *Storage) GetScheduledAnnouncements() []ScheduledAnnouncement {
+	result := []ScheduledAnnouncement{}
+	err := st.db.Find(&result, &badgerhold.Query{})
+	if err != nil {
+		// fmt.Printf("Failed to find scheduled announcements: %v\n", err)
+	}
+	return result
+}
+
+// GetScheduledAnnouncementKey returns the value stored in the store's key.
+func (st *Storage) GetScheduledAnnouncementKey(k string) (ScheduledAnnouncement, bool) {
+	result := ScheduledAnnouncement{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find scheduled announcement: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetScheduledAnnouncementKey stores value v in key k.
+func (st *Storage) SetScheduledAnnouncementKey(k string, v ScheduledAnnouncement) {
+	v.ID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set scheduled announcement: %v\n", err)
+	}
+}
+
+// DeleteScheduledAnnouncementKey deletes value at key k.
+func (st *Storage) DeleteScheduledAnnouncementKey(k string) {
+	st.db.Delete(k, ScheduledAnnouncement{})
+}
+
 // GetUserExpiries returns a copy of the store.
 func (st *Storage) GetUserExpiries() []UserExpiry {
 	result := []UserExpiry{}
@@ -621,6 +804,193 @@ func (st *Storage) DeleteActivityKey(k string) {
 	st.db.Delete(k, Activity{})
 }
 
+// GetKnownUsers returns a copy of the store.
+func (st *Storage) GetKnownUsers() []KnownUser {
+	result := []KnownUser{}
+	err := st.db.Find(&result, &badgerhold.Query{})
+	if err != nil {
+		// fmt.Printf("Failed to find known users: %v\n", err)
+	}
+	return result
+}
+
+// GetKnownUserKey returns the value stored in the store's key.
+func (st *Storage) GetKnownUserKey(k string) (KnownUser, bool) {
+	result := KnownUser{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find known user: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetKnownUserKey stores value v in key k.
+func (st *Storage) SetKnownUserKey(k string, v KnownUser) {
+	v.JellyfinID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set known user: %v\n", err)
+	}
+}
+
+// DeleteKnownUserKey deletes value at key k.
+func (st *Storage) DeleteKnownUserKey(k string) {
+	st.db.Delete(k, KnownUser{})
+}
+
+// GetRenewalCodes returns a copy of the store.
+func (st *Storage) GetRenewalCodes() []RenewalCode {
+	result := []RenewalCode{}
+	err := st.db.Find(&result, &badgerhold.Query{})
+	if err != nil {
+		// fmt.Printf("Failed to find renewal codes: %v\n", err)
+	}
+	return result
+}
+
+// GetRenewalCodeKey returns the value stored in the store's key.
+func (st *Storage) GetRenewalCodeKey(k string) (RenewalCode, bool) {
+	result := RenewalCode{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find renewal code: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetRenewalCodeKey stores value v in key k.
+func (st *Storage) SetRenewalCodeKey(k string, v RenewalCode) {
+	st.DebugWatch(StoredRenewalCodes, k, "changed")
+	v.Code = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set renewal code: %v\n", err)
+	}
+}
+
+// DeleteRenewalCodeKey deletes value at key k.
+func (st *Storage) DeleteRenewalCodeKey(k string) {
+	st.DebugWatch(StoredRenewalCodes, k, "")
+	st.db.Delete(k, RenewalCode{})
+}
+
+// GetPaymentCustomers returns a copy of the store.
+func (st *Storage) GetPaymentCustomers() []PaymentCustomer {
+	result := []PaymentCustomer{}
+	err := st.db.Find(&result, &badgerhold.Query{})
+	if err != nil {
+		// fmt.Printf("Failed to find payment customers: %v\n", err)
+	}
+	return result
+}
+
+// GetPaymentCustomerKey returns the value stored in the store's key.
+func (st *Storage) GetPaymentCustomerKey(k string) (PaymentCustomer, bool) {
+	result := PaymentCustomer{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find payment customer: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetPaymentCustomerKey stores value v in key k.
+func (st *Storage) SetPaymentCustomerKey(k string, v PaymentCustomer) {
+	v.CustomerID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set payment customer: %v\n", err)
+	}
+}
+
+// DeletePaymentCustomerKey deletes value at key k.
+func (st *Storage) DeletePaymentCustomerKey(k string) {
+	st.db.Delete(k, PaymentCustomer{})
+}
+
+// GetPaymentEventKey returns the value stored in the store's key.
+func (st *Storage) GetPaymentEventKey(k string) (PaymentEvent, bool) {
+	result := PaymentEvent{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find payment event: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetPaymentEventKey stores value v in key k.
+func (st *Storage) SetPaymentEventKey(k string, v PaymentEvent) {
+	v.ID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set payment event: %v\n", err)
+	}
+}
+
+// GetDigestStatus returns when the admin digest was last sent, if it has been.
+func (st *Storage) GetDigestStatus() (DigestStatus, bool) {
+	result := DigestStatus{}
+	err := st.db.Get(DIGEST_STATUS_KEY, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find digest status: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetDigestStatus stores the admin digest's status.
+func (st *Storage) SetDigestStatus(v DigestStatus) {
+	err := st.db.Upsert(DIGEST_STATUS_KEY, v)
+	if err != nil {
+		// fmt.Printf("Failed to set digest status: %v\n", err)
+	}
+}
+
+// GetDeadMessages returns queued messages which have failed to send too many times, oldest first.
+func (st *Storage) GetDeadMessages() []QueuedMessage {
+	result := []QueuedMessage{}
+	err := st.db.Find(&result, badgerhold.Where("Dead").Eq(true).SortBy("Created"))
+	if err != nil {
+		// fmt.Printf("Failed to find dead messages: %v\n", err)
+	}
+	return result
+}
+
+// GetQueuedMessageKey returns the value stored in the store's key.
+func (st *Storage) GetQueuedMessageKey(k string) (QueuedMessage, bool) {
+	result := QueuedMessage{}
+	err := st.db.Get(k, &result)
+	ok := true
+	if err != nil {
+		// fmt.Printf("Failed to find queued message: %v\n", err)
+		ok = false
+	}
+	return result, ok
+}
+
+// SetQueuedMessageKey stores value v in key k.
+func (st *Storage) SetQueuedMessageKey(k string, v QueuedMessage) {
+	v.ID = k
+	err := st.db.Upsert(k, v)
+	if err != nil {
+		// fmt.Printf("Failed to set queued message: %v\n", err)
+	}
+}
+
+// DeleteQueuedMessageKey deletes value at key k.
+func (st *Storage) DeleteQueuedMessageKey(k string) {
+	st.db.Delete(k, QueuedMessage{})
+}
+
 type ThirdPartyService interface {
 	common.ConfigurableTransport
 	// ok implies user imported, err can be any issue that occurs during
@@ -668,6 +1038,23 @@ type DiscordUser struct {
 	JellyfinID    string `json:"-" badgerhold:"key"`
 }
 
+// DiscordDeparture is a linked user who has left or been banned from the Discord server, whose account will be disabled after a grace period.
+type DiscordDeparture struct {
+	JellyfinID string `badgerhold:"key"`
+	DiscordID  string `badgerhold:"index"`
+	Banned     bool
+	Time       time.Time
+	DisableAt  time.Time
+}
+
+// TelegramDeparture is a linked user who has left the Telegram chat required for signup.
+type TelegramDeparture struct {
+	JellyfinID string `badgerhold:"key"`
+	ChatID     int64
+	Time       time.Time
+	Disabled   bool // Whether their account was disabled because of it, so it can be re-enabled if they rejoin.
+}
+
 type TelegramUser struct {
 	TelegramVerifiedToken
 	Lang    string
@@ -682,6 +1069,39 @@ type MatrixUser struct {
 	JellyfinID string `badgerhold:"key"`
 }
 
+type PushUser struct {
+	Topic      string `badgerhold:"index"` // ntfy topic or Gotify application token.
+	Contact    bool
+	JellyfinID string `badgerhold:"key"`
+}
+
+// MessagePreferences holds the message categories a user has turned off on each channel. Anything not listed is sent.
+type MessagePreferences struct {
+	JellyfinID string                     `badgerhold:"key"`
+	Disabled   map[string]map[string]bool // Map of categories to the channels they're disabled on.
+}
+
+// ScheduledAnnouncement is an announcement sent at a later time, and optionally repeated.
+type ScheduledAnnouncement struct {
+	ID         string `badgerhold:"key"`
+	Subject    string
+	Message    string
+	Users      []string            // IDs of users to send to, if Filter is nil.
+	Filter     *ServerFilterReqDTO // If set, sent to all users matching it at the time of sending.
+	Recurrence string              // RecurrenceNone/Daily/Weekly/Monthly.
+	Start      time.Time           // Time of the first send, which recurrences are based on.
+	NextSend   time.Time           // Zero once sent (if not recurring) or cancelled.
+	Created    time.Time
+	History    []AnnouncementSend
+}
+
+// AnnouncementSend records a single send of a ScheduledAnnouncement.
+type AnnouncementSend struct {
+	Time       time.Time
+	Recipients int
+	Failed     int
+}
+
 type EmailAddress struct {
 	Addr                string `badgerhold:"index"`
 	Label               string // User Label.
@@ -689,6 +1109,10 @@ type EmailAddress struct {
 	Admin               bool   // Whether or not user is jfa-go admin.
 	JellyfinID          string `badgerhold:"key"`
 	ReferralTemplateKey string
+	Profile             string // Name of the profile the user was created with, or last had applied.
+	CalendarToken       string `badgerhold:"index"` // Token for the user's expiry calendar feed.
+	Undeliverable       bool   // Set when mail to the address bounces or is reported as spam.
+	UndeliverableReason string
 }
 
 type customEmails struct {
@@ -822,6 +1246,68 @@ func (invite Invite) Source() (ActivitySource, string) {
 	return sourceType, source
 }
 
+// RenewalCode is a code users can redeem on their user page to extend their expiry by a fixed duration.
+type RenewalCode struct {
+	Code          string     `badgerhold:"key"`
+	Created       time.Time  `json:"created"`
+	ValidTill     time.Time  `json:"valid_till"`
+	NoLimit       bool       `json:"no-limit"`
+	RemainingUses int        `json:"remaining-uses"`
+	Months        int        `json:"months,omitempty"`
+	Days          int        `json:"days,omitempty"`
+	Hours         int        `json:"hours,omitempty"`
+	Minutes       int        `json:"minutes,omitempty"`
+	Label         string     `json:"label,omitempty"`
+	UsedBy        [][]string `json:"used-by"` // Jellyfin ID and Unix time of redemption.
+}
+
+// PaymentCustomer maps a customer ID from a payment provider to a Jellyfin user.
+type PaymentCustomer struct {
+	CustomerID string `badgerhold:"key"`
+	JellyfinID string `badgerhold:"index"`
+}
+
+// PaymentEvent records a payment webhook event that has been processed, so retries aren't applied twice.
+type PaymentEvent struct {
+	ID         string    `badgerhold:"key"`
+	Type       string    // Event type, e.g. "checkout.session.completed".
+	Received   time.Time `badgerhold:"index"`
+	JellyfinID string    // User the payment was matched to, if any.
+	Expiry     time.Time // New expiry of the user, if it was extended.
+}
+
+// QueuedMessage is a message waiting to be sent through a single channel, or one that has failed to send too many times.
+type QueuedMessage struct {
+	ID          string `badgerhold:"key"`
+	Channel     string `badgerhold:"index"` // "email", "discord", "telegram", "matrix" or "push".
+	JellyfinID  string // User to send to, looked up again when sending in case their details change.
+	Address     string // Email address to send to, if not sent to a user.
+	Destination string // Address/username at the time of queueing, for display.
+	Message     Message
+	Created     time.Time
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	Dead        bool `badgerhold:"index"`
+}
+
+// MessageLogEntry records an attempt to send a message to someone through a single channel.
+type MessageLogEntry struct {
+	ID          string `badgerhold:"key"`
+	JellyfinID  string `badgerhold:"index"` // Blank if sent to an address not linked to a user.
+	Type        string `badgerhold:"index"` // CustomContentInfo.Name of the message.
+	Channel     string `badgerhold:"index"`
+	Destination string // Address/username the message was sent to.
+	Subject     string
+	Time        time.Time `badgerhold:"index"`
+	Error       string    // Blank if sent successfully.
+}
+
+// DigestStatus stores when the admin digest was last sent.
+type DigestStatus struct {
+	LastSent time.Time
+}
+
 type Captcha struct {
 	Answer    string
 	Image     []byte // image/png
@@ -1382,6 +1868,9 @@ func (st *Storage) loadLangEmail(filesystems ...fs.FS) error {
 					patchLang(&lang.EmailConfirmation, &fallback.EmailConfirmation, &english.EmailConfirmation)
 					patchLang(&lang.UserExpired, &fallback.UserExpired, &english.UserExpired)
 					patchLang(&lang.ExpiryReminder, &fallback.ExpiryReminder, &english.ExpiryReminder)
+					patchLang(&lang.UserAdopted, &fallback.UserAdopted, &english.UserAdopted)
+					patchLang(&lang.AdminDigest, &fallback.AdminDigest, &english.AdminDigest)
+					patchLang(&lang.AdminEvents, &fallback.AdminEvents, &english.AdminEvents)
 					patchLang(&lang.Strings, &fallback.Strings, &english.Strings)
 				}
 			}
@@ -1398,6 +1887,9 @@ func (st *Storage) loadLangEmail(filesystems ...fs.FS) error {
 				patchLang(&lang.EmailConfirmation, &english.EmailConfirmation)
 				patchLang(&lang.UserExpired, &english.UserExpired)
 				patchLang(&lang.ExpiryReminder, &english.ExpiryReminder)
+				patchLang(&lang.UserAdopted, &english.UserAdopted)
+				patchLang(&lang.AdminDigest, &english.AdminDigest)
+				patchLang(&lang.AdminEvents, &english.AdminEvents)
 				patchLang(&lang.Strings, &english.Strings)
 			}
 		}
@@ -1774,3 +2266,12 @@ func (st *Storage) MatrixUsersByID() map[string]MatrixUser {
 	}
 	return out
 }
+
+// PushUsersByID returns a map of jellyfin user IDs to push user entries, if they have one.
+func (st *Storage) PushUsersByID() map[string]PushUser {
+	out := map[string]PushUser{}
+	for _, user := range st.GetPush() {
+		out[user.JellyfinID] = user
+	}
+	return out
+}
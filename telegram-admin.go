@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api"
+	lm "github.com/hrfee/jfa-go/logmessages"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Default number of days /pending looks ahead.
+const TELEGRAM_PENDING_DAYS = 7
+
+// replyString replies to the given update with the named string, logging any error.
+func (t *TelegramDaemon) replyString(upd *tg.Update, lang, key string, vals tmpl) {
+	content := t.app.storage.lang.Telegram[lang].Strings.get(key)
+	if vals != nil {
+		content = t.app.storage.lang.Telegram[lang].Strings.template(key, vals)
+	}
+	t.replyText(upd, content)
+}
+
+func (t *TelegramDaemon) replyText(upd *tg.Update, content string) {
+	if err := t.Reply(upd, content); err != nil {
+		t.app.err.Printf(lm.FailedReply, lm.Telegram, upd.Message.From.UserName, err)
+	}
+}
+
+// chatAdmin returns the Jellyfin ID of the jfa-go admin linked to the chat the update was sent from.
+// Only private chats count, as in a group the chat is shared by every member.
+func (t *TelegramDaemon) chatAdmin(upd *tg.Update) (string, bool) {
+	if !upd.Message.Chat.IsPrivate() {
+		return "", false
+	}
+	users := []TelegramUser{}
+	err := t.app.storage.db.Find(&users, badgerhold.Where("ChatID").Eq(upd.Message.Chat.ID))
+	if err != nil {
+		return "", false
+	}
+	for _, user := range users {
+		if t.app.canAccessAdminPageByID(user.JellyfinID) {
+			return user.JellyfinID, true
+		}
+	}
+	return "", false
+}
+
+// commandAdmin runs the given admin command if the chat is linked to a jfa-go admin.
+func (t *TelegramDaemon) commandAdmin(upd *tg.Update, sects []string, lang string, cmd func(upd *tg.Update, sects []string, lang, adminID string)) {
+	adminID, ok := t.chatAdmin(upd)
+	if !ok {
+		t.app.info.Printf(lm.FailedBotAdminCommand, lm.Telegram, sects[0], upd.Message.From.UserName, fmt.Sprintf(lm.NonAdminUser, upd.Message.From.UserName))
+		t.replyString(upd, lang, "noPermission", nil)
+		return
+	}
+	t.app.info.Printf(lm.BotAdminCommand, lm.Telegram, upd.Message.From.UserName, sects[0])
+	t.app.recordAdminCommand(adminID, strings.Join(sects, " "))
+	cmd(upd, sects, lang, adminID)
+}
+
+// commandInvite creates an invite, with options given as "expiry=<duration>", "profile=<name>" and "label=<user label>".
+func (t *TelegramDaemon) commandInvite(upd *tg.Update, sects []string, lang, adminID string) {
+	opts, errKey, errVals := t.app.parseBotInviteOptions(sects[1:])
+	if errKey != "" {
+		t.replyString(upd, lang, errKey, errVals)
+		return
+	}
+	invite := t.app.createBotInvite(opts, fmt.Sprintf("%s: @%s", lm.Telegram, upd.Message.From.UserName), adminID)
+	t.replyString(upd, lang, "createdInvite", tmpl{
+		"link":    ExternalURI(nil) + PAGES.Form + "/" + invite.Code,
+		"expiry":  formatDatetime(invite.ValidTill),
+		"profile": invite.Profile,
+	})
+}
+
+// commandExtend extends a user's expiry: "/extend <user> <duration>".
+func (t *TelegramDaemon) commandExtend(upd *tg.Update, sects []string, lang, adminID string) {
+	if len(sects) < 3 {
+		t.replyString(upd, lang, "adminHelp", nil)
+		return
+	}
+	user, err := t.app.jf.UserByName(sects[1], false)
+	if err != nil {
+		t.replyString(upd, lang, "userNotFound", tmpl{"user": sects[1]})
+		return
+	}
+	months, days, hours, minutes, ok := parseDurationString(strings.Join(sects[2:], ""))
+	if !ok {
+		t.replyString(upd, lang, "invalidDuration", tmpl{"duration": strings.Join(sects[2:], " ")})
+		return
+	}
+	expiry := t.app.ExtendUserExpiry(user.ID, ExpiryExtension{Months: months, Days: days, Hours: hours, Minutes: minutes})
+	t.app.InvalidateUserCaches()
+	t.replyString(upd, lang, "extendedExpiry", tmpl{"user": user.Name, "expiry": formatDatetime(expiry.Expiry)})
+}
+
+// commandDisable disables a user: "/disable <user>".
+func (t *TelegramDaemon) commandDisable(upd *tg.Update, sects []string, lang, adminID string) {
+	if len(sects) < 2 {
+		t.replyString(upd, lang, "adminHelp", nil)
+		return
+	}
+	user, err := t.app.jf.UserByName(sects[1], false)
+	if err != nil {
+		t.replyString(upd, lang, "userNotFound", tmpl{"user": sects[1]})
+		return
+	}
+	err, _, activityType := t.app.SetUserDisabled(user, true)
+	if err != nil {
+		t.app.err.Printf(lm.FailedApplyTemplate, "policy", lm.Jellyfin, user.ID, err)
+		t.replyString(upd, lang, "commandFailed", nil)
+		return
+	}
+	t.app.recordActivity(Activity{
+		Type:       activityType,
+		UserID:     user.ID,
+		SourceType: ActivityAdmin,
+		Source:     adminID,
+		Time:       time.Now(),
+	}, nil, false)
+	t.app.InvalidateUserCaches()
+	t.replyString(upd, lang, "disabledUser", tmpl{"user": user.Name})
+}
+
+// commandUser shows a summary of a user's account: "/user <name>".
+func (t *TelegramDaemon) commandUser(upd *tg.Update, sects []string, lang, adminID string) {
+	if len(sects) < 2 {
+		t.replyString(upd, lang, "adminHelp", nil)
+		return
+	}
+	jfUser, err := t.app.jf.UserByName(sects[1], false)
+	if err != nil {
+		t.replyString(upd, lang, "userNotFound", tmpl{"user": sects[1]})
+		return
+	}
+	lines := append([]string{jfUser.Name}, t.app.botUserSummary(jfUser, t.app.storage.lang.Telegram[lang].Strings)...)
+	t.replyText(upd, strings.Join(lines, "\n"))
+}
+
+// commandPending lists users expiring soon: "/pending [days]".
+func (t *TelegramDaemon) commandPending(upd *tg.Update, sects []string, lang, adminID string) {
+	days := TELEGRAM_PENDING_DAYS
+	if len(sects) > 1 {
+		if n, err := strconv.Atoi(sects[1]); err == nil && n > 0 {
+			days = n
+		}
+	}
+	expiries := t.app.upcomingExpiries(time.Now(), days)
+	if len(expiries) == 0 {
+		t.replyString(upd, lang, "noPendingExpiries", tmpl{"n": days})
+		return
+	}
+	lines := []string{t.app.storage.lang.Telegram[lang].Strings.template("pendingExpiries", tmpl{"n": days})}
+	for _, e := range expiries {
+		lines = append(lines, "- "+t.app.usernameOrID(e.JellyfinID)+": "+formatDatetime(e.Expiry))
+	}
+	t.replyText(upd, strings.Join(lines, "\n"))
+}
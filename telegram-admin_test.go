@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestTelegramChatAdminRejectsNonAdmins(t *testing.T) {
+	opts := badgerhold.DefaultOptions
+	opts.Dir = t.TempDir()
+	opts.ValueDir = opts.Dir
+	opts.Logger = nil
+	db, err := badgerhold.Open(opts)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	// A group's chat ID linked to an admin, as if an admin had linked a group.
+	if err := db.Upsert("admin", TelegramUser{TelegramVerifiedToken: TelegramVerifiedToken{JellyfinID: "admin", ChatID: -100}}); err != nil {
+		t.Fatalf("failed to store user: %v", err)
+	}
+	td := &TelegramDaemon{app: &appContext{storage: &Storage{db: db}}}
+
+	cases := map[string]*tg.Message{
+		"group": {
+			From: &tg.User{ID: 1, UserName: "member"},
+			Chat: &tg.Chat{ID: -100, Type: "group"},
+		},
+		"unlinked private chat": {
+			From: &tg.User{ID: 2, UserName: "stranger"},
+			Chat: &tg.Chat{ID: 2, Type: "private"},
+		},
+	}
+	for name, msg := range cases {
+		t.Run(name, func(t *testing.T) {
+			if id, ok := td.chatAdmin(&tg.Update{Message: msg}); ok {
+				t.Errorf("got admin %q, want rejection", id)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api"
+	lm "github.com/hrfee/jfa-go/logmessages"
+)
+
+const (
+	TelegramLeftNone    = "none"
+	TelegramLeftNotify  = "notify"
+	TelegramLeftDisable = "disable"
+	// Source of activities for accounts disabled/enabled after their Telegram user left/rejoined the required chat.
+	TELEGRAM_CHAT_SOURCE = "telegramChat"
+)
+
+// telegramChatConfig returns the config for looking up the given user in the given chat, which can be an @username or a numeric ID.
+func telegramChatConfig(chat string, userID int64) (conf tg.ChatConfigWithUser, ok bool) {
+	chat = strings.TrimSpace(chat)
+	if chat == "" || chat == "@" {
+		return
+	}
+	conf.UserID = int(userID)
+	if id, err := strconv.ParseInt(chat, 10, 64); err == nil {
+		conf.ChatID = id
+	} else {
+		conf.SuperGroupUsername = "@" + strings.TrimPrefix(chat, "@")
+	}
+	ok = true
+	return
+}
+
+// isChatMember returns whether the given chat member is in the chat.
+// Restricted members are counted, as the library doesn't tell us whether they've left since being restricted.
+func isChatMember(member tg.ChatMember) bool {
+	switch member.Status {
+	case "creator", "administrator", "member", "restricted":
+		return true
+	}
+	return false
+}
+
+// telegramChatRequired returns whether users must be a member of a Telegram chat to sign up.
+func (app *appContext) telegramChatRequired() bool {
+	return strings.TrimSpace(app.config.Section("telegram").Key("required_chat").String()) != ""
+}
+
+// InRequiredChat returns whether the Telegram user with the given chat ID is in the chat required for signup.
+// Always true if there isn't one.
+func (t *TelegramDaemon) InRequiredChat(chatID int64) (bool, error) {
+	conf, ok := telegramChatConfig(t.app.config.Section("telegram").Key("required_chat").String(), chatID)
+	if !ok {
+		return true, nil
+	}
+	member, err := t.bot.GetChatMember(conf)
+	if err != nil {
+		return false, err
+	}
+	return isChatMember(member), nil
+}
+
+// requiredChatName returns the title of the chat required for signup, or how it was given in the config if it can't be found.
+func (t *TelegramDaemon) requiredChatName() string {
+	chat := strings.TrimSpace(t.app.config.Section("telegram").Key("required_chat").String())
+	conf, ok := telegramChatConfig(chat, 0)
+	if !ok {
+		return chat
+	}
+	info, err := t.bot.GetChat(tg.ChatConfig{ChatID: conf.ChatID, SuperGroupUsername: conf.SuperGroupUsername})
+	if err != nil || info.Title == "" {
+		return chat
+	}
+	return info.Title
+}
+
+// checkTelegramMembership checks every linked Telegram user is still in the required chat. Those who've left are messaged,
+// and with the "disable" action, disabled. Accounts disabled this way are re-enabled if they rejoin, as long as nothing else has disabled them since.
+func (app *appContext) checkTelegramMembership() {
+	if app.telegram == nil || !app.telegramChatRequired() {
+		return
+	}
+	action := app.config.Section("telegram").Key("left_chat_action").In(TelegramLeftNotify, []string{TelegramLeftNone, TelegramLeftNotify, TelegramLeftDisable})
+	chatName := app.telegram.requiredChatName()
+	changed := false
+	for _, user := range app.storage.GetTelegram() {
+		member, err := app.telegram.InRequiredChat(user.ChatID)
+		if err != nil {
+			app.err.Printf(lm.FailedGetTelegramChatMember, user.Username, err)
+			continue
+		}
+		departure, left := app.storage.GetTelegramDepartureKey(user.JellyfinID)
+		if member {
+			if !left {
+				continue
+			}
+			app.storage.DeleteTelegramDepartureKey(user.JellyfinID)
+			app.info.Printf(lm.TelegramUserRejoined, user.Username)
+			// Leave them disabled if anything else has disabled them since.
+			if !departure.Disabled || !app.onlyDisabledBy(user.JellyfinID, TELEGRAM_CHAT_SOURCE) {
+				continue
+			}
+			jfUser, err := app.jf.UserByID(user.JellyfinID, false)
+			if err != nil || !jfUser.Policy.IsDisabled {
+				continue
+			}
+			err, _, activityType := app.SetUserDisabled(jfUser, false)
+			if err != nil {
+				app.err.Printf(lm.FailedApplyTemplate, "policy", lm.Jellyfin, jfUser.ID, err)
+				continue
+			}
+			app.recordActivity(Activity{
+				Type:       activityType,
+				UserID:     jfUser.ID,
+				SourceType: ActivityDaemon,
+				Source:     TELEGRAM_CHAT_SOURCE,
+				Time:       time.Now(),
+			}, nil, false)
+			changed = true
+			continue
+		}
+		if left {
+			continue
+		}
+		departure = TelegramDeparture{ChatID: user.ChatID, Time: time.Now()}
+		app.info.Printf(lm.TelegramUserLeftChat, user.Username, action)
+		key := "leftRequiredChat"
+		if action == TelegramLeftDisable {
+			jfUser, err := app.jf.UserByID(user.JellyfinID, false)
+			if err != nil {
+				app.err.Printf(lm.FailedGetUser, user.JellyfinID, lm.Jellyfin, err)
+			} else if app.disableDepartedUser(jfUser, TELEGRAM_CHAT_SOURCE) {
+				departure.Disabled = true
+				key = "leftRequiredChatDisabled"
+				changed = true
+			}
+		}
+		app.storage.SetTelegramDepartureKey(user.JellyfinID, departure)
+		if action == TelegramLeftNone {
+			continue
+		}
+		lang := user.Lang
+		if _, ok := app.storage.lang.Telegram[lang]; !ok {
+			lang = app.storage.lang.chosenTelegramLang
+		}
+		msg := &Message{Text: app.storage.lang.Telegram[lang].Strings.template(key, tmpl{"chat": chatName})}
+		if err := app.sendByIDVia(msg, MessageCategoryRequired, []string{ChannelTelegram}, user.JellyfinID); err != nil {
+			app.err.Printf(lm.FailedMessage, lm.Telegram, user.Username, err)
+		}
+	}
+	if changed {
+		app.InvalidateUserCaches()
+	}
+}
+
+func newTelegramMembershipDaemon(interval time.Duration, app *appContext) *GenericDaemon {
+	d := NewGenericDaemon(interval, app,
+		func(app *appContext) {
+			app.checkTelegramMembership()
+		},
+	)
+	d.Name("Telegram chat membership")
+	return d
+}
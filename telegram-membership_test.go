@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestTelegramChatConfig(t *testing.T) {
+	type testCase struct {
+		chat     string
+		ok       bool
+		chatID   int64
+		username string
+	}
+	cases := []testCase{
+		{"", false, 0, ""},
+		{"  ", false, 0, ""},
+		{"@", false, 0, ""},
+		{"@mygroup", true, 0, "@mygroup"},
+		{"mygroup", true, 0, "@mygroup"},
+		{" -1001234567890 ", true, -1001234567890, ""},
+	}
+	for _, c := range cases {
+		conf, ok := telegramChatConfig(c.chat, 42)
+		if ok != c.ok {
+			t.Fatalf("%q: ok = %t, want %t", c.chat, ok, c.ok)
+		}
+		if !ok {
+			continue
+		}
+		if conf.ChatID != c.chatID || conf.SuperGroupUsername != c.username || conf.UserID != 42 {
+			t.Fatalf("%q: got %+v", c.chat, conf)
+		}
+	}
+}
+
+func TestIsChatMember(t *testing.T) {
+	for status, want := range map[string]bool{
+		"creator":       true,
+		"administrator": true,
+		"member":        true,
+		"restricted":    true,
+		"left":          false,
+		"kicked":        false,
+		"":              false,
+	} {
+		if got := isChatMember(tg.ChatMember{Status: status}); got != want {
+			t.Fatalf("%q: got %t, want %t", status, got, want)
+		}
+	}
+}
+
+func TestOnlyDisabledBy(t *testing.T) {
+	opts := badgerhold.DefaultOptions
+	opts.Dir = t.TempDir()
+	opts.ValueDir = opts.Dir
+	opts.Logger = nil
+	db, err := badgerhold.Open(opts)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	app := &appContext{storage: &Storage{db: db}}
+	now := time.Now()
+	disabled := func(id, user string, sourceType ActivitySource, source string, when time.Time) {
+		db.Upsert(id, Activity{ID: id, Type: ActivityDisabled, UserID: user, SourceType: sourceType, Source: source, Time: when})
+	}
+
+	disabled("a", "left", ActivityDaemon, TELEGRAM_CHAT_SOURCE, now.Add(-time.Hour))
+
+	disabled("b", "adminAfter", ActivityDaemon, TELEGRAM_CHAT_SOURCE, now.Add(-time.Hour))
+	disabled("c", "adminAfter", ActivityAdmin, "admin", now.Add(-time.Minute))
+
+	disabled("d", "expiredAfter", ActivityDaemon, TELEGRAM_CHAT_SOURCE, now.Add(-time.Hour))
+	disabled("e", "expiredAfter", ActivityDaemon, "", now.Add(-time.Minute))
+
+	disabled("f", "expired", ActivityDaemon, TELEGRAM_CHAT_SOURCE, now.Add(-time.Hour))
+	db.Upsert("expired", UserExpiry{JellyfinID: "expired", Expiry: now.Add(-time.Minute)})
+
+	cases := map[string]bool{
+		"left":         true,
+		"adminAfter":   false,
+		"expiredAfter": false,
+		"expired":      false,
+		"unknown":      false,
+	}
+	for user, want := range cases {
+		if got := app.onlyDisabledBy(user, TELEGRAM_CHAT_SOURCE); got != want {
+			t.Errorf("%s: got %t, want %t", user, got, want)
+		}
+	}
+}
@@ -183,6 +183,21 @@ func (t *TelegramDaemon) run() {
 			case "/lang":
 				t.commandLang(&upd, sects, lang)
 				continue
+			case "/invite":
+				t.commandAdmin(&upd, sects, lang, t.commandInvite)
+				continue
+			case "/extend":
+				t.commandAdmin(&upd, sects, lang, t.commandExtend)
+				continue
+			case "/disable":
+				t.commandAdmin(&upd, sects, lang, t.commandDisable)
+				continue
+			case "/user":
+				t.commandAdmin(&upd, sects, lang, t.commandUser)
+				continue
+			case "/pending":
+				t.commandAdmin(&upd, sects, lang, t.commandPending)
+				continue
 			default:
 				t.commandPIN(&upd, sects, lang)
 			}
@@ -240,6 +255,9 @@ func (t *TelegramDaemon) Shutdown() {
 func (t *TelegramDaemon) commandStart(upd *tg.Update, sects []string, lang string) {
 	content := t.app.storage.lang.Telegram[lang].Strings.get("startMessage") + "\n"
 	content += t.app.storage.lang.Telegram[lang].Strings.template("languageMessage", tmpl{"command": "/lang"})
+	if _, ok := t.chatAdmin(upd); ok {
+		content += "\n\n" + t.app.storage.lang.Telegram[lang].Strings.get("adminHelp")
+	}
 	err := t.Reply(upd, content)
 	if err != nil {
 		t.app.err.Printf(lm.FailedReply, lm.Telegram, upd.Message.From.UserName, err)
@@ -342,8 +360,9 @@ func (t *TelegramDaemon) PIN(req newUserDTO) string { return req.TelegramPIN }
 
 func (t *TelegramDaemon) Name() string { return lm.Telegram }
 
+// Required returns whether users must link Telegram on sign-up, which they must if they have to be in a chat.
 func (t *TelegramDaemon) Required() bool {
-	return t.app.config.Section("telegram").Key("required").MustBool(false)
+	return t.app.config.Section("telegram").Key("required").MustBool(false) || t.app.telegramChatRequired()
 }
 
 func (t *TelegramDaemon) UniqueRequired() bool {
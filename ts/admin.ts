@@ -11,6 +11,11 @@ import { _get, _post, notificationBox, whichAnimationEvent, bindManualDropdowns
 import { Updater } from "./modules/update.js";
 import { Login } from "./modules/login.js";
 import { setupTooltips } from "./modules/ui.js";
+import { RenewalCodeManager } from "./modules/renewals.js";
+import { CalendarFeed } from "./modules/calendar.js";
+import { FailedMessages } from "./modules/failed-messages.js";
+import { MessageLog } from "./modules/message-log.js";
+import { ScheduledAnnouncements } from "./modules/scheduled-announcements.js";
 
 declare var window: GlobalWindow;
 
@@ -86,6 +91,18 @@ window.availableProfiles = window.availableProfiles || [];
 
     window.modals.backups = new Modal(document.getElementById("modal-backups"));
 
+    window.modals.renewals = new Modal(document.getElementById("modal-renewals"));
+
+    window.modals.failedMessages = new Modal(document.getElementById("modal-failed-messages"));
+
+    window.modals.messageLog = new Modal(document.getElementById("modal-message-log"));
+
+    window.modals.scheduledAnnouncements = new Modal(document.getElementById("modal-scheduled-announcements"));
+
+    if (window.calendarFeedEnabled) {
+        window.modals.calendar = new Modal(document.getElementById("modal-calendar"));
+    }
+
     if (window.telegramEnabled) {
         window.modals.telegram = new Modal(document.getElementById("modal-telegram"));
     }
@@ -147,6 +164,17 @@ var settings = new settingsList();
 
 var profiles = new ProfileEditor();
 
+var renewals = new RenewalCodeManager();
+
+var failedMessages = new FailedMessages();
+
+var messageLog = new MessageLog();
+
+var scheduledAnnouncements = new ScheduledAnnouncements();
+
+var calendarFeed: CalendarFeed;
+if (window.calendarFeedEnabled) calendarFeed = new CalendarFeed();
+
 window.notifications = new notificationBox(document.getElementById("notification-box") as HTMLDivElement, 5);
 
 // only use a navigatable URL once
@@ -3,7 +3,7 @@ import { notificationBox, whichAnimationEvent } from "./modules/common.js";
 import { _get, _post, toggleLoader, addLoader, removeLoader, toDateString } from "./modules/common.js";
 import { loadLangSelector } from "./modules/lang.js";
 import { Validator, ValidatorConf, ValidatorRespDTO } from "./modules/validator.js";
-import { Discord, Telegram, Matrix, ServiceConfiguration, MatrixConfiguration } from "./modules/account-linking.js";
+import { Discord, Telegram, Matrix, Push, ServiceConfiguration, MatrixConfiguration } from "./modules/account-linking.js";
 import { Captcha, GreCAPTCHA } from "./modules/captcha.js";
 import { setupTooltips } from "./modules/ui.js";
 
@@ -13,6 +13,7 @@ interface formWindow extends GlobalWindow {
     telegramModal: Modal;
     discordModal: Modal;
     matrixModal: Modal;
+    pushModal: Modal;
     confirmationModal: Modal;
     redirectToJellyfin: boolean;
     code: string;
@@ -25,8 +26,10 @@ interface formWindow extends GlobalWindow {
     discordStartCommand: string;
     discordInviteLink: boolean;
     discordServerName: string;
+    discordOAuthURL: string;
     matrixRequired: boolean;
     matrixUserID: string;
+    pushRequired: boolean;
     userExpiryEnabled: boolean;
     userExpiryMonths: number;
     userExpiryDays: number;
@@ -95,6 +98,7 @@ if (window.discordEnabled) {
         modal: window.discordModal as Modal,
         pin: window.discordPIN,
         inviteURL: window.discordInviteLink ? window.pages.Form + "/" + window.code + "/discord/invite" : "",
+        oauthURL: window.discordOAuthURL,
         pinURL: "",
         verifiedURL: window.pages.Form + "/" + window.code + "/discord/verified/",
         invalidCodeError: window.messages["errorInvalidPIN"],
@@ -154,6 +158,41 @@ if (window.matrixEnabled) {
     };
 }
 
+var pushVerified = false;
+var pushPIN = "";
+if (window.pushEnabled) {
+    window.pushModal = new Modal(document.getElementById("modal-push"), window.pushRequired);
+    const pushButton = document.getElementById("link-push") as HTMLSpanElement;
+
+    const pushConf: MatrixConfiguration = {
+        modal: window.pushModal as Modal,
+        sendMessageURL: window.pages.Form + "/" + window.code + "/push/user",
+        verifiedURL: window.pages.Form + "/" + window.code + "/push/verified/",
+        invalidCodeError: window.messages["errorInvalidPIN"],
+        accountLinkedError: window.messages["errorAccountLinked"],
+        unknownError: window.messages["errorUnknown"],
+        successError: window.messages["verified"],
+        errors: { errorInvalidPushTopic: window.messages["errorInvalidPushTopic"] },
+        successFunc: () => {
+            pushVerified = true;
+            pushPIN = push.pin;
+            pushButton.classList.add("unfocused");
+            document.getElementById("contact-via").classList.remove("unfocused");
+            document.getElementById("contact-via-email").parentElement.classList.remove("unfocused");
+            const checkbox = document.getElementById("contact-via-push") as HTMLInputElement;
+            checkbox.parentElement.classList.remove("unfocused");
+            checkbox.checked = true;
+            validator.validate();
+        },
+    };
+
+    const push = new Push(pushConf);
+
+    pushButton.onclick = () => {
+        push.show();
+    };
+}
+
 if (window.confirmation) {
     window.confirmationModal = new Modal(document.getElementById("modal-confirmation"), true);
 }
@@ -231,6 +270,10 @@ function _baseValidator(oncomplete: (valid: boolean) => void, captchaValid: bool
         oncomplete(false);
         return;
     }
+    if (window.pushEnabled && window.pushRequired && !pushVerified) {
+        oncomplete(false);
+        return;
+    }
     if (window.captcha && !window.reCAPTCHA && !captchaValid) {
         oncomplete(false);
         return;
@@ -269,6 +312,8 @@ interface sendDTO {
     discord_contact?: boolean;
     matrix_pin?: string;
     matrix_contact?: boolean;
+    push_pin?: string;
+    push_contact?: boolean;
     captcha_id?: string;
     captcha_text?: string;
 }
@@ -312,7 +357,14 @@ const create = (event: SubmitEvent) => {
             send.matrix_contact = true;
         }
     }
-    if (matrixVerified || discordVerified || telegramVerified) {
+    if (pushVerified) {
+        send.push_pin = pushPIN;
+        const checkbox = document.getElementById("contact-via-push") as HTMLInputElement;
+        if (checkbox.checked) {
+            send.push_contact = true;
+        }
+    }
+    if (matrixVerified || discordVerified || telegramVerified || pushVerified) {
         const checkbox = document.getElementById("contact-via-email") as HTMLInputElement;
         send.email_contact = checkbox.checked;
     }
@@ -39,6 +39,7 @@ export interface ServiceConfiguration {
     modal: Modal;
     pin: string;
     inviteURL?: string;
+    oauthURL?: string; // Address starting the Discord OAuth2 flow, minus the "pin" parameter.
     pinURL: string;
     verifiedURL: string;
     invalidCodeError: string;
@@ -109,21 +110,23 @@ export class ServiceLinker {
         });
     };
 
+    protected _setPIN(pin: string) {
+        this._pin = pin;
+        this._conf.modal.modal.querySelector(".pin").textContent = this._pin;
+        this._pinAcquired = true;
+    }
+
     onclick() {
         toggleLoader(this._waiting);
 
         this._pinAcquired = false;
         this._pin = "";
         if (this._conf.pin) {
-            this._pinAcquired = true;
-            this._pin = this._conf.pin;
-            this._conf.modal.modal.querySelector(".pin").textContent = this._pin;
+            this._setPIN(this._conf.pin);
         } else if (this._conf.pinURL) {
             _get(this._conf.pinURL, null, (req: XMLHttpRequest) => {
                 if (req.readyState == 4 && req.status == 200) {
-                    this._pin = req.response["pin"];
-                    this._conf.modal.modal.querySelector(".pin").textContent = this._pin;
-                    this._pinAcquired = true;
+                    this._setPIN(req.response["pin"]);
                 }
             });
         }
@@ -160,6 +163,13 @@ export class Discord extends ServiceLinker {
             link.innerHTML = innerHTML;
         });
 
+    protected _setPIN(pin: string) {
+        super._setPIN(pin);
+        if (!this._conf.oauthURL) return;
+        const oauth = document.getElementById("discord-oauth") as HTMLAnchorElement;
+        oauth.href = this._conf.oauthURL + "?pin=" + encodeURIComponent(pin);
+    }
+
     onclick() {
         if (this._conf.inviteURL != "") {
             this._getInviteURL();
@@ -188,16 +198,17 @@ export interface MatrixConfiguration {
     unknownError: string;
     successError: string;
     successFunc: () => void;
+    errors?: { [error: string]: string }; // Messages for other known errors returned when sending the PIN.
 }
 
 export class Matrix {
-    private _conf: MatrixConfiguration;
-    private _verified = false;
-    private _name: string = "matrix";
-    private _userID: string = "";
-    private _pin: string = "";
-    private _input: HTMLInputElement;
-    private _submit: HTMLSpanElement;
+    protected _conf: MatrixConfiguration;
+    protected _verified = false;
+    protected _name: string = "matrix";
+    protected _userID: string = "";
+    protected _pin: string = "";
+    protected _input: HTMLInputElement;
+    protected _submit: HTMLSpanElement;
 
     get verified(): boolean {
         return this._verified;
@@ -206,10 +217,10 @@ export class Matrix {
         return this._pin;
     }
 
-    constructor(conf: MatrixConfiguration) {
+    constructor(conf: MatrixConfiguration, inputID: string = "matrix-userid", submitID: string = "matrix-send") {
         this._conf = conf;
-        this._input = document.getElementById("matrix-userid") as HTMLInputElement;
-        this._submit = document.getElementById("matrix-send") as HTMLSpanElement;
+        this._input = document.getElementById(inputID) as HTMLInputElement;
+        this._submit = document.getElementById(submitID) as HTMLSpanElement;
         this._submit.onclick = () => {
             this._onclick();
         };
@@ -229,14 +240,23 @@ export class Matrix {
         this._conf.modal.show();
     };
 
+    // Request body for sending the PIN.
+    protected _sendBody = (): object => ({ user_id: this._input.value });
+
+    // URL to check the entered PIN.
+    protected _checkURL = (): string => this._conf.verifiedURL + this._userID + "/" + this._input.value;
+
     private _sendMessage = () =>
-        _post(this._conf.sendMessageURL, { user_id: this._input.value }, (req: XMLHttpRequest) => {
+        _post(this._conf.sendMessageURL, this._sendBody(), (req: XMLHttpRequest) => {
             if (req.readyState != 4) return;
             removeLoader(this._submit);
             if (req.status == 400 && req.response["error"] == "errorAccountLinked") {
                 this._conf.modal.close();
                 window.notifications.customError("accountLinkedError", this._conf.accountLinkedError);
                 return;
+            } else if (req.status == 400 && req.response["error"] && this._conf.errors?.[req.response["error"]]) {
+                window.notifications.customError(req.response["error"], this._conf.errors[req.response["error"]]);
+                return;
             } else if (req.status != 200) {
                 this._conf.modal.close();
                 window.notifications.customError("unknownError", this._conf.unknownError);
@@ -254,7 +274,7 @@ export class Matrix {
         });
 
     private _verifyCode = () =>
-        _get(this._conf.verifiedURL + this._userID + "/" + this._input.value, null, (req: XMLHttpRequest) => {
+        _get(this._checkURL(), null, (req: XMLHttpRequest) => {
             if (req.readyState != 4) return;
             removeLoader(this._submit);
             const valid = req.response["success"] as boolean;
@@ -277,3 +297,15 @@ export class Matrix {
             }
         });
 }
+
+// Push links an ntfy topic or Gotify app token. Only the PIN is needed to verify, as it's only sent to the given topic.
+export class Push extends Matrix {
+    constructor(conf: MatrixConfiguration) {
+        super(conf, "push-topic", "push-send");
+        this._name = "push";
+    }
+
+    protected _sendBody = (): object => ({ topic: this._input.value });
+
+    protected _checkURL = (): string => this._conf.verifiedURL + this._input.value;
+}
@@ -13,10 +13,17 @@ import { templateEmail } from "../modules/settings";
 import { Marked } from "@ts-stack/markdown";
 import { stripMarkdown } from "../modules/stripmd";
 import { DiscordUser, newDiscordSearch } from "../modules/discord";
-import { SearchConfiguration, QueryType, SearchableItem, SearchableItemDataAttribute } from "../modules/search";
+import {
+    SearchConfiguration,
+    QueryType,
+    SearchableItem,
+    SearchableItemDataAttribute,
+    ServerFilterReqDTO,
+} from "../modules/search";
 import { HiddenInputField, RadioBasedTabSelector } from "./ui";
 import { PaginatedList } from "./list";
 import { TableRow } from "./row";
+import { MessageLogEntryDTO, messageLogRow } from "./message-log";
 
 declare var window: GlobalWindow;
 
@@ -37,6 +44,7 @@ interface UserDTO {
     name: string;
     email: string | undefined;
     notify_email: boolean;
+    email_undeliverable: boolean;
     last_active: number;
     admin: boolean;
     disabled: boolean;
@@ -48,6 +56,8 @@ interface UserDTO {
     discord_id: string;
     matrix: string;
     notify_matrix: boolean;
+    push: string;
+    notify_push: boolean;
     label: string;
     accounts_admin: boolean;
     referrals_enabled: boolean;
@@ -62,8 +72,23 @@ interface announcementTemplate {
     name: string;
     subject: string;
     message: string;
+    filter?: ServerFilterReqDTO;
 }
 
+// Per-user variables available to announcements, see announcementUserVars in announcements.go.
+const announcementVariables = [
+    "username",
+    "unsubscribeLink",
+    "expiry",
+    "expiryTime",
+    "expiresIn",
+    "label",
+    "email",
+    "referralLink",
+    "myAccountURL",
+    "profile",
+];
+
 var addDiscord: (passData: string) => void;
 
 const queries = (): { [field: string]: QueryType } => {
@@ -144,6 +169,14 @@ const queries = (): { [field: string]: QueryType } => {
             date: false,
             dependsOnElement: ".accounts-header-matrix",
         },
+        push: {
+            name: window.lang.strings("pushNotifications"),
+            getter: "push",
+            bool: true,
+            string: true,
+            date: false,
+            dependsOnElement: ".accounts-header-push",
+        },
         discord: {
             name: "Discord",
             getter: "discord",
@@ -188,6 +221,7 @@ class User extends TableRow implements UserDTO, SearchableItem {
     private _emailEditor: HiddenInputField;
     private _notifyEmail: boolean;
     private _emailAddress: string;
+    private _emailUndeliverable: HTMLSpanElement;
     private _telegram: HTMLTableDataCellElement;
     private _telegramUsername: string;
     private _notifyTelegram: boolean;
@@ -198,6 +232,9 @@ class User extends TableRow implements UserDTO, SearchableItem {
     private _matrix: HTMLTableDataCellElement;
     private _matrixID: string;
     private _notifyMatrix: boolean;
+    private _push: HTMLTableDataCellElement;
+    private _pushTopic: string;
+    private _notifyPush: boolean;
     private _expiry: HTMLTableDataCellElement;
     private _expiryUnix: number;
     private _lastActive: HTMLTableDataCellElement;
@@ -216,11 +253,13 @@ class User extends TableRow implements UserDTO, SearchableItem {
     focus = () => this._row.scrollIntoView({ behavior: "smooth", block: "center" });
 
     lastNotifyMethod = (): string => {
-        // Telegram, Matrix, Discord
+        // Telegram, Matrix, Discord, Push
         const telegram = window.telegramEnabled && this._telegramUsername && this._telegramUsername != "";
         const discord = window.discordEnabled && this._discordUsername && this._discordUsername != "";
         const matrix = window.matrixEnabled && this._matrixID && this._matrixID != "";
+        const push = window.pushEnabled && this._pushTopic && this._pushTopic != "";
         const email = window.emailEnabled && this.email != "";
+        if (push) return "push";
         if (discord) return "discord";
         if (matrix) return "matrix";
         if (telegram) return "telegram";
@@ -314,6 +353,17 @@ class User extends TableRow implements UserDTO, SearchableItem {
         }
     }
 
+    get email_undeliverable(): boolean {
+        return !this._emailUndeliverable.classList.contains("hidden");
+    }
+    set email_undeliverable(state: boolean) {
+        if (state) {
+            this._emailUndeliverable.classList.remove("hidden");
+        } else {
+            this._emailUndeliverable.classList.add("hidden");
+        }
+    }
+
     get notify_email(): boolean {
         return this._notifyEmail;
     }
@@ -341,8 +391,9 @@ class User extends TableRow implements UserDTO, SearchableItem {
         const telegram = this._telegramUsername != "";
         const discord = this._discordUsername != "";
         const matrix = this._matrixID != "";
+        const push = this._pushTopic != "";
         const email = this._emailAddress != "";
-        if (!telegram && !discord && !matrix && !email) return;
+        if (!telegram && !discord && !matrix && !push && !email) return;
         let innerHTML = `
         <i class="icon ri-settings-2-line dropdown-button"></i>
         <div class="dropdown manual over-top">
@@ -373,6 +424,12 @@ class User extends TableRow implements UserDTO, SearchableItem {
                             <span>Matrix</span>
                         </label>
                     </div>
+                    <div class="accounts-area-push">
+                        <label class="row switch flex flex-row gap-2">
+                            <input type="checkbox" name="accounts-contact-${this.id}" class="accounts-contact-push">
+                            <span>${window.lang.strings("pushNotifications")}</span>
+                        </label>
+                    </div>
                     <div class="supra sm accounts-unlink-header">${window.lang.strings("unlink")}:</div>
                     <div class="accounts-unlink-telegram"> 
                         <button class="button ~critical w-full">Telegram</button>
@@ -383,6 +440,9 @@ class User extends TableRow implements UserDTO, SearchableItem {
                     <div class="accounts-unlink-matrix"> 
                         <button class="button ~critical w-full">Matrix</button>
                     </div>
+                    <div class="accounts-unlink-push"> 
+                        <button class="button ~critical w-full">${window.lang.strings("pushNotifications")}</button>
+                    </div>
                 </div>
             </div>
         </div>
@@ -395,7 +455,7 @@ class User extends TableRow implements UserDTO, SearchableItem {
             checks[i].onclick = () => this._setNotifyMethod();
         }
 
-        for (let service of ["telegram", "discord", "matrix"]) {
+        for (let service of ["telegram", "discord", "matrix", "push"]) {
             el.querySelector(".accounts-unlink-" + service).addEventListener("click", () =>
                 _delete(`/users/${service}`, { id: this.id }, () =>
                     document.dispatchEvent(new CustomEvent("accounts-reload")),
@@ -499,6 +559,49 @@ class User extends TableRow implements UserDTO, SearchableItem {
         }
     };
 
+    get push(): string {
+        return this._pushTopic;
+    }
+    set push(u: string) {
+        if (!window.pushEnabled) {
+            this._notifyDropdown.querySelector(".accounts-area-push").classList.add("unfocused");
+            this._notifyDropdown.querySelector(".accounts-unlink-push").classList.add("unfocused");
+            return;
+        }
+        const lastNotifyMethod = this.lastNotifyMethod() == "push";
+        this._pushTopic = u;
+        if (!u) {
+            // Topics can only be linked by the user, as a PIN has to be received.
+            this._notifyDropdown.querySelector(".accounts-area-push").classList.add("unfocused");
+            this._notifyDropdown.querySelector(".accounts-unlink-push").classList.add("unfocused");
+            this._push.textContent = "";
+        } else {
+            this._notifyDropdown.querySelector(".accounts-area-push").classList.remove("unfocused");
+            this._notifyDropdown.querySelector(".accounts-unlink-push").classList.remove("unfocused");
+            this._push.innerHTML = `
+            <div class="accounts-settings-area flex flex-row gap-2 justify-center">
+                <span class="accounts-push-topic"></span>
+            </div>
+            `;
+            (this._push.querySelector(".accounts-push-topic") as HTMLSpanElement).textContent = u;
+            if (lastNotifyMethod) {
+                (this._push.querySelector(".accounts-settings-area") as HTMLDivElement).appendChild(
+                    this._notifyDropdown,
+                );
+            }
+        }
+        this._checkUnlinkArea();
+    }
+
+    get notify_push(): boolean {
+        return this._notifyPush;
+    }
+    set notify_push(s: boolean) {
+        if (this._notifyDropdown) {
+            (this._notifyDropdown.querySelector(".accounts-contact-push") as HTMLInputElement).checked = s;
+        }
+    }
+
     get notify_matrix(): boolean {
         return this._notifyMatrix;
     }
@@ -568,6 +671,10 @@ class User extends TableRow implements UserDTO, SearchableItem {
             )[0] as HTMLInputElement;
             send["discord"] = discord.checked;
         }
+        if (window.pushEnabled && this._pushTopic) {
+            const push = this._notifyDropdown.getElementsByClassName("accounts-contact-push")[0] as HTMLInputElement;
+            send["push"] = push.checked;
+        }
         _post(
             "/users/contact",
             send,
@@ -686,6 +793,7 @@ class User extends TableRow implements UserDTO, SearchableItem {
             this.email.toLowerCase().includes(query) ||
             this.discord.toLowerCase().includes(query) ||
             this.matrix.toLowerCase().includes(query) ||
+            this.push.toLowerCase().includes(query) ||
             this.telegram.toLowerCase().includes(query)
         );
     };
@@ -714,6 +822,7 @@ class User extends TableRow implements UserDTO, SearchableItem {
         innerHTML += `
             <td><div class="flex flex-row gap-2 items-baseline">
                 <span class="accounts-email-container" title="${window.lang.strings("emailAddress")}"></span>
+                <span class="accounts-email-undeliverable chip ~critical hidden" title="${window.lang.strings("emailUndeliverableDescription")}">${window.lang.strings("undeliverable")}</span>
             </div></td>
         `;
         if (window.telegramEnabled) {
@@ -726,6 +835,11 @@ class User extends TableRow implements UserDTO, SearchableItem {
             <td class="accounts-matrix"></td>
             `;
         }
+        if (window.pushEnabled) {
+            innerHTML += `
+            <td class="accounts-push"></td>
+            `;
+        }
         if (window.discordEnabled) {
             innerHTML += `
             <td class="accounts-discord"></td>
@@ -756,6 +870,7 @@ class User extends TableRow implements UserDTO, SearchableItem {
         this._admin = this._row.querySelector(".accounts-admin") as HTMLSpanElement;
         this._disabled = this._row.querySelector(".accounts-disabled") as HTMLSpanElement;
         this._email = this._row.querySelector(".accounts-email-container") as HTMLInputElement;
+        this._emailUndeliverable = this._row.querySelector(".accounts-email-undeliverable") as HTMLSpanElement;
         this._emailEditor = new HiddenInputField({
             container: this._email,
             onSet: this._updateEmail,
@@ -766,6 +881,7 @@ class User extends TableRow implements UserDTO, SearchableItem {
         this._telegram = this._row.querySelector(".accounts-telegram") as HTMLTableDataCellElement;
         this._discord = this._row.querySelector(".accounts-discord") as HTMLTableDataCellElement;
         this._matrix = this._row.querySelector(".accounts-matrix") as HTMLTableDataCellElement;
+        this._push = this._row.querySelector(".accounts-push") as HTMLTableDataCellElement;
         this._expiry = this._row.querySelector(".accounts-expiry") as HTMLTableDataCellElement;
         this._lastActive = this._row.querySelector(".accounts-last-active") as HTMLTableDataCellElement;
         this._label = this._row.querySelector(".accounts-label-container") as HTMLInputElement;
@@ -915,9 +1031,11 @@ class User extends TableRow implements UserDTO, SearchableItem {
         this._discordUsername = user.discord;
         this._telegramUsername = user.telegram;
         this._matrixID = user.matrix;
+        this._pushTopic = user.push || "";
         this.discord = user.discord;
         this.telegram = user.telegram;
         this.matrix = user.matrix;
+        this.push = user.push || "";
         this.last_active = user.last_active;
         this.admin = user.admin;
         this.disabled = user.disabled;
@@ -925,7 +1043,9 @@ class User extends TableRow implements UserDTO, SearchableItem {
         this.notify_discord = user.notify_discord;
         this.notify_telegram = user.notify_telegram;
         this.notify_matrix = user.notify_matrix;
+        this.notify_push = user.notify_push;
         this.notify_email = user.notify_email;
+        this.email_undeliverable = user.email_undeliverable;
         this.discord_id = user.discord_id;
         this.label = user.label;
         this.accounts_admin = user.accounts_admin;
@@ -970,6 +1090,11 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
     private _announcePreview: HTMLElement;
     private _previewLoaded = false;
     private _announceTextarea = document.getElementById("textarea-announce") as HTMLTextAreaElement;
+    private _announceTarget = document.getElementById("announce-target") as HTMLSelectElement;
+    private _announceSendAt = document.getElementById("announce-send-at") as HTMLInputElement;
+    private _announceRecurrence = document.getElementById("announce-recurrence") as HTMLSelectElement;
+    // Search the announcement will be sent to if "Users matching current search" is picked.
+    private _announceFilter: ServerFilterReqDTO | null = null;
     private _deleteUser = document.getElementById("accounts-delete-user") as HTMLSpanElement;
     private _disableEnable = document.getElementById("accounts-disable-enable") as HTMLSpanElement;
     private _enableExpiry = document.getElementById("accounts-enable-expiry") as HTMLSpanElement;
@@ -1354,11 +1479,17 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
         );
 
         this._announceSaveButton.onclick = this.saveAnnouncement;
-        const announceVarUsername = document.getElementById("announce-variables-username") as HTMLSpanElement;
-        announceVarUsername.onclick = () => {
-            insertText(this._announceTextarea, announceVarUsername.children[0].textContent);
-            this.loadPreview();
-        };
+        const announceVars = document.getElementById("announce-variables") as HTMLDivElement;
+        for (let v of announcementVariables) {
+            const button = document.createElement("span") as HTMLSpanElement;
+            button.classList.add("button", "~urge", "@low");
+            button.innerHTML = `<span class="font-mono bg-inherit">{${v}}</span>`;
+            button.onclick = () => {
+                insertText(this._announceTextarea, button.children[0].textContent);
+                this.loadPreview();
+            };
+            announceVars.appendChild(button);
+        }
 
         const headerNames: string[] = [
             "username",
@@ -1366,6 +1497,7 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
             "email",
             "telegram",
             "matrix",
+            "push",
             "discord",
             "expiry",
             "last-active",
@@ -1377,6 +1509,7 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
             "email",
             "telegram",
             "matrix",
+            "push",
             "discord",
             "expiry",
             "last_active",
@@ -1781,6 +1914,7 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
             subject: subject.value,
             message: this._announceTextarea.value,
         };
+        if (this._announceTarget.value == "filter") send.filter = this._announceFilter;
         _post("/users/announce/template", send, (req: XMLHttpRequest) => {
             if (req.readyState == 4) {
                 this.reload();
@@ -1814,6 +1948,15 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
             subject.value = "";
             this._announceTextarea.value = "";
         }
+        this._announceFilter = template?.filter || this._search.serverFilter();
+        document.getElementById("announce-target-users").textContent = window.lang
+            .strings("selectedUsers")
+            .replace("{n}", "" + list.length);
+        (this._announceTarget.querySelector(`option[value="filter"]`) as HTMLOptionElement).disabled =
+            !this._announceFilter;
+        this._announceTarget.value = template?.filter ? "filter" : "users";
+        this._announceSendAt.value = "";
+        this._announceRecurrence.value = "";
         form.onsubmit = (event: Event) => {
             event.preventDefault();
             toggleLoader(button);
@@ -1821,7 +1964,11 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
                 users: list,
                 subject: subject.value,
                 message: this._announceTextarea.value,
+                recurrence: this._announceRecurrence.value,
             };
+            if (this._announceTarget.value == "filter") send["filter"] = this._announceFilter;
+            if (this._announceSendAt.value) send["send_at"] = Math.floor(new Date(this._announceSendAt.value).getTime() / 1000);
+            const scheduled = !!send.recurrence || (send["send_at"] || 0) > Date.now() / 1000;
             _post("/users/announce", send, (req: XMLHttpRequest) => {
                 if (req.readyState == 4) {
                     toggleLoader(button);
@@ -1834,7 +1981,7 @@ export class accountsList extends PaginatedList implements Navigatable, AsTab {
                     } else {
                         window.notifications.customSuccess(
                             "announcementSuccess",
-                            window.lang.notif("sentAnnouncement"),
+                            window.lang.notif(scheduled ? "scheduledAnnouncement" : "sentAnnouncement"),
                         );
                     }
                 }
@@ -2879,6 +3026,7 @@ class UserInfo extends PaginatedList {
     private _rowArea: HTMLElement;
     private _noResults: HTMLElement;
     private _link: HTMLAnchorElement;
+    private _messages: HTMLElement;
     get entries(): Map<string, ActivityLogEntry> {
         return this._search.items as Map<string, ActivityLogEntry>;
     }
@@ -2926,6 +3074,23 @@ class UserInfo extends PaginatedList {
                     <button class="button ~neutral @low accounts-load-all jf-activity-load-all">${window.lang.strings("loadAll")}</button>
                 </div>
             </div>
+            <div class="flex flex-col gap-2">
+                <h3 class="heading text-lg">${window.lang.strings("recentMessages")}</h3>
+                <div class="card @low overflow-x-scroll">
+                    <table class="table text-xs leading-5">
+                        <thead>
+                            <tr>
+                                <th>${window.lang.strings("date")}</th>
+                                <th>${window.lang.strings("channel")}</th>
+                                <th>${window.lang.strings("type")}</th>
+                                <th>${window.lang.strings("subject")}</th>
+                                <th>${window.lang.strings("status")}</th>
+                            </tr>
+                        </thead>
+                        <tbody class="user-info-messages"></tbody>
+                    </table>
+                </div>
+            </div>
         </div>
         `;
         super({
@@ -2993,6 +3158,7 @@ class UserInfo extends PaginatedList {
         this._back = document.getElementById("user-details-back") as HTMLButtonElement;
         this._noResults = this._card.getElementsByClassName("jf-activity-no-activity")[0] as HTMLElement;
         this._link = this._card.getElementsByClassName("jf-activity-jfa-link")[0] as HTMLAnchorElement;
+        this._messages = this._card.getElementsByClassName("user-info-messages")[0] as HTMLElement;
         let searchConfig: SearchConfiguration = {
             queries: {},
             setVisibility: null,
@@ -3039,6 +3205,7 @@ class UserInfo extends PaginatedList {
             this._back.onclick = null;
         }
         this.reload(onLoad);
+        this._loadMessages();
         /*_get("/users/" + jfId + "/activities/jellyfin", null, (req: XMLHttpRequest) => {
             if (req.readyState != 4) return;
             if (req.status != 200) {
@@ -3056,6 +3223,21 @@ class UserInfo extends PaginatedList {
         });*/
     };
 
+    private _loadMessages = () => {
+        this._messages.textContent = ``;
+        _get("/users/" + this.jfId, null, (req: XMLHttpRequest) => {
+            if (req.readyState != 4 || req.status != 200) return;
+            const deliveries = (req.response["deliveries"] || []) as MessageLogEntryDTO[];
+            if (deliveries.length == 0) {
+                this._messages.innerHTML = `<tr><td colspan="5" class="text-center">${window.lang.strings("noMessagesSent")}</td></tr>`;
+                return;
+            }
+            for (let entry of deliveries) {
+                this._messages.appendChild(messageLogRow(entry, false));
+            }
+        });
+    };
+
     get hidden(): boolean {
         return this._hidden;
     }
@@ -40,6 +40,13 @@ var activityTypeMoods = {
     resetPassword: 0,
     createInvite: 1,
     deleteInvite: -1,
+    adopted: 1,
+    downgraded: -1,
+    renewed: 1,
+    payment: 1,
+    emailUndeliverable: -1,
+    adminCommand: 0,
+    discordDeparture: -1,
 };
 
 // window.lang doesn't exist at page load, so I made this a function that's invoked by activityList.
@@ -159,6 +166,55 @@ const queries = (): { [field: string]: QueryType } => {
             string: false,
             date: false,
         },
+        "account-adopted": {
+            name: window.lang.strings("accountAdoptedFilter"),
+            getter: "accountAdopted",
+            bool: true,
+            string: false,
+            date: false,
+        },
+        "account-downgraded": {
+            name: window.lang.strings("accountDowngradedFilter"),
+            getter: "accountDowngraded",
+            bool: true,
+            string: false,
+            date: false,
+        },
+        "account-renewed": {
+            name: window.lang.strings("accountRenewedFilter"),
+            getter: "accountRenewed",
+            bool: true,
+            string: false,
+            date: false,
+        },
+        "payment-received": {
+            name: window.lang.strings("paymentReceivedFilter"),
+            getter: "paymentReceived",
+            bool: true,
+            string: false,
+            date: false,
+        },
+        "email-undeliverable": {
+            name: window.lang.strings("emailUndeliverableFilter"),
+            getter: "emailUndeliverable",
+            bool: true,
+            string: false,
+            date: false,
+        },
+        "admin-command": {
+            name: window.lang.strings("adminCommandFilter"),
+            getter: "adminCommand",
+            bool: true,
+            string: false,
+            date: false,
+        },
+        "discord-departure": {
+            name: window.lang.strings("discordDepartureFilter"),
+            getter: "discordDeparture",
+            bool: true,
+            string: false,
+            date: false,
+        },
     };
 };
 
@@ -233,6 +289,27 @@ export class Activity implements activity, SearchableItem {
     get inviteDeleted(): boolean {
         return this.type == "deleteInvite";
     }
+    get accountAdopted(): boolean {
+        return this.type == "adopted";
+    }
+    get accountDowngraded(): boolean {
+        return this.type == "downgraded";
+    }
+    get accountRenewed(): boolean {
+        return this.type == "renewed";
+    }
+    get paymentReceived(): boolean {
+        return this.type == "payment";
+    }
+    get emailUndeliverable(): boolean {
+        return this.type == "emailUndeliverable";
+    }
+    get adminCommand(): boolean {
+        return this.type == "adminCommand";
+    }
+    get discordDeparture(): boolean {
+        return this.type == "discordDeparture";
+    }
 
     get mentionedUsers(): string {
         return (this.username + " " + this.source_username).toLowerCase();
@@ -337,6 +414,26 @@ export class Activity implements activity, SearchableItem {
             }
 
             this._title.innerHTML = innerHTML.replace("{invite}", this._renderInvText());
+        } else if (this.type == "adopted") {
+            this._title.innerHTML = window.lang.strings("accountAdopted").replace("{user}", this._genUserLink());
+        } else if (this.type == "downgraded") {
+            this._title.innerHTML = window.lang.strings("accountExpired").replace("{user}", this._genUserLink());
+            this._expiryTypeBadge.classList.remove("unfocused");
+            this._expiryTypeBadge.classList.add("~info");
+            this._expiryTypeBadge.classList.remove("~critical");
+            this._expiryTypeBadge.textContent = window.lang.strings("downgradedTo").replace("{profile}", this.value);
+        } else if (this.type == "renewed") {
+            this._title.innerHTML = window.lang.strings("accountRenewed").replace("{user}", this._genUserLink());
+        } else if (this.type == "payment") {
+            this._title.innerHTML = window.lang.strings("paymentReceived").replace("{user}", this._genUserLink());
+        } else if (this.type == "emailUndeliverable") {
+            this._title.innerHTML = window.lang.strings("emailUndeliverable").replace("{user}", this._genUserLink());
+        } else if (this.type == "adminCommand") {
+            this._title.textContent = window.lang.strings("adminCommand").replace("{command}", this.value);
+        } else if (this.type == "discordDeparture") {
+            let innerHTML = window.lang.strings("discordLeft");
+            if (this.value == "banned") innerHTML = window.lang.strings("discordBanned");
+            this._title.innerHTML = innerHTML.replace("{user}", this._genUserLink());
         }
     }
 
@@ -0,0 +1,44 @@
+import { _get, _post, addLoader, removeLoader, SetupCopyButton } from "./common.js";
+
+declare var window: GlobalWindow;
+
+export class CalendarFeed {
+    private _link = document.getElementById("calendar-link") as HTMLInputElement;
+    private _reset = document.getElementById("calendar-reset") as HTMLSpanElement;
+
+    private _setToken = (token: string) => {
+        this._link.value = window.pages.ExternalURI + "/calendar/expiries/" + token + ".ics";
+    };
+
+    load = () =>
+        _get("/calendar/token", null, (req: XMLHttpRequest) => {
+            if (req.readyState != 4 || req.status != 200) return;
+            this._setToken(req.response["token"] as string);
+        });
+
+    constructor() {
+        SetupCopyButton(document.getElementById("calendar-copy") as HTMLButtonElement, () => this._link.value);
+        this._reset.onclick = () => {
+            addLoader(this._reset);
+            _post(
+                "/calendar/token",
+                null,
+                (req: XMLHttpRequest) => {
+                    if (req.readyState != 4) return;
+                    removeLoader(this._reset);
+                    if (req.status != 200) {
+                        window.notifications.customError("errorUnknown", window.lang.notif("errorUnknown"));
+                        return;
+                    }
+                    this._setToken(req.response["token"] as string);
+                    window.notifications.customSuccess("calendarLinkReset", window.lang.notif("calendarLinkReset"));
+                },
+                true,
+            );
+        };
+        document.getElementById("accounts-calendar").onclick = () => {
+            this.load();
+            window.modals.calendar.show();
+        };
+    }
+}
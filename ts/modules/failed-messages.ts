@@ -0,0 +1,87 @@
+import { _get, _post, _delete, toDateString } from "./common.js";
+
+declare var window: GlobalWindow;
+
+interface QueuedMessageDTO {
+    id: string;
+    channel: string;
+    user_id: string;
+    username: string;
+    destination: string;
+    subject: string;
+    created: number;
+    attempts: number;
+    last_error: string;
+}
+
+export class FailedMessages {
+    private _list = document.getElementById("failed-messages-list") as HTMLTableSectionElement;
+    private _pending = document.getElementById("failed-messages-pending") as HTMLParagraphElement;
+
+    load = () =>
+        _get("/messages/failed", null, (req: XMLHttpRequest) => {
+            if (req.readyState != 4 || req.status != 200) return;
+            const messages = req.response["messages"] as QueuedMessageDTO[];
+            this._pending.textContent = window.lang.var("strings", "messagesPending", `${req.response["pending"]}`);
+            this._list.textContent = ``;
+            if (messages.length == 0) {
+                this._list.innerHTML = `<tr><td colspan="6" class="text-center">${window.lang.strings("noFailedMessages")}</td></tr>`;
+                return;
+            }
+            for (let msg of messages) {
+                const tr = document.createElement("tr") as HTMLTableRowElement;
+                tr.classList.add("align-middle");
+                tr.innerHTML = `
+                <td class="whitespace-nowrap">${toDateString(new Date(msg.created * 1000))}</td>
+                <td><div class="flex flex-col">
+                    <span class="font-bold failed-messages-username"></span>
+                    <span class="flex flex-row gap-1"><span class="chip ~neutral @low">${msg.channel}</span><span class="failed-messages-destination"></span></span>
+                </div></td>
+                <td class="failed-messages-subject"></td>
+                <td>${msg.attempts}</td>
+                <td class="failed-messages-error font-mono"></td>
+                <td><div class="flex flex-row gap-2 justify-center">
+                    <button class="button ~info @low failed-messages-retry" title="${window.lang.strings("retry")}"><i class="icon ri-restart-line"></i></button>
+                    <button class="button ~critical @low failed-messages-delete" title="${window.lang.strings("delete")}"><i class="icon ri-delete-bin-line"></i></button>
+                </div></td>
+                `;
+                // Set with textContent, as these can come from users.
+                tr.querySelector(".failed-messages-username").textContent = msg.username || "";
+                tr.querySelector(".failed-messages-destination").textContent = msg.destination;
+                tr.querySelector(".failed-messages-subject").textContent = msg.subject;
+                tr.querySelector(".failed-messages-error").textContent = msg.last_error;
+                (tr.querySelector(".failed-messages-retry") as HTMLButtonElement).onclick = () =>
+                    _post("/messages/failed/" + msg.id, null, (req: XMLHttpRequest) => {
+                        if (req.readyState != 4) return;
+                        if (req.status != 200) {
+                            window.notifications.customError("errorUnknown", window.lang.notif("errorUnknown"));
+                        } else {
+                            window.notifications.customSuccess("messageRequeued", window.lang.notif("messageRequeued"));
+                        }
+                        this.load();
+                    });
+                (tr.querySelector(".failed-messages-delete") as HTMLButtonElement).onclick = () =>
+                    _delete("/messages/failed/" + msg.id, null, (req: XMLHttpRequest) => {
+                        if (req.readyState != 4) return;
+                        this.load();
+                    });
+                this._list.appendChild(tr);
+            }
+        });
+
+    constructor() {
+        document.getElementById("failed-messages-refresh").onclick = this.load;
+        document.getElementById("failed-messages-retry-all").onclick = () =>
+            _post("/messages/failed", null, (req: XMLHttpRequest) => {
+                if (req.readyState != 4) return;
+                if (req.status == 200) {
+                    window.notifications.customSuccess("messagesRequeued", window.lang.notif("messagesRequeued"));
+                }
+                this.load();
+            });
+        document.getElementById("settings-failed-messages").onclick = () => {
+            this.load();
+            window.modals.failedMessages.show();
+        };
+    }
+}
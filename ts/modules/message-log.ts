@@ -0,0 +1,127 @@
+import { _post, toDateString } from "./common.js";
+
+declare var window: GlobalWindow;
+
+export interface MessageLogEntryDTO {
+    id: string;
+    user_id: string;
+    username: string;
+    type: string;
+    channel: string;
+    destination: string;
+    subject: string;
+    time: number;
+    error: string;
+}
+
+interface MessageLogReqDTO {
+    limit: number;
+    page: number;
+    search: string;
+    type: string;
+    channel: string;
+    failed: boolean;
+}
+
+// messageLogRow renders a delivery log entry as a table row, with columns for date, recipient (if showRecipient), type, subject and status.
+export const messageLogRow = (entry: MessageLogEntryDTO, showRecipient: boolean = true): HTMLTableRowElement => {
+    const tr = document.createElement("tr") as HTMLTableRowElement;
+    tr.classList.add("align-middle");
+    const status = entry.error
+        ? `<span class="chip ~critical @low message-log-error">${window.lang.strings("failed")}</span>`
+        : `<span class="chip ~positive @low">${window.lang.strings("sent")}</span>`;
+    tr.innerHTML = `
+    <td class="whitespace-nowrap">${toDateString(new Date(entry.time * 1000))}</td>
+    ${
+        showRecipient
+            ? `<td><div class="flex flex-col">
+        <span class="font-bold message-log-username"></span>
+        <span class="flex flex-row gap-1"><span class="chip ~neutral @low">${entry.channel}</span><span class="message-log-destination"></span></span>
+    </div></td>`
+            : `<td><span class="chip ~neutral @low">${entry.channel}</span></td>`
+    }
+    <td class="message-log-type"></td>
+    <td class="message-log-subject"></td>
+    <td>${status}</td>
+    `;
+    // Set with textContent, as these can come from users.
+    if (showRecipient) {
+        tr.querySelector(".message-log-username").textContent = entry.username || "";
+        tr.querySelector(".message-log-destination").textContent = entry.destination;
+    }
+    tr.querySelector(".message-log-type").textContent = entry.type;
+    tr.querySelector(".message-log-subject").textContent = entry.subject;
+    if (entry.error) tr.querySelector(".message-log-error").setAttribute("title", entry.error);
+    return tr;
+};
+
+export class MessageLog {
+    private _list = document.getElementById("message-log-list") as HTMLTableSectionElement;
+    private _search = document.getElementById("message-log-search") as HTMLInputElement;
+    private _channel = document.getElementById("message-log-channel") as HTMLSelectElement;
+    private _type = document.getElementById("message-log-type") as HTMLSelectElement;
+    private _failed = document.getElementById("message-log-failed") as HTMLInputElement;
+    private _loadMore = document.getElementById("message-log-load-more") as HTMLButtonElement;
+    private _page = 0;
+    private _limit = 50;
+
+    private _setTypes = (types: string[]) => {
+        const selected = this._type.value;
+        let innerHTML = `<option value="">${window.lang.strings("allTypes")}</option>`;
+        for (let t of types) {
+            innerHTML += `<option value="${t}">${t}</option>`;
+        }
+        this._type.innerHTML = innerHTML;
+        if (types.includes(selected)) this._type.value = selected;
+    };
+
+    load = (append: boolean = false) => {
+        if (!append) this._page = 0;
+        const req: MessageLogReqDTO = {
+            limit: this._limit,
+            page: this._page,
+            search: this._search.value,
+            type: this._type.value,
+            channel: this._channel.value,
+            failed: this._failed.checked,
+        };
+        _post("/messages/log", req, (req: XMLHttpRequest) => {
+            if (req.readyState != 4) return;
+            if (req.status != 200) {
+                window.notifications.customError("errorUnknown", window.lang.notif("errorUnknown"));
+                return;
+            }
+            const entries = req.response["entries"] as MessageLogEntryDTO[];
+            this._setTypes(req.response["types"] as string[]);
+            this._loadMore.classList.toggle("unfocused", req.response["last_page"] as boolean);
+            if (!append) this._list.textContent = ``;
+            if (!append && entries.length == 0) {
+                this._list.innerHTML = `<tr><td colspan="5" class="text-center">${window.lang.strings("noMessagesSent")}</td></tr>`;
+                return;
+            }
+            for (let entry of entries) {
+                this._list.appendChild(messageLogRow(entry));
+            }
+        }, true);
+    };
+
+    constructor() {
+        document.getElementById("message-log-refresh").onclick = () => this.load();
+        this._channel.onchange = () => this.load();
+        this._type.onchange = () => this.load();
+        this._failed.onchange = () => this.load();
+        let timeout: ReturnType<typeof setTimeout>;
+        this._search.oninput = () => {
+            clearTimeout(timeout);
+            timeout = setTimeout(() => this.load(), 500);
+        };
+        this._loadMore.onclick = () => {
+            this._page++;
+            this.load(true);
+        };
+        document.getElementById("settings-message-log").onclick = () => {
+            this.load();
+            window.modals.messageLog.show();
+        };
+    }
+}
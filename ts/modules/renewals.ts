@@ -0,0 +1,126 @@
+import { _get, _post, _delete, toDateString, addLoader, removeLoader, SetupCopyButton } from "./common.js";
+
+declare var window: GlobalWindow;
+
+interface RenewalCode {
+    code: string;
+    created: number;
+    valid_till: number;
+    no_limit: boolean;
+    remaining_uses: number;
+    months: number;
+    days: number;
+    hours: number;
+    minutes: number;
+    label?: string;
+    used_by?: { [name: string]: number };
+}
+
+export class RenewalCodeManager {
+    private _form = document.getElementById("form-renewals") as HTMLFormElement;
+    private _submit = this._form.querySelector("span.submit") as HTMLSpanElement;
+    private _list = document.getElementById("renewals-list") as HTMLTableSectionElement;
+    private _noLimit = document.getElementById("renewals-no-limit") as HTMLInputElement;
+    private _uses = document.getElementById("renewals-uses") as HTMLInputElement;
+    private _label = document.getElementById("renewals-label") as HTMLInputElement;
+
+    private _value = (id: string): number => +(document.getElementById(id) as HTMLInputElement).value;
+
+    private _renderDuration = (code: RenewalCode): string => {
+        const parts: string[] = [];
+        if (code.months) parts.push(`${code.months} ${window.lang.strings("inviteMonths")}`);
+        if (code.days) parts.push(`${code.days} ${window.lang.strings("inviteDays")}`);
+        if (code.hours) parts.push(`${code.hours} ${window.lang.strings("inviteHours")}`);
+        if (code.minutes) parts.push(`${code.minutes} ${window.lang.strings("inviteMinutes")}`);
+        return parts.join(", ");
+    };
+
+    private _renderUsedBy = (code: RenewalCode): string => {
+        if (!code.used_by) return `0`;
+        const names = Object.keys(code.used_by).sort((a, b) => code.used_by[a] - code.used_by[b]);
+        return names
+            .map((name: string) => `<span title="${toDateString(new Date(code.used_by[name] * 1000))}">${name}</span>`)
+            .join(", ");
+    };
+
+    load = () =>
+        _get("/renewals", null, (req: XMLHttpRequest) => {
+            if (req.readyState != 4 || req.status != 200) return;
+            const codes = (req.response["codes"] as RenewalCode[]).sort((a, b) => b.created - a.created);
+            this._list.textContent = ``;
+            if (codes.length == 0) {
+                this._list.innerHTML = `<tr><td colspan="7" class="text-center">${window.lang.strings("renewalNoCodes")}</td></tr>`;
+                return;
+            }
+            for (let code of codes) {
+                const tr = document.createElement("tr") as HTMLTableRowElement;
+                tr.classList.add("align-middle");
+                tr.innerHTML = `
+                <td class="whitespace-nowrap"><span class="text-black dark:text-white font-mono bg-inherit">${code.code}</span> <button class="renewal-copy m-2"></button></td>
+                <td>${code.label || ""}</td>
+                <td>${this._renderDuration(code)}</td>
+                <td>${code.no_limit ? "∞" : code.remaining_uses}</td>
+                <td>${toDateString(new Date(code.valid_till * 1000))}</td>
+                <td>${this._renderUsedBy(code)}</td>
+                <td><span class="renewal-delete button ~critical @low" title="${window.lang.strings("delete")}"><i class="icon ri-delete-bin-line"></i></span></td>
+                `;
+                SetupCopyButton(tr.querySelector(".renewal-copy"), code.code);
+                tr.querySelector(".renewal-delete").addEventListener("click", () =>
+                    _delete("/renewals", { codes: [code.code] }, (req: XMLHttpRequest) => {
+                        if (req.readyState != 4) return;
+                        this.load();
+                    }),
+                );
+                this._list.appendChild(tr);
+            }
+        });
+
+    private _generate = (event: Event) => {
+        event.preventDefault();
+        const send = {
+            count: this._value("renewals-count"),
+            months: this._value("renewals-months"),
+            days: this._value("renewals-days"),
+            hours: this._value("renewals-hours"),
+            minutes: this._value("renewals-minutes"),
+            valid_months: this._value("renewals-valid-months"),
+            valid_days: this._value("renewals-valid-days"),
+            no_limit: this._noLimit.checked,
+            remaining_uses: +this._uses.value,
+            label: this._label.value,
+        };
+        addLoader(this._submit);
+        _post(
+            "/renewals",
+            send,
+            (req: XMLHttpRequest) => {
+                if (req.readyState != 4) return;
+                removeLoader(this._submit);
+                if (req.status == 400) {
+                    window.notifications.customError("errorRenewalCodes", window.lang.notif("errorRenewalCodes"));
+                    return;
+                } else if (req.status != 200) {
+                    window.notifications.customError("errorRenewalCodes", window.lang.notif("errorUnknown"));
+                    return;
+                }
+                window.notifications.customSuccess(
+                    "renewalCodesGenerated",
+                    window.lang.var("notifications", "renewalCodesGenerated", `${send.count}`),
+                );
+                this.load();
+            },
+            true,
+        );
+    };
+
+    constructor() {
+        this._form.onsubmit = this._generate;
+        this._noLimit.onchange = () => {
+            this._uses.disabled = this._noLimit.checked;
+        };
+        document.getElementById("invites-renewals").onclick = () => {
+            this.load();
+            window.modals.renewals.show();
+        };
+    }
+}
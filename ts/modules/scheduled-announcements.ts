@@ -0,0 +1,102 @@
+import { _get, _delete, toDateString } from "./common.js";
+import { ServerFilterReqDTO } from "./search.js";
+
+declare var window: GlobalWindow;
+
+interface AnnouncementSendDTO {
+    time: number;
+    recipients: number;
+    failed: number;
+}
+
+interface ScheduledAnnouncementDTO {
+    id: string;
+    subject: string;
+    message: string;
+    users: string[];
+    filter?: ServerFilterReqDTO;
+    recurrence: string;
+    next_send: number;
+    created: number;
+    history: AnnouncementSendDTO[];
+}
+
+// filterString roughly renders a stored search as it would be typed in the search bar.
+const filterString = (filter: ServerFilterReqDTO): string => {
+    let parts = [...(filter.searchTerms || [])];
+    for (let q of filter.queries || []) {
+        parts.push(`${q.field}:${typeof q.value == "object" ? "…" : q.value}`);
+    }
+    return parts.join(" ");
+};
+
+const recurrenceStrings: { [recurrence: string]: string } = {
+    "": "repeatNever",
+    daily: "repeatDaily",
+    weekly: "repeatWeekly",
+    monthly: "repeatMonthly",
+};
+
+export class ScheduledAnnouncements {
+    private _list = document.getElementById("scheduled-announcements-list") as HTMLTableSectionElement;
+    private _empty = document.getElementById("scheduled-announcements-empty") as HTMLParagraphElement;
+
+    private _row = (a: ScheduledAnnouncementDTO): HTMLTableRowElement => {
+        const tr = document.createElement("tr") as HTMLTableRowElement;
+        tr.classList.add("align-middle");
+        const last = a.history.length != 0 ? a.history[a.history.length - 1] : null;
+        tr.innerHTML = `
+        <td class="scheduled-announcement-subject"></td>
+        <td class="scheduled-announcement-target"></td>
+        <td class="whitespace-nowrap">${a.next_send ? toDateString(new Date(a.next_send * 1000)) : "-"}</td>
+        <td>${window.lang.strings(recurrenceStrings[a.recurrence] || "repeatNever")}</td>
+        <td>${
+            last
+                ? `<div class="flex flex-col"><span class="whitespace-nowrap">${toDateString(new Date(last.time * 1000))}</span>
+                <span class="support">${window.lang.strings("sentToCount").replace("{n}", "" + last.recipients).replace("{failed}", "" + last.failed)}</span></div>`
+                : "-"
+        }</td>
+        <td>${a.next_send ? `<button class="button ~critical @low scheduled-announcement-cancel">${window.lang.strings("cancelAnnouncement")}</button>` : ""}</td>
+        `;
+        // Set with textContent, as these are typed by admins.
+        tr.querySelector(".scheduled-announcement-subject").textContent = a.subject;
+        tr.querySelector(".scheduled-announcement-target").textContent = a.filter
+            ? window.lang.strings("usersMatchingFilter").replace("{filter}", filterString(a.filter))
+            : window.lang.strings("selectedUsers").replace("{n}", "" + (a.users || []).length);
+        const cancel = tr.querySelector(".scheduled-announcement-cancel") as HTMLButtonElement;
+        if (cancel)
+            cancel.onclick = () =>
+                _delete("/users/announce/scheduled/" + a.id, null, (req: XMLHttpRequest) => {
+                    if (req.readyState != 4) return;
+                    if (req.status != 200) {
+                        window.notifications.customError("announcementError", window.lang.notif("errorFailureCheckLogs"));
+                        return;
+                    }
+                    window.notifications.customSuccess("announcementSuccess", window.lang.notif("cancelledAnnouncement"));
+                    this.load();
+                });
+        return tr;
+    };
+
+    load = () =>
+        _get("/users/announce/scheduled", null, (req: XMLHttpRequest) => {
+            if (req.readyState != 4) return;
+            if (req.status != 200) {
+                window.notifications.customError("errorUnknown", window.lang.notif("errorUnknown"));
+                return;
+            }
+            const announcements = req.response["announcements"] as ScheduledAnnouncementDTO[];
+            this._list.textContent = ``;
+            this._empty.classList.toggle("unfocused", announcements.length != 0);
+            for (let a of announcements) {
+                this._list.appendChild(this._row(a));
+            }
+        });
+
+    constructor() {
+        document.getElementById("announce-show-scheduled").onclick = () => {
+            this.load();
+            window.modals.scheduledAnnouncements.show();
+        };
+    }
+}
@@ -51,6 +51,11 @@ export interface SearchConfiguration {
     loadMore?: () => void;
 }
 
+export interface ServerFilterReqDTO {
+    searchTerms: string[];
+    queries: QueryDTO[];
+}
+
 export interface ServerSearchReqDTO extends PaginatedReqDTO {
     searchTerms: string[];
     queries: QueryDTO[];
@@ -722,6 +727,13 @@ export class Search implements Navigatable {
         return req;
     };
 
+    // serverFilter returns the current search in a form the server can re-run, or null if not searching.
+    serverFilter = (): ServerFilterReqDTO | null => {
+        if (!this.inSearch) return null;
+        const params = this.serverSearchParams(this._searchTerms, this._queries) as ServerSearchReqDTO;
+        return { searchTerms: params.searchTerms, queries: params.queries };
+    };
+
     private _qps: URLSearchParams = new URLSearchParams();
     private _clearWithoutNavigate = false;
     // clearQueryParam removes the "search" query parameter --without-- triggering a navigate call.
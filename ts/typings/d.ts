@@ -38,6 +38,7 @@ declare interface GlobalWindow extends Window {
     telegramEnabled: boolean;
     discordEnabled: boolean;
     matrixEnabled: boolean;
+    pushEnabled: boolean;
     ombiEnabled: boolean;
     jellyseerrEnabled: boolean;
     pwrEnabled: boolean;
@@ -58,6 +59,8 @@ declare interface GlobalWindow extends Window {
     jfAdminOnly: boolean;
     jfAllowAll: boolean;
     referralsEnabled: boolean;
+    renewalCodesEnabled: boolean;
+    calendarFeedEnabled: boolean;
     loginAppearance: string;
 }
 
@@ -173,6 +176,7 @@ declare interface Modals {
     telegram: Modal;
     discord: Modal;
     matrix: Modal;
+    push?: Modal;
     sendPWR?: Modal;
     pwr?: Modal;
     logs: Modal;
@@ -182,6 +186,11 @@ declare interface Modals {
     enableReferralsProfile?: Modal;
     backedUp?: Modal;
     backups?: Modal;
+    renewals?: Modal;
+    calendar?: Modal;
+    failedMessages?: Modal;
+    messageLog?: Modal;
+    scheduledAnnouncements?: Modal;
 }
 
 declare interface Page {
@@ -11,9 +11,10 @@ import {
     addLoader,
     removeLoader,
     toClipboard,
+    SetupCopyButton,
 } from "./modules/common.js";
 import { Login } from "./modules/login.js";
-import { Discord, Telegram, Matrix, ServiceConfiguration, MatrixConfiguration } from "./modules/account-linking.js";
+import { Discord, Telegram, Matrix, Push, ServiceConfiguration, MatrixConfiguration } from "./modules/account-linking.js";
 import { Validator, ValidatorConf, ValidatorRespDTO } from "./modules/validator.js";
 import { PageManager } from "./modules/pages.js";
 import { generateCodeLink } from "./modules/invites.js";
@@ -26,11 +27,15 @@ interface userWindow extends GlobalWindow {
     discordRequired: boolean;
     telegramRequired: boolean;
     matrixRequired: boolean;
+    pushRequired: boolean;
     discordServerName: string;
     discordInviteLink: boolean;
+    discordOAuthURL: string;
     matrixUserID: string;
     discordSendPINMessage: string;
     referralsEnabled: boolean;
+    renewalCodesEnabled: boolean;
+    calendarFeedEnabled: boolean;
 }
 
 declare var window: userWindow;
@@ -85,6 +90,9 @@ pages.setPage({
     if (window.matrixEnabled) {
         window.modals.matrix = new Modal(document.getElementById("modal-matrix"), false);
     }
+    if (window.pushEnabled) {
+        window.modals.push = new Modal(document.getElementById("modal-push"), false);
+    }
     if (window.pwrEnabled) {
         window.modals.pwr = new Modal(document.getElementById("modal-pwr"), false);
         pages.setPage({
@@ -149,6 +157,10 @@ interface MyDetailsContactMethod {
     enabled: boolean;
 }
 
+interface MessagePreferencesDTO {
+    [category: string]: { [channel: string]: boolean };
+}
+
 interface MyDetails {
     id: string;
     username: string;
@@ -160,7 +172,10 @@ interface MyDetails {
     discord?: MyDetailsContactMethod;
     telegram?: MyDetailsContactMethod;
     matrix?: MyDetailsContactMethod;
+    push?: MyDetailsContactMethod;
     has_referrals: boolean;
+    calendar_token?: string;
+    message_preferences: MessagePreferencesDTO;
 }
 
 interface MyReferral {
@@ -176,6 +191,7 @@ interface ContactDTO {
     discord?: boolean;
     telegram?: boolean;
     matrix?: boolean;
+    push?: boolean;
 }
 
 class ContactMethods {
@@ -309,6 +325,67 @@ class ContactMethods {
     };
 }
 
+class MessagePreferences {
+    private _card: HTMLElement;
+    private _header: HTMLElement;
+    private _list: HTMLElement;
+    private _prefs: MessagePreferencesDTO;
+    private _categoryNames: { [category: string]: string } = {
+        announcements: "messageCategoryAnnouncements",
+        expiry: "messageCategoryExpiry",
+        account: "messageCategoryAccount",
+    };
+
+    constructor(card: HTMLElement) {
+        this._card = card;
+        this._header = this._card.querySelector(".preferences-header");
+        this._list = this._card.querySelector(".preferences-list");
+    }
+
+    // load shows a checkbox for each category on each of the given channels (those the user has linked and enabled).
+    load = (prefs: MessagePreferencesDTO, channels: { name: string; icon: string }[]) => {
+        this._prefs = prefs;
+        if (channels.length == 0) {
+            this._card.classList.add("unfocused");
+            return;
+        }
+        this._card.classList.remove("unfocused");
+        this._header.innerHTML = `<th></th>`;
+        for (let channel of channels) {
+            this._header.innerHTML += `<th class="text-center" title="${channel.name}">${channel.icon}</th>`;
+        }
+        this._list.textContent = ``;
+        for (let category of Object.keys(this._categoryNames)) {
+            if (!(category in prefs)) continue;
+            const tr = document.createElement("tr");
+            tr.innerHTML = `<td class="font-bold">${window.lang.strings(this._categoryNames[category])}</td>`;
+            for (let channel of channels) {
+                const td = document.createElement("td");
+                td.classList.add("text-center");
+                const checkbox = document.createElement("input");
+                checkbox.type = "checkbox";
+                checkbox.checked = prefs[category][channel.name];
+                checkbox.onchange = () => {
+                    this._prefs[category][channel.name] = checkbox.checked;
+                    this._save();
+                };
+                td.appendChild(checkbox);
+                tr.appendChild(td);
+            }
+            this._list.appendChild(tr);
+        }
+    };
+
+    private _save = () =>
+        _post("/my/preferences", this._prefs, (req: XMLHttpRequest) => {
+            if (req.readyState != 4) return;
+            if (req.status != 200) {
+                window.notifications.customError("errorSetPreferences", window.lang.notif("errorSaveSettings"));
+                document.dispatchEvent(new CustomEvent("details-reload"));
+            }
+        });
+}
+
 class ReferralCard {
     private _card: HTMLElement;
     private _code: string;
@@ -519,11 +596,85 @@ class ExpiryCard {
 
 var expiryCard = new ExpiryCard(statusCard);
 
+if (window.renewalCodesEnabled) {
+    const renewalCodeField = document.getElementById("user-renewal-code") as HTMLInputElement;
+    const renewalSubmit = document.getElementById("user-renewal-submit") as HTMLSpanElement;
+    renewalSubmit.onclick = () => {
+        if (renewalCodeField.value == "") return;
+        addLoader(renewalSubmit);
+        _post(
+            "/my/renewal",
+            { code: renewalCodeField.value.trim() },
+            (req: XMLHttpRequest) => {
+                if (req.readyState != 4) return;
+                removeLoader(renewalSubmit);
+                if (req.status == 200) {
+                    renewalCodeField.value = "";
+                    expiryCard.expiry = req.response["expiry"] as number;
+                    window.notifications.customSuccess("renewalCodeRedeemed", window.lang.notif("renewalCodeRedeemed"));
+                } else if (req.status == 400) {
+                    const err = req.response["error"] as string;
+                    window.notifications.customError("renewalCodeError", window.lang.notif(err));
+                } else {
+                    window.notifications.customError("errorUnknown", window.lang.notif("errorUnknown"));
+                }
+            },
+            true,
+        );
+    };
+}
+
+class CalendarFeed {
+    private _el: HTMLDivElement;
+    private _link: HTMLInputElement;
+    private _reset: HTMLSpanElement;
+
+    set token(t: string) {
+        if (!t) {
+            this._el.classList.add("unfocused");
+            this._link.value = "";
+            return;
+        }
+        this._link.value = window.pages.ExternalURI + "/calendar/user/" + t + ".ics";
+        this._el.classList.remove("unfocused");
+    }
+
+    constructor(el: HTMLDivElement) {
+        this._el = el;
+        this._link = el.querySelector("#user-calendar-link") as HTMLInputElement;
+        this._reset = el.querySelector("#user-calendar-reset") as HTMLSpanElement;
+        SetupCopyButton(el.querySelector("#user-calendar-copy") as HTMLButtonElement, () => this._link.value);
+        this._reset.onclick = () => {
+            addLoader(this._reset);
+            _post(
+                "/my/calendar",
+                null,
+                (req: XMLHttpRequest) => {
+                    if (req.readyState != 4) return;
+                    removeLoader(this._reset);
+                    if (req.status != 200) {
+                        window.notifications.customError("errorUnknown", window.lang.notif("errorUnknown"));
+                        return;
+                    }
+                    this.token = req.response["token"] as string;
+                    window.notifications.customSuccess("calendarLinkReset", window.lang.notif("calendarLinkReset"));
+                },
+                true,
+            );
+        };
+    }
+}
+
+var calendarFeed: CalendarFeed;
+if (window.calendarFeedEnabled) calendarFeed = new CalendarFeed(document.getElementById("user-calendar") as HTMLDivElement);
+
 var referralCard: ReferralCard;
 if (window.referralsEnabled) referralCard = new ReferralCard(document.getElementById("card-referrals"));
 
 var contactMethodList = new ContactMethods(contactCard);
 
+var messagePreferences = new MessagePreferences(document.getElementById("card-preferences"));
+
 const addEditEmail = (add: boolean): void => {
     const heading = window.modals.email.modal.querySelector(".heading");
     heading.innerHTML =
@@ -570,6 +721,7 @@ const discordConf: ServiceConfiguration = {
     modal: window.modals.discord as Modal,
     pin: "",
     inviteURL: window.discordInviteLink ? "/my/discord/invite" : "",
+    oauthURL: window.discordOAuthURL,
     pinURL: "/my/pin/discord",
     verifiedURL: "/my/discord/verified/",
     invalidCodeError: window.lang.notif("errorInvalidPIN"),
@@ -615,6 +767,23 @@ const matrixConf: MatrixConfiguration = {
 let matrix: Matrix;
 if (window.matrixEnabled) matrix = new Matrix(matrixConf);
 
+const pushConf: MatrixConfiguration = {
+    modal: window.modals.push as Modal,
+    sendMessageURL: "/my/push/user",
+    verifiedURL: "/my/push/verified/",
+    invalidCodeError: window.lang.notif("errorInvalidPIN"),
+    accountLinkedError: window.lang.notif("errorAccountLinked"),
+    unknownError: window.lang.notif("errorUnknown"),
+    successError: window.lang.notif("verified"),
+    errors: { errorInvalidPushTopic: window.lang.notif("errorInvalidPushTopic") },
+    successFunc: () => {
+        setTimeout(() => document.dispatchEvent(new CustomEvent("details-reload")), 1200);
+    },
+};
+
+let push: Push;
+if (window.pushEnabled) push = new Push(pushConf);
+
 const oldPasswordField = document.getElementById("user-old-password") as HTMLInputElement;
 const newPasswordField = document.getElementById("user-new-password") as HTMLInputElement;
 const rePasswordField = document.getElementById("user-reenter-new-password") as HTMLInputElement;
@@ -735,16 +904,29 @@ document.addEventListener("details-reload", () => {
                     required: window.matrixRequired,
                     enabled: window.matrixEnabled,
                 },
+                {
+                    name: "push",
+                    icon: `<i class="ri-notification-3-fill ri-lg"></i>`,
+                    f: (add: boolean) => {
+                        push.show();
+                    },
+                    required: window.pushRequired,
+                    enabled: window.pushEnabled,
+                },
             ];
 
+            let contactableMethods: { name: string; icon: string }[] = [];
             for (let method of contactMethods) {
                 if (!method.enabled) continue;
                 if (method.name in details) {
                     contactMethodList.append(method.name, details[method.name], method.icon, method.f, method.required);
+                    if (details[method.name].value != "" && details[method.name].enabled) contactableMethods.push(method);
                 }
             }
+            messagePreferences.load(details.message_preferences, contactableMethods);
 
             expiryCard.expiry = details.expiry;
+            if (window.calendarFeedEnabled) calendarFeed.token = details.calendar_token;
 
             const adminBackButton = document.getElementById("admin-back-button") as HTMLAnchorElement;
             adminBackButton.href = window.pages.Base + window.pages.Admin + "/";
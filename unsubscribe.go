@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	lm "github.com/hrfee/jfa-go/logmessages"
+)
+
+const (
+	UNSUBSCRIBE_SECRET_KEY = "unsubscribe_secret"
+	UNSUBSCRIBE_MAC_LENGTH = 16
+)
+
+// UnsubscribeSecret signs unsubscribe tokens. Unlike JFA_SECRET, it's stored so links in old emails keep working after a restart.
+type UnsubscribeSecret struct {
+	Secret []byte
+}
+
+// Lang strings for the name of each message category.
+var messageCategoryNames = map[string]string{
+	MessageCategoryAnnouncements: "messageCategoryAnnouncements",
+	MessageCategoryExpiry:        "messageCategoryExpiry",
+	MessageCategoryAccount:       "messageCategoryAccount",
+}
+
+// loadUnsubscribeSecret returns the stored unsubscribe secret, generating one if needed.
+func (st *Storage) loadUnsubscribeSecret() []byte {
+	secret := UnsubscribeSecret{}
+	st.db.Get(UNSUBSCRIBE_SECRET_KEY, &secret)
+	if len(secret.Secret) == 0 {
+		secret.Secret = make([]byte, 32)
+		rand.Read(secret.Secret)
+		st.db.Upsert(UNSUBSCRIBE_SECRET_KEY, secret)
+	}
+	return secret.Secret
+}
+
+func unsubscribeMAC(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)[:UNSUBSCRIBE_MAC_LENGTH]
+}
+
+// genUnsubscribeToken returns a token allowing the given user to stop receiving the given category of email, without logging in.
+func genUnsubscribeToken(secret []byte, jfID, category string) string {
+	payload := jfID + ":" + category
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(unsubscribeMAC(secret, payload))
+}
+
+// parseUnsubscribeToken returns the user and category an unsubscribe token is for, if it's valid.
+func parseUnsubscribeToken(secret []byte, token string) (jfID, category string, ok bool) {
+	encPayload, encMAC, found := strings.Cut(token, ".")
+	if !found {
+		return
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encMAC)
+	if err != nil || !hmac.Equal(mac, unsubscribeMAC(secret, string(payload))) {
+		return
+	}
+	jfID, category, found = strings.Cut(string(payload), ":")
+	if !found || jfID == "" || !slices.Contains(messageCategories, category) {
+		return "", "", false
+	}
+	ok = true
+	return
+}
+
+// unsubscribeURL returns the one-click unsubscribe link for the given user and category of email.
+func (app *appContext) unsubscribeURL(jfID, category string) string {
+	return ExternalURI(nil) + "/unsubscribe/" + genUnsubscribeToken(app.unsubscribeSecret, jfID, category)
+}
+
+func (app *appContext) unsubscribePage(gc *gin.Context, post bool) {
+	lang := app.getLang(gc, UserPage, app.storage.lang.chosenUserLang)
+	strs := app.storage.lang.User[lang].Strings
+	data := gin.H{
+		"contactMessage": app.config.Section("ui").Key("contact_message").String(),
+		"strings":        strs,
+	}
+	jfID, category, ok := parseUnsubscribeToken(app.unsubscribeSecret, gc.Param("token"))
+	if !ok {
+		app.debug.Printf(lm.InvalidUnsubscribeToken, gc.ClientIP())
+		app.gcHTML(gc, 404, "unsubscribe.html", OtherPage, lang, data)
+		return
+	}
+	data["valid"] = true
+	vals := tmpl{"category": strings.ToLower(strs.get(messageCategoryNames[category]))}
+	if !post {
+		data["description"] = strs.template("unsubscribeConfirm", vals)
+		app.gcHTML(gc, http.StatusOK, "unsubscribe.html", OtherPage, lang, data)
+		return
+	}
+	prefs, _ := app.storage.GetMessagePreferencesKey(jfID)
+	if prefs.Disabled == nil {
+		prefs.Disabled = map[string]map[string]bool{}
+	}
+	if prefs.Disabled[category] == nil {
+		prefs.Disabled[category] = map[string]bool{}
+	}
+	prefs.Disabled[category][ChannelEmail] = true
+	app.storage.SetMessagePreferencesKey(jfID, prefs)
+	app.info.Printf(lm.Unsubscribed, jfID, category)
+
+	data["success"] = true
+	data["description"] = strs.template("unsubscribedDescription", vals)
+	app.gcHTML(gc, http.StatusOK, "unsubscribe.html", OtherPage, lang, data)
+}
+
+// @Summary Shows a page confirming the user wants to stop receiving a category of email.
+// @Produce html
+// @Param token path string true "Unsubscribe token from the email."
+// @Success 200 {string} string
+// @Failure 404 {string} string
+// @Router /unsubscribe/{token} [get]
+// @tags Other
+func (app *appContext) UnsubscribePage(gc *gin.Context) { app.unsubscribePage(gc, false) }
+
+// @Summary Stops the user receiving a category of email. Accepts RFC 8058 one-click requests from mail clients, and the form on the confirmation page.
+// @Produce html
+// @Param token path string true "Unsubscribe token from the email."
+// @Success 200 {string} string
+// @Failure 404 {string} string
+// @Router /unsubscribe/{token} [post]
+// @tags Other
+func (app *appContext) Unsubscribe(gc *gin.Context) { app.unsubscribePage(gc, true) }
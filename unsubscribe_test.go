@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	token := genUnsubscribeToken(secret, "a1b2c3", MessageCategoryAnnouncements)
+
+	jfID, category, ok := parseUnsubscribeToken(secret, token)
+	if !ok || jfID != "a1b2c3" || category != MessageCategoryAnnouncements {
+		t.Fatalf("valid token not accepted: got (%q, %q, %v)", jfID, category, ok)
+	}
+
+	if _, _, ok := parseUnsubscribeToken([]byte("another secret"), token); ok {
+		t.Error("token accepted with wrong secret")
+	}
+
+	forged := genUnsubscribeToken([]byte("another secret"), "a1b2c3", MessageCategoryExpiry)
+	if _, _, ok := parseUnsubscribeToken(secret, forged); ok {
+		t.Error("forged token accepted")
+	}
+
+	unknown := genUnsubscribeToken(secret, "a1b2c3", "everything")
+	if _, _, ok := parseUnsubscribeToken(secret, unknown); ok {
+		t.Error("token for unknown category accepted")
+	}
+
+	for _, bad := range []string{"", ".", "notatoken", token + "x", "x" + token} {
+		if _, _, ok := parseUnsubscribeToken(secret, bad); ok {
+			t.Errorf("malformed token %q accepted", bad)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 
 	lm "github.com/hrfee/jfa-go/logmessages"
 	"github.com/hrfee/mediabrowser"
-	"github.com/lithammer/shortuuid/v3"
 )
 
 func newUserDaemon(interval time.Duration, app *appContext) *GenericDaemon {
@@ -20,12 +19,16 @@ func newUserDaemon(interval time.Duration, app *appContext) *GenericDaemon {
 		},
 	)
 	d.Name("User daemon")
+	if app.config.Section("user_adoption").Key("enabled").MustBool(false) {
+		d.appendJobs(func(app *appContext) { app.adoptUnknownUsers() })
+	}
 	return d
 }
 
 const (
 	ExpiryModeDisable = iota
 	ExpiryModeDelete
+	ExpiryModeDowngrade
 )
 
 func (app *appContext) checkUsers(remindBeforeExpiry *DayTimerSet) {
@@ -40,12 +43,27 @@ func (app *appContext) checkUsers(remindBeforeExpiry *DayTimerSet) {
 		return
 	}
 	expiryMode := ExpiryModeDisable
-	if app.config.Section("user_expiry").Key("behaviour").MustString("disable_user") == "delete_user" {
+	switch app.config.Section("user_expiry").Key("behaviour").MustString("disable_user") {
+	case "delete_user":
 		expiryMode = ExpiryModeDelete
+	case "downgrade_user":
+		expiryMode = ExpiryModeDowngrade
+	}
+
+	var downgradeProfile Profile
+	if expiryMode == ExpiryModeDowngrade {
+		name := app.config.Section("user_expiry").Key("downgrade_profile").String()
+		var ok bool
+		downgradeProfile, ok = app.storage.GetProfileKey(name)
+		// Better to disable than to let expired users keep their access.
+		if !ok {
+			app.err.Printf(lm.FailedGetProfile, name)
+			expiryMode = ExpiryModeDisable
+		}
 	}
 
 	deleteAfterPeriod := app.config.Section("user_expiry").Key("delete_expired_after_days").MustInt(0)
-	if expiryMode == ExpiryModeDelete {
+	if expiryMode == ExpiryModeDelete || expiryMode == ExpiryModeDowngrade {
 		deleteAfterPeriod = 0
 	}
 
@@ -84,7 +102,7 @@ func (app *appContext) checkUsers(remindBeforeExpiry *DayTimerSet) {
 					msg, err := app.email.constructExpiryReminder(user.Name, expiry.Expiry, false)
 					if err != nil {
 						app.err.Printf(lm.FailedConstructExpiryReminderMessage, user.ID, err)
-					} else if err := app.sendByID(msg, user.ID); err != nil {
+					} else if err := app.sendByID(msg, MessageCategoryExpiry, user.ID); err != nil {
 						app.err.Printf(lm.FailedSendExpiryReminderMessage, user.ID, name, err)
 					} else {
 						app.info.Printf(lm.SentExpiryReminderMessage, user.ID, name)
@@ -94,6 +112,11 @@ func (app *appContext) checkUsers(remindBeforeExpiry *DayTimerSet) {
 			continue
 		}
 
+		// Downgraded users keep their expiry (and previous policy) until it's extended.
+		if expiry.Downgraded {
+			continue
+		}
+
 		// True when "Delete after period" enabled and this user's account has already expired.
 		alreadyExpired := false
 		// True when the user has expired and N days has passed for them to be deleted.
@@ -132,12 +155,24 @@ func (app *appContext) checkUsers(remindBeforeExpiry *DayTimerSet) {
 			// Store the user name, since there's no longer a user ID to reference back to
 			activity.Value = user.Name
 			app.InvalidateUserCaches()
+		} else if expiryMode == ExpiryModeDowngrade {
+			app.info.Printf(lm.DowngradeExpiredUser, user.Name, downgradeProfile.Name)
+			err = app.DowngradeUser(user, &expiry, &downgradeProfile)
+			if err == nil {
+				app.removeFromMatrixRooms(user.ID, app.matrixRooms(downgradeProfile.Name))
+			}
+			activity.Type = ActivityDowngraded
+			activity.Value = downgradeProfile.Name
+			app.InvalidateUserCaches()
 		} else {
 			app.info.Printf(lm.DisableExpiredUser, user.Name)
 			// Admins can't be disabled
 			// so they're not an admin anymore, sorry
 			user.Policy.IsAdministrator = false
 			err, _, _ = app.SetUserDisabled(user, true)
+			if err == nil {
+				app.removeFromMatrixRooms(user.ID, nil)
+			}
 			activity.Type = ActivityDisabled
 			app.InvalidateUserCaches()
 		}
@@ -148,14 +183,21 @@ func (app *appContext) checkUsers(remindBeforeExpiry *DayTimerSet) {
 
 		// Sanity check
 		if activity.Type != ActivityUnknown {
-			app.storage.SetActivityKey(shortuuid.New(), activity, nil, false)
+			app.recordActivity(activity, nil, false)
 		}
 
 		// If we're not gonna be deleting the user later, we don't need the expiry stored anymore:
 		// 1. Delete after N days is disabled, or Expiry mode is set to delete.
 		// 2. User has expired and been deleted after N days.
 		// 3. User has expired, but their account is not disabled (i.e. an Admin intervened).
-		if deleteAfterPeriod <= 0 || alreadyExpiredShouldDelete || (alreadyExpired && !user.Policy.IsDisabled) {
+		// Downgraded users are the exception, as the expiry holds their previous policy.
+		if expiryMode == ExpiryModeDowngrade {
+			if shouldContact {
+				expiry.LastNotified = time.Now()
+			}
+			app.storage.SetUserExpiryKey(user.ID, expiry)
+			app.syncDiscordRoles(user.ID, false)
+		} else if deleteAfterPeriod <= 0 || alreadyExpiredShouldDelete || (alreadyExpired && !user.Policy.IsDisabled) {
 			app.storage.DeleteUserExpiryKey(user.ID)
 		} else if deleteAfterPeriod > 0 && !alreadyExpired {
 			// Otherwise, mark the expiry as done pending a delete after N days.
@@ -178,7 +220,7 @@ func (app *appContext) checkUsers(remindBeforeExpiry *DayTimerSet) {
 			msg, err := app.email.constructUserExpired(user.Name, false)
 			if err != nil {
 				app.err.Printf(lm.FailedConstructExpiryMessage, user.ID, err)
-			} else if err := app.sendByID(msg, user.ID); err != nil {
+			} else if err := app.sendByID(msg, MessageCategoryExpiry, user.ID); err != nil {
 				app.err.Printf(lm.FailedSendExpiryMessage, user.ID, name, err)
 			} else {
 				app.info.Printf(lm.SentExpiryMessage, user.ID, name)
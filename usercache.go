@@ -96,6 +96,7 @@ func (c *UserCache) MaybeSync(app *appContext) error {
 			discordCache := app.storage.DiscordUsersByID()
 			telegramCache := app.storage.TelegramUsersByID()
 			matrixCache := app.storage.MatrixUsersByID()
+			pushCache := app.storage.PushUsersByID()
 			referralCache := app.storage.ActiveReferralsByID()
 
 			for i, jfUser := range users {
@@ -125,10 +126,16 @@ func (c *UserCache) MaybeSync(app *appContext) error {
 						matrixPtr = &matrix
 					}
 				}
+				var pushPtr *PushUser = nil
+				if pushEnabled {
+					if push, ok := pushCache[jfUser.ID]; ok {
+						pushPtr = &push
+					}
+				}
 				_, referralsActive := referralCache[jfUser.ID]
 
 				// cache[i] = app.userSummary(jfUser, &referralCache)
-				cache[i] = app.userSummary(jfUser, emailPtr, expiryPtr, discordPtr, telegramPtr, matrixPtr, referralsActive)
+				cache[i] = app.userSummary(jfUser, emailPtr, expiryPtr, discordPtr, telegramPtr, matrixPtr, pushPtr, referralsActive)
 				if cache[i].Label != "" {
 					labels[cache[i].Label] = true
 				}
@@ -271,6 +278,14 @@ func SortUsersBy(field string) Sorter {
 		return func(a, b *respUser) int {
 			return cmp.Compare(bool2int(a.NotifyThroughMatrix), bool2int(b.NotifyThroughMatrix))
 		}
+	case "push":
+		return func(a, b *respUser) int {
+			return cmp.Compare(strings.ToLower(a.Push), strings.ToLower(b.Push))
+		}
+	case "notify_push":
+		return func(a, b *respUser) int {
+			return cmp.Compare(bool2int(a.NotifyThroughPush), bool2int(b.NotifyThroughPush))
+		}
 	case "label":
 		return func(a, b *respUser) int {
 			return cmp.Compare(strings.ToLower(a.Label), strings.ToLower(b.Label))
@@ -511,6 +526,24 @@ func (q QueryDTO) AsFilter() Filter {
 		return func(a *respUser) bool {
 			return cmp.Compare(bool2int(a.NotifyThroughMatrix), bool2int(q.Value.(bool))) == int(operator)
 		}
+	case "push":
+		switch q.Class {
+		case BoolQuery:
+			return func(a *respUser) bool {
+				if q.Value.(bool) {
+					return a.Push != ""
+				}
+				return a.Push == ""
+			}
+		case StringQuery:
+			return func(a *respUser) bool {
+				return cmp.Compare(strings.ToLower(a.Push), strings.ToLower(q.Value.(string))) == int(operator)
+			}
+		}
+	case "notify_push":
+		return func(a *respUser) bool {
+			return cmp.Compare(bool2int(a.NotifyThroughPush), bool2int(q.Value.(bool))) == int(operator)
+		}
 	case "label":
 		switch q.Class {
 		case BoolQuery:
@@ -545,6 +578,7 @@ func (ru *respUser) MatchesSearch(term string) bool {
 		strings.Contains(strings.ToLower(ru.Email), term) ||
 		strings.Contains(strings.ToLower(ru.Discord), term) ||
 		strings.Contains(strings.ToLower(ru.Matrix), term) ||
+		strings.Contains(strings.ToLower(ru.Push), term) ||
 		strings.Contains(strings.ToLower(ru.Telegram), term))
 }
 
@@ -11,6 +11,7 @@ import (
 	lm "github.com/hrfee/jfa-go/logmessages"
 	"github.com/hrfee/mediabrowser"
 	"github.com/lithammer/shortuuid/v3"
+	"github.com/timshannon/badgerhold/v4"
 )
 
 // ReponseFunc responds to the user, generally by HTTP response
@@ -102,6 +103,11 @@ func (app *appContext) NewUserPostVerification(p NewUserParams) (out NewUserData
 	// Invalidate cache to be safe
 	app.InvalidateUserCaches()
 
+	// Make sure the adoption daemon doesn't pick up this user.
+	if app.config.Section("user_adoption").Key("enabled").MustBool(false) {
+		app.storage.SetKnownUserKey(out.User.ID, KnownUser{Seen: time.Now()})
+	}
+
 	app.storage.SetActivityKey(shortuuid.New(), Activity{
 		Type:       ActivityCreation,
 		UserID:     out.User.ID,
@@ -173,7 +179,7 @@ func (app *appContext) WelcomeNewUser(user mediabrowser.User, expiry time.Time)
 	msg, err := app.email.constructWelcome(user.Name, expiry, false)
 	if err != nil {
 		app.err.Printf(lm.FailedConstructWelcomeMessage, user.ID, err)
-	} else if err := app.sendByID(msg, user.ID); err != nil {
+	} else if err := app.sendByID(msg, MessageCategoryAccount, user.ID); err != nil {
 		app.err.Printf(lm.FailedSendWelcomeMessage, user.ID, name, err)
 	} else {
 		app.info.Printf(lm.SentWelcomeMessage, user.ID, name)
@@ -182,6 +188,159 @@ func (app *appContext) WelcomeNewUser(user mediabrowser.User, expiry time.Time)
 	return
 }
 
+// ApplyProfileToUser applies the given profile's policy, and homescreen layout if it has one, to an existing Jellyfin user.
+// Third-party services aren't touched, as they generally need details only available at account creation.
+func (app *appContext) ApplyProfileToUser(jfID string, profile *Profile) error {
+	err := app.jf.SetPolicy(jfID, profile.Policy)
+	if err != nil {
+		app.err.Printf(lm.FailedApplyTemplate, "policy", lm.Jellyfin, jfID, err)
+		return err
+	}
+	app.setUserProfile(jfID, profile.Name)
+	if !profile.Homescreen {
+		return nil
+	}
+	err = app.jf.SetConfiguration(jfID, profile.Configuration)
+	if err == nil {
+		err = app.jf.SetDisplayPreferences(jfID, profile.Displayprefs)
+	}
+	if err != nil {
+		app.err.Printf(lm.FailedApplyTemplate, "configuration", lm.Jellyfin, jfID, err)
+	}
+	return err
+}
+
+// setUserProfile records the name of the profile last applied to the user, and moves them to the new profile's Matrix rooms if it's changed.
+func (app *appContext) setUserProfile(jfID, profile string) {
+	emailStore, _ := app.storage.GetEmailsKey(jfID)
+	changed := emailStore.Profile != profile
+	if changed {
+		app.removeFromMatrixRooms(jfID, app.matrixRooms(profile))
+	}
+	emailStore.Profile = profile
+	app.storage.SetEmailsKey(jfID, emailStore)
+	app.syncDiscordRolesForUser(jfID)
+	if changed {
+		app.inviteToMatrixRooms(jfID)
+	}
+}
+
+// DowngradeUser applies the given profile's policy to the user, storing their previous policy in "expiry" so it can later be restored by RestoreDowngradedUser.
+// The caller is responsible for storing "expiry".
+func (app *appContext) DowngradeUser(user mediabrowser.User, expiry *UserExpiry, profile *Profile) error {
+	err := app.jf.SetPolicy(user.ID, profile.Policy)
+	if err != nil {
+		return err
+	}
+	expiry.Downgraded = true
+	expiry.PreviousPolicy = user.Policy
+	return nil
+}
+
+// RestoreDowngradedUser re-applies the policy the user had before being downgraded on expiry.
+func (app *appContext) RestoreDowngradedUser(jfID string, expiry UserExpiry) error {
+	if !expiry.Downgraded {
+		return nil
+	}
+	app.info.Printf(lm.RestoreDowngradedUser, jfID)
+	err := app.jf.SetPolicy(jfID, expiry.PreviousPolicy)
+	if err == nil {
+		app.inviteToMatrixRooms(jfID)
+	}
+	return err
+}
+
+// updateDowngradedPolicy applies the given change to the policy restored when the user's downgrade ends, if they're downgraded,
+// so changes made to their policy in the meantime aren't lost when it's restored.
+func (app *appContext) updateDowngradedPolicy(jfID string, update func(policy *mediabrowser.Policy)) {
+	expiry, ok := app.storage.GetUserExpiryKey(jfID)
+	if !ok || !expiry.Downgraded {
+		return
+	}
+	update(&expiry.PreviousPolicy)
+	app.storage.SetUserExpiryKey(jfID, expiry)
+}
+
+// ExpiryExtension describes how ExtendUserExpiry should change a user's expiry.
+type ExpiryExtension struct {
+	Months, Days, Hours, Minutes int
+	Timestamp                    int64 // Optional, exact time to expire at. Overrides the duration.
+	// For users who've already expired, extend from when they did instead of the current time, as long as the result isn't in the past.
+	TryExtendFromPreviousExpiry bool
+}
+
+// lastExpiryDisable returns when the given user was disabled on expiry, if that's what they were last disabled for.
+func (app *appContext) lastExpiryDisable(jfID string) (time.Time, bool) {
+	var acts []Activity
+	app.storage.db.Find(&acts, badgerhold.Where("Type").Eq(ActivityDisabled).And("UserID").Eq(jfID).SortBy("Time").Reverse().Limit(1))
+	if len(acts) == 0 {
+		return time.Time{}, false
+	}
+	// Only if the most recent reason for disabling was expiry, rather than an admin or a departure.
+	if acts[0].SourceType != ActivityDaemon || acts[0].Source != "" {
+		app.debug.Printf(lm.PreviousExpiryNotExpiry)
+		return time.Time{}, false
+	}
+	app.debug.Printf(lm.FoundPreviousExpiryLog, acts[0].Time)
+	return acts[0].Time, true
+}
+
+// ExtendUserExpiry extends the given user's expiry, or creates one if they don't have one. A current expiry is extended from,
+// otherwise the current time is, unless ext.TryExtendFromPreviousExpiry is set. Downgraded users are restored if the new expiry
+// isn't in the past. The new expiry is stored and returned.
+func (app *appContext) ExtendUserExpiry(jfID string, ext ExpiryExtension) UserExpiry {
+	now := time.Now()
+	base := now
+	add := func(t time.Time) time.Time {
+		return t.AddDate(0, ext.Months, ext.Days).Add(time.Duration((60*ext.Hours)+ext.Minutes) * time.Minute)
+	}
+	previousExpiry, ok := app.storage.GetUserExpiryKey(jfID)
+	var expiredAt time.Time
+	expired := false
+	if ok {
+		app.debug.Printf(lm.FoundExistingExpiry)
+		if !previousExpiry.Downgraded && previousExpiry.Expiry.After(now) {
+			base = previousExpiry.Expiry
+		} else {
+			expiredAt, expired = previousExpiry.Expiry, true
+		}
+	} else if ext.TryExtendFromPreviousExpiry {
+		expiredAt, expired = app.lastExpiryDisable(jfID)
+	}
+	if expired && ext.TryExtendFromPreviousExpiry {
+		if add(expiredAt).After(now) {
+			base = expiredAt
+		} else {
+			app.debug.Printf(lm.ExpiryWouldBeInPast)
+		}
+	}
+	app.debug.Printf(lm.ExtendCreateExpiry, jfID)
+	expiry := UserExpiry{Expiry: add(base)}
+	if ext.Timestamp != 0 {
+		expiry.Expiry = time.Unix(ext.Timestamp, 0)
+	}
+	if previousExpiry.Downgraded {
+		// Keep hold of the previous policy if we fail to restore it, or the user is still expired.
+		restored := false
+		if expiry.Expiry.After(now) {
+			if err := app.RestoreDowngradedUser(jfID, previousExpiry); err != nil {
+				app.err.Printf(lm.FailedRestoreDowngrade, jfID, err)
+			} else {
+				restored = true
+			}
+		}
+		if !restored {
+			expiry.Downgraded = true
+			expiry.PreviousPolicy = previousExpiry.PreviousPolicy
+		}
+	}
+	app.storage.SetUserExpiryKey(jfID, expiry)
+	if previousExpiry.Downgraded {
+		app.syncDiscordRolesForUser(jfID)
+	}
+	return expiry
+}
+
 func (app *appContext) SetUserDisabled(user mediabrowser.User, disabled bool) (err error, change bool, activityType ActivityType) {
 	activityType = ActivityEnabled
 	if disabled {
@@ -194,6 +353,7 @@ func (app *appContext) SetUserDisabled(user mediabrowser.User, disabled bool) (e
 	if err != nil {
 		return
 	}
+	app.updateDowngradedPolicy(user.ID, func(policy *mediabrowser.Policy) { policy.IsDisabled = disabled })
 
 	if app.discord != nil && app.config.Section("discord").Key("disable_enable_role").MustBool(false) {
 		cmUser, ok := app.storage.GetDiscordKey(user.ID)
@@ -203,9 +363,48 @@ func (app *appContext) SetUserDisabled(user mediabrowser.User, disabled bool) (e
 			}
 		}
 	}
+	app.syncDiscordRoles(user.ID, disabled)
+	if change && !disabled {
+		app.inviteToMatrixRooms(user.ID)
+	}
 	return
 }
 
+// disableDepartedUser disables a user who has left a community they had to be part of, recording it in the activity log with the given source.
+// Returns whether they were disabled.
+func (app *appContext) disableDepartedUser(user mediabrowser.User, source string) bool {
+	if user.Policy.IsDisabled {
+		return false
+	}
+	err, _, activityType := app.SetUserDisabled(user, true)
+	if err != nil {
+		app.err.Printf(lm.FailedApplyTemplate, "policy", lm.Jellyfin, user.ID, err)
+		return false
+	}
+	app.recordActivity(Activity{
+		Type:       activityType,
+		UserID:     user.ID,
+		SourceType: ActivityDaemon,
+		Source:     source,
+		Time:       time.Now(),
+	}, nil, false)
+	return true
+}
+
+// onlyDisabledBy returns whether the given user was last disabled by the daemon with the given source, and hasn't expired since,
+// i.e. re-enabling them wouldn't undo an admin's or the expiry daemon's doing.
+func (app *appContext) onlyDisabledBy(jfID, source string) bool {
+	acts := []Activity{}
+	app.storage.db.Find(&acts, badgerhold.Where("Type").Eq(ActivityDisabled).And("UserID").Eq(jfID).SortBy("Time").Reverse().Limit(1))
+	if len(acts) == 0 || acts[0].SourceType != ActivityDaemon || acts[0].Source != source {
+		return false
+	}
+	if expiry, ok := app.storage.GetUserExpiryKey(jfID); ok && time.Now().After(expiry.Expiry) {
+		return false
+	}
+	return true
+}
+
 func (app *appContext) DeleteUser(user mediabrowser.User) (err error, deleted bool) {
 	// FIXME: Add DeleteContactMethod to TPS
 	if app.ombi != nil {
@@ -229,6 +428,8 @@ func (app *appContext) DeleteUser(user mediabrowser.User) (err error, deleted bo
 			}
 		}
 	}
+	app.syncDiscordRoles(user.ID, true)
+	app.removeFromMatrixRooms(user.ID, nil)
 
 	err = app.jf.DeleteUser(user.ID)
 	if err != nil {
@@ -21,7 +21,6 @@ import (
 	lm "github.com/hrfee/jfa-go/logmessages"
 	"github.com/hrfee/mediabrowser"
 	sTemplate "github.com/hrfee/simple-template"
-	"github.com/lithammer/shortuuid/v3"
 	"github.com/steambap/captcha"
 )
 
@@ -118,6 +117,7 @@ func (app *appContext) BasePageTemplateValues(gc *gin.Context, lang string, page
 	set("telegramEnabled", telegramEnabled)
 	set("discordEnabled", discordEnabled)
 	set("matrixEnabled", matrixEnabled)
+	set("pushEnabled", pushEnabled)
 	set("ombiEnabled", ombiEnabled)
 	set("jellyseerrEnabled", jellyseerrEnabled)
 	// QUIRK: The login modal html template uses this' existence to check if the modal is for the admin or user page.
@@ -125,6 +125,8 @@ func (app *appContext) BasePageTemplateValues(gc *gin.Context, lang string, page
 		set("pwrEnabled", app.config.Section("password_resets").Key("enabled").MustBool(false))
 	}
 	set("referralsEnabled", app.config.Section("user_page").Key("enabled").MustBool(false) && app.config.Section("user_page").Key("referrals").MustBool(false))
+	set("renewalCodesEnabled", app.config.Section("user_page").Key("enabled").MustBool(false) && app.config.Section("user_page").Key("renewal_codes").MustBool(false))
+	set("calendarFeedEnabled", app.config.Section("user_expiry").Key("calendar_feed").MustBool(false))
 	app.SetBaseLangTemplateValues(gc, lang, base)
 }
 
@@ -267,12 +269,17 @@ func (app *appContext) MyUserPage(gc *gin.Context) {
 	if telegramEnabled {
 		data["telegramUsername"] = app.telegram.username
 		data["telegramURL"] = app.telegram.link
-		data["telegramRequired"] = app.config.Section("telegram").Key("required").MustBool(false)
+		data["telegramRequired"] = app.telegram.Required()
 	}
 	if matrixEnabled {
 		data["matrixRequired"] = app.config.Section("matrix").Key("required").MustBool(false)
 		data["matrixUser"] = app.matrix.userID
 	}
+	if pushEnabled {
+		data["pushRequired"] = app.push.Required()
+		data["pushProvider"] = app.push.provider
+		data["pushServer"] = app.push.server
+	}
 	if discordEnabled {
 		data["discordUsername"] = app.discord.username
 		data["discordRequired"] = app.config.Section("discord").Key("required").MustBool(false)
@@ -282,6 +289,8 @@ func (app *appContext) MyUserPage(gc *gin.Context) {
 		}))
 		data["discordServerName"] = app.discord.serverName
 		data["discordInviteLink"] = app.discord.InviteChannel.Name != ""
+		data["discordOAuth"] = app.discordOAuthEnabled()
+		data["discordOAuthURL"] = discordOAuthStartURL()
 	}
 	if data["linkResetEnabled"].(bool) {
 		data["resetPasswordUsername"] = app.config.Section("user_page").Key("allow_pwr_username").MustBool(true)
@@ -343,6 +352,7 @@ func (app *appContext) ResetPassword(gc *gin.Context) {
 		data["telegramEnabled"] = false
 		data["discordEnabled"] = false
 		data["matrixEnabled"] = false
+		data["pushEnabled"] = false
 		data["captcha"] = app.config.Section("captcha").Key("enabled").MustBool(false)
 		data["reCAPTCHA"] = app.config.Section("captcha").Key("recaptcha").MustBool(false)
 		data["reCAPTCHASiteKey"] = app.config.Section("captcha").Key("recaptcha_site_key").MustString("")
@@ -405,7 +415,7 @@ func (app *appContext) ResetPassword(gc *gin.Context) {
 	if username != "" {
 		jfUser, err := app.jf.UserByName(username, false)
 		if err == nil {
-			app.storage.SetActivityKey(shortuuid.New(), Activity{
+			app.recordActivity(Activity{
 				Type:       ActivityResetPassword,
 				UserID:     jfUser.ID,
 				SourceType: ActivityUser,
@@ -766,6 +776,7 @@ func (app *appContext) InviteProxy(gc *gin.Context) {
 	telegram := telegramEnabled && app.config.Section("telegram").Key("show_on_reg").MustBool(true)
 	discord := discordEnabled && app.config.Section("discord").Key("show_on_reg").MustBool(true)
 	matrix := matrixEnabled && app.config.Section("matrix").Key("show_on_reg").MustBool(true)
+	push := pushEnabled && app.config.Section("push").Key("show_on_reg").MustBool(true)
 
 	userPageAddress := ExternalURI(gc) + PAGES.MyAccount
 
@@ -805,6 +816,7 @@ func (app *appContext) InviteProxy(gc *gin.Context) {
 		"telegramEnabled":   telegram,
 		"discordEnabled":    discord,
 		"matrixEnabled":     matrix,
+		"pushEnabled":       push,
 		"emailRequired":     app.config.Section("email").Key("required").MustBool(false),
 		"captcha":           app.config.Section("captcha").Key("enabled").MustBool(false),
 		"reCAPTCHA":         app.config.Section("captcha").Key("recaptcha").MustBool(false),
@@ -817,14 +829,20 @@ func (app *appContext) InviteProxy(gc *gin.Context) {
 		data["telegramPIN"] = app.telegram.NewAuthToken()
 		data["telegramUsername"] = app.telegram.username
 		data["telegramURL"] = app.telegram.link
-		data["telegramRequired"] = app.config.Section("telegram").Key("required").MustBool(false)
+		data["telegramRequired"] = app.telegram.Required()
 	}
 	if matrix {
 		data["matrixRequired"] = app.config.Section("matrix").Key("required").MustBool(false)
 		data["matrixUser"] = app.matrix.userID
 	}
+	if push {
+		data["pushRequired"] = app.push.Required()
+		data["pushProvider"] = app.push.provider
+		data["pushServer"] = app.push.server
+	}
 	if discord {
 		data["discordPIN"] = app.discord.NewAuthToken()
+		app.setDiscordOAuthCookie(gc, data["discordPIN"].(string))
 		data["discordUsername"] = app.discord.username
 		data["discordRequired"] = app.config.Section("discord").Key("required").MustBool(false)
 		data["discordSendPINMessage"] = template.HTML(app.storage.lang.User[lang].Strings.template("sendPINDiscord", tmpl{
@@ -833,6 +851,8 @@ func (app *appContext) InviteProxy(gc *gin.Context) {
 		}))
 		data["discordServerName"] = app.discord.serverName
 		data["discordInviteLink"] = app.discord.InviteChannel.Name != ""
+		data["discordOAuth"] = app.discordOAuthEnabled()
+		data["discordOAuthURL"] = discordOAuthStartURL()
 	}
 	if msg, ok := app.storage.GetCustomContentKey("PostSignupCard"); ok && msg.Enabled {
 		data["customSuccessCard"] = true